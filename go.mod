@@ -1,3 +1,5 @@
 module music-queue
 
 go 1.24.4
+
+require golang.org/x/text v0.34.0
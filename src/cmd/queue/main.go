@@ -1,12 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"music-queue/src/internal/library"
 	"music-queue/src/internal/queue"
 	"music-queue/src/internal/storage"
 )
@@ -22,14 +30,50 @@ func main() {
 	switch command {
 	case "import":
 		handleImportCommand()
+	case "export":
+		handleExportCommand()
 	case "add":
 		handleAddCommand()
 	case "next":
 		handleNextCommand()
+	case "peek":
+		handlePeekCommand()
+	case "prioritize":
+		handlePrioritizeCommand()
+	case "skip":
+		handleSkipCommand()
+	case "snooze":
+		handleSnoozeCommand()
+	case "remove":
+		handleRemoveCommand()
+	case "edit", "rename":
+		handleEditCommand()
+	case "move":
+		handleMoveCommand()
+	case "pin", "unpin":
+		handlePinCommand(command)
+	case "play":
+		handlePlayCommand()
+	case "tag", "untag":
+		handleTagCommand(command)
+	case "tags":
+		handleTagsCommand()
+	case "dedupe":
+		handleDedupeCommand()
 	case "list":
 		handleListCommand()
+	case "search":
+		handleSearchCommand()
 	case "count":
 		handleCountCommand()
+	case "history":
+		handleHistoryCommand()
+	case "config":
+		handleConfigCommand()
+	case "undo":
+		handleJournalCommand("undo")
+	case "redo":
+		handleJournalCommand("redo")
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -43,17 +87,44 @@ func handleImportCommand() {
 	// Set up flag parsing for import command
 	importFlags := flag.NewFlagSet("import", flag.ExitOnError)
 	queuePath := importFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := importFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	similarity := importFlags.String("similarity", "", "Also skip albums at least this similar to one in the queue, e.g. 0.9 (default from config)")
+	reportFormat := importFlags.String("report", "text", "How to print the import report: text or json")
+	rejectsPath := importFlags.String("rejects", "", "Write the rejected lines to this file, to fix and import again")
+	format := importFlags.String("format", "", "Import file format: text, csv, json, m3u or pls (default from the file extension: .csv, .tsv, .json,\n.m3u, .m3u8 or .pls), or the data export of a service: "+strings.Join(library.Services, ", "))
+	delimiter := importFlags.String("delimiter", "", "CSV field delimiter, e.g. ';' or tab (default ',' or tab for .tsv)")
+	header := importFlags.String("header", queue.CSVHeaderAuto, "Whether the CSV starts with a header row: auto, yes or no")
+	artistCol := importFlags.String("artist-col", "", "CSV artist column, by header name or number from 1 (default: a column named artist, or the first)")
+	albumCol := importFlags.String("album-col", "", "CSV album column, by header name or number from 1 (default: a column named album or title, or the second)")
+	fromDir := importFlags.String("from-dir", "", "Import the albums of a music library directory instead of a file, from MP3, FLAC, Ogg, Opus and M4A tags or Artist/Album folders")
 
 	importFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s import [flags] <import-file>\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Import albums from a text file to the queue.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s import [flags] <import-file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s import [flags] --from-dir <directory>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Import albums from a text file, or a CSV file, to the queue.\n")
+		fmt.Fprintf(os.Stderr, "Data exports of Spotify, Last.fm, Bandcamp, Discogs and RateYourMusic are read with --format.\n")
+		fmt.Fprintf(os.Stderr, "M3U and PLS playlists import the distinct albums of their tracks.\n")
+		fmt.Fprintf(os.Stderr, "Each line that is not added is reported with its line number and the reason.\n")
+		fmt.Fprintf(os.Stderr, "With --from-dir, the albums found in a music library are imported instead.\n\n")
 		fmt.Fprintf(os.Stderr, "Arguments:\n")
-		fmt.Fprintf(os.Stderr, "  <import-file>  Path to text file containing album names (one per line)\n\n")
+		fmt.Fprintf(os.Stderr, "  <import-file>  Path to text file containing album names (one per line), CSV file with one album per row,\n")
+		fmt.Fprintf(os.Stderr, "                 JSON document written by export (queue and history), M3U or PLS playlist,\n")
+		fmt.Fprintf(os.Stderr, "                 or data export of a service\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		importFlags.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s import albums.txt\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s import --queue /custom/path/queue.txt albums.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --rejects rejects.txt albums.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --report json albums.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import albums.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --format csv --delimiter ';' --artist-col Band --album-col Record albums.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --header no --artist-col 2 --album-col 1 albums.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import queue.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --format spotify YourLibrary.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --format discogs collection.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --format m3u playlist.m3u8\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s import --from-dir ~/Music\n", os.Args[0])
 	}
 
 	// Parse import command arguments
@@ -63,18 +134,64 @@ func handleImportCommand() {
 	}
 
 	// Check if import file was provided
-	if importFlags.NArg() != 1 {
+	if *fromDir != "" && importFlags.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "Error: --from-dir imports a directory instead of an import file\n\n")
+		importFlags.Usage()
+		os.Exit(1)
+	}
+	if *fromDir == "" && importFlags.NArg() != 1 {
 		fmt.Fprintf(os.Stderr, "Error: Import file not specified\n\n")
 		importFlags.Usage()
 		os.Exit(1)
 	}
 
+	if *reportFormat != "text" && *reportFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: --report must be text or json, got %q\n", *reportFormat)
+		os.Exit(1)
+	}
+
 	importFile := importFlags.Arg(0)
+	importFormat, csvOptions := "", queue.CSVOptions{}
+	if *fromDir != "" {
+		if *format != "" || *delimiter != "" || *header != queue.CSVHeaderAuto || *artistCol != "" || *albumCol != "" {
+			fmt.Fprintf(os.Stderr, "Error: --format, --delimiter, --header, --artist-col and --album-col do not apply to --from-dir\n")
+			os.Exit(1)
+		}
+		importFile = *fromDir
+	} else {
+		if playlist := playlistFormat(importFile, *format); slices.Contains(library.Services, *format) || playlist != "" {
+			// Service exports and playlists have a fixed layout
+			if *delimiter != "" {
+				fmt.Fprintf(os.Stderr, "Error: --delimiter only applies to --format %s\n", formatCSV)
+				os.Exit(1)
+			}
+			importFormat = *format
+			if playlist != "" {
+				importFormat = playlist
+			}
+		} else if *format != "" && *format != formatText && *format != formatCSV && *format != formatJSON {
+			fmt.Fprintf(os.Stderr, "Error: --format must be %s, %s, %s, %s, %s or a service (%s), got %q\n", formatText, formatCSV, formatJSON, formatM3U, formatPLS, strings.Join(library.Services, ", "), *format)
+			os.Exit(1)
+		} else {
+			importFormat, csvOptions, err = formatFlags(importFile, *format, *delimiter)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if importFormat != formatCSV && (*header != queue.CSVHeaderAuto || *artistCol != "" || *albumCol != "") {
+			fmt.Fprintf(os.Stderr, "Error: --header, --artist-col and --album-col only apply to --format %s\n", formatCSV)
+			os.Exit(1)
+		}
+		csvOptions.Header = *header
+		csvOptions.ArtistColumn = *artistCol
+		csvOptions.AlbumColumn = *albumCol
 
-	// Validate import file exists
-	if _, err := os.Stat(importFile); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Error: Import file '%s' not found\n", importFile)
-		os.Exit(1)
+		// Validate import file exists
+		if _, err := os.Stat(importFile); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: Import file '%s' not found\n", importFile)
+			os.Exit(1)
+		}
 	}
 
 	// Get absolute path for better error messages
@@ -85,41 +202,432 @@ func handleImportCommand() {
 
 	// Create storage and queue service
 	queueStorage := storage.NewFileStorage(*queuePath)
-	queueService := queue.NewQueue(queueStorage)
+	options, err := similarityOptions(*similarity, queueStorage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	queueService := queue.NewQueue(queueStorage, append(options, queue.WithLockTimeout(*lockTimeout))...)
 
 	// Perform import
-	fmt.Printf("Importing albums from '%s'...\n", absImportFile)
+	if *reportFormat == "text" && *fromDir == "" {
+		fmt.Printf("Importing albums from '%s'...\n", absImportFile)
+	}
 
-	added, duplicates, formatErrors, err := queueService.ImportAlbums(importFile)
+	var report queue.ImportReport
+	switch {
+	case *fromDir != "":
+		report, err = importLibrary(queueService, absImportFile, *reportFormat == "text")
+	case importFormat == formatCSV:
+		report, err = queueService.ImportCSV(importFile, csvOptions)
+	case importFormat == formatJSON:
+		report, err = queueService.ImportJSON(importFile)
+	case importFormat == formatM3U || importFormat == formatPLS:
+		report, err = importPlaylist(queueService, importFile, importFormat, *reportFormat == "text")
+	case slices.Contains(library.Services, importFormat):
+		report, err = importServiceExport(queueService, importFile, importFormat)
+	default:
+		report, err = queueService.ImportFile(importFile)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *rejectsPath != "" {
+		if err := writeRejects(*rejectsPath, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *reportFormat == "json" {
+		if report.Rejected == nil {
+			report.Rejected = []queue.RejectedLine{}
+		}
+		output, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(output))
+		return
+	}
+
+	printImportReport(report, *queuePath)
+
+	if *rejectsPath != "" && len(report.Rejected) > 0 {
+		absRejectsPath, err := filepath.Abs(*rejectsPath)
+		if err != nil {
+			absRejectsPath = *rejectsPath
+		}
+		fmt.Printf("Rejected lines written to: %s\n", absRejectsPath)
+	}
+}
+
+// importLibrary imports the albums found by scanning a music library
+// directory. With verbose set, what the scan found is printed first.
+func importLibrary(queueService *queue.QueueService, dir string, verbose bool) (queue.ImportReport, error) {
+	if verbose {
+		fmt.Printf("Scanning '%s'...\n", dir)
+	}
+
+	result, err := library.Scan(dir)
+	if err != nil {
+		return queue.ImportReport{}, err
+	}
+
+	items := make([]queue.ImportItem, len(result.Albums))
+	fromFolders := 0
+	for i, album := range result.Albums {
+		items[i] = queue.ImportItem{
+			Text:  album.Dir,
+			Album: queue.Album{Artist: album.Artist, Title: album.Title, Year: album.Year, Source: queue.SourceLibrary},
+		}
+		if album.FromFolders {
+			fromFolders++
+		}
+	}
+
+	if verbose {
+		fmt.Printf("Found %d audio files in %d albums", result.Files, len(result.Albums))
+		if fromFolders > 0 {
+			fmt.Printf(" (%d named from their folders)", fromFolders)
+		}
+		fmt.Println()
+		if len(result.Problems) > 0 {
+			fmt.Printf("\nSkipped %d files:\n", len(result.Problems))
+			for _, problem := range result.Problems {
+				fmt.Printf("  %s: %v\n", problem.Path, problem.Err)
+			}
+			fmt.Println()
+		}
+	}
+
+	return queueService.ImportItems(items)
+}
+
+// importPlaylist imports the distinct albums of the tracks in an M3U or PLS
+// playlist. Entries whose album cannot be derived are rejected with their
+// line. With verbose set, what the playlist lists is printed first.
+func importPlaylist(queueService *queue.QueueService, path, format string, verbose bool) (queue.ImportReport, error) {
+	result, err := library.ReadPlaylist(path, format)
+	if err != nil {
+		return queue.ImportReport{}, err
+	}
+
+	var items []queue.ImportItem
+	for _, album := range result.Albums {
+		items = append(items, queue.ImportItem{
+			Line:  album.Line,
+			Text:  album.Text,
+			Album: queue.Album{Artist: album.Artist, Title: album.Title, Year: album.Year, Source: queue.SourcePlaylist},
+		})
+	}
+	for _, problem := range result.Problems {
+		items = append(items, queue.ImportItem{Line: problem.Line, Text: problem.Text, Err: problem.Err})
+	}
+	slices.SortFunc(items, func(a, b queue.ImportItem) int { return a.Line - b.Line })
+
+	if verbose {
+		fmt.Printf("Found %d tracks in %d albums\n", result.Tracks, len(result.Albums))
+	}
+
+	return queueService.ImportItems(items)
+}
+
+// importServiceExport imports the albums of a data export file downloaded
+// from service, one of library.Services, with the service as their source.
+// Rejected entries are reported with their line in CSV exports, or their
+// position in JSON exports; the report's Header holds the header row of CSV
+// exports.
+func importServiceExport(queueService *queue.QueueService, path, service string) (queue.ImportReport, error) {
+	export, err := library.ReadServiceExport(path, service)
+	if err != nil {
+		return queue.ImportReport{}, err
+	}
+
+	items := make([]queue.ImportItem, len(export.Albums))
+	for i, album := range export.Albums {
+		items[i] = queue.NewImportItem(album.Line, album.Text, queue.Album{Artist: album.Artist, Title: album.Title, Year: album.Year})
+		items[i].Album.Source = service
+	}
+
+	report, err := queueService.ImportItems(items)
+	if err != nil {
+		return queue.ImportReport{}, err
+	}
+
+	report.Header = export.Header
+	return report, nil
+}
+
+// printImportReport shows the counts of an import and every rejected line
+func printImportReport(report queue.ImportReport, queuePath string) {
 	// Display results with clear formatting
-	if added == 0 && duplicates == 0 && formatErrors == 0 {
+	if report.Added == 0 && report.Duplicates == 0 && report.FormatErrors == 0 && report.History == 0 {
 		fmt.Println("No albums found in import file.")
-	} else {
-		// Build result message with dynamic components
-		var resultParts []string
-		if added > 0 {
-			resultParts = append(resultParts, fmt.Sprintf("Added %d albums", added))
+		return
+	}
+
+	// Build result message with dynamic components
+	var resultParts []string
+	if report.Added > 0 {
+		resultParts = append(resultParts, fmt.Sprintf("Added %d albums", report.Added))
+	}
+	if report.Duplicates > 0 {
+		resultParts = append(resultParts, fmt.Sprintf("Skipped %d duplicates", report.Duplicates))
+	}
+	if report.FormatErrors > 0 {
+		resultParts = append(resultParts, fmt.Sprintf("%d format errors", report.FormatErrors))
+	}
+	if report.History > 0 {
+		resultParts = append(resultParts, fmt.Sprintf("Added %d history entries", report.History))
+	}
+
+	fmt.Printf("Import complete! %s\n", strings.Join(resultParts, ", "))
+
+	if len(report.Rejected) > 0 {
+		// Sources without lines, such as a directory scan, reject albums
+		if report.Rejected[0].Line == 0 {
+			fmt.Println("\nRejected albums:")
+		} else {
+			fmt.Println("\nRejected lines:")
+		}
+		for _, rejected := range report.Rejected {
+			if rejected.Line == 0 {
+				fmt.Printf("  %s: %s\n", rejected.Reason, rejected.Message)
+			} else {
+				fmt.Printf("  line %d, %s: %s\n", rejected.Line, rejected.Reason, rejected.Message)
+			}
+			fmt.Printf("    %s\n", rejected.Text)
+		}
+		fmt.Println()
+	}
+
+	// Show queue file location
+	absQueuePath, err := filepath.Abs(queuePath)
+	if err != nil {
+		absQueuePath = queuePath
+	}
+	fmt.Printf("Queue saved to: %s\n", absQueuePath)
+}
+
+// writeRejects writes the text of each rejected import line to path, one per
+// line and after the header line of a CSV import, replacing the file.
+// Albums rejected from a source without lines, such as a directory scan,
+// are written as records so that the file can be imported again.
+func writeRejects(path string, report queue.ImportReport) error {
+	var builder strings.Builder
+	if report.Header != "" && len(report.Rejected) > 0 {
+		builder.WriteString(report.Header)
+		builder.WriteByte('\n')
+	}
+	for _, line := range report.Rejected {
+		if line.Record != "" {
+			builder.WriteString(line.Record)
+		} else {
+			builder.WriteString(line.Text)
+		}
+		builder.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write rejected lines: %w", err)
+	}
+	return nil
+}
+
+// File formats of import and export
+const (
+	formatText = "text"
+	formatCSV  = "csv"
+	formatJSON = "json"
+	formatM3U  = library.PlaylistM3U
+	formatPLS  = library.PlaylistPLS
+)
+
+// playlistFormat returns the playlist format of an import file, formatM3U or
+// formatPLS, by --format (where m3u8 is m3u) or else by the file extension,
+// or "" when it is not a playlist
+func playlistFormat(path, format string) string {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case formatM3U, "m3u8":
+		return formatM3U
+	case formatPLS:
+		return formatPLS
+	}
+	return ""
+}
+
+// formatFlags resolves the --format and --delimiter flags of import and
+// export for path: without --format, .csv and .tsv files are CSV, .json
+// files JSON and other files text
+func formatFlags(path, format, delimiter string) (string, queue.CSVOptions, error) {
+	var options queue.CSVOptions
+	extension := strings.ToLower(filepath.Ext(path))
+	if format == "" {
+		format = formatText
+		switch extension {
+		case ".csv", ".tsv":
+			format = formatCSV
+		case ".json":
+			format = formatJSON
+		}
+	}
+	if format != formatText && format != formatCSV && format != formatJSON {
+		return "", options, fmt.Errorf("--format must be %s, %s or %s, got %q", formatText, formatCSV, formatJSON, format)
+	}
+
+	if delimiter == "" && extension == ".tsv" && format == formatCSV {
+		delimiter = "tab"
+	}
+	if delimiter != "" {
+		if format != formatCSV {
+			return "", options, fmt.Errorf("--delimiter only applies to --format %s", formatCSV)
+		}
+		comma, err := parseDelimiter(delimiter)
+		if err != nil {
+			return "", options, err
+		}
+		options.Delimiter = comma
+	}
+
+	return format, options, nil
+}
+
+// parseDelimiter parses a --delimiter value: a single character, or "tab"
+// or "\t" for a tab
+func parseDelimiter(value string) (rune, error) {
+	if value == "tab" || value == `\t` {
+		return '\t', nil
+	}
+
+	runes := []rune(value)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\n' || runes[0] == '\r' || runes[0] == utf8.RuneError {
+		return 0, fmt.Errorf("invalid --delimiter %q: must be a single character other than a quote, e.g. ';' or tab", value)
+	}
+	return runes[0], nil
+}
+
+func handleExportCommand() {
+	// Set up flag parsing for export command
+	exportFlags := flag.NewFlagSet("export", flag.ExitOnError)
+	queuePath := exportFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	format := exportFlags.String("format", "", "Export format: text, csv or json (default from the --output extension, otherwise text)")
+	delimiter := exportFlags.String("delimiter", "", "CSV field delimiter, e.g. ';' or tab (default ',' or tab for .tsv)")
+	history := exportFlags.Bool("history", false, "Export the listening history instead of the queue")
+	output := exportFlags.String("output", "", "Write to this file instead of standard output")
+
+	exportFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Write the queue or the listening history with all album details.\n")
+		fmt.Fprintf(os.Stderr, "The text format is that of the queue file; csv has a header row and one column per detail.\n")
+		fmt.Fprintf(os.Stderr, "json writes a versioned document with both the queue and the history, which import reads back.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		exportFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s export --output queue.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s export --format csv --delimiter ';' > queue.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s export --history --output history.csv\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s export --output queue.json\n", os.Args[0])
+	}
+
+	// Parse export command arguments
+	err := exportFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if exportFlags.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "Error: export takes no arguments; use --output to write to a file\n\n")
+		exportFlags.Usage()
+		os.Exit(1)
+	}
+
+	exportFormat, csvOptions, err := formatFlags(*output, *format, *delimiter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if exportFormat == formatJSON && *history {
+		fmt.Fprintf(os.Stderr, "Error: --history does not apply to --format json, which always includes the history\n")
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage)
+
+	var albums []queue.Album
+	var entries []queue.HistoryEntry
+	if *history || exportFormat == formatJSON {
+		entries, err = queueService.History(time.Time{}, time.Time{})
+	}
+	if err == nil && (!*history || exportFormat == formatJSON) {
+		albums, err = queueService.ListAlbums()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	writer := bufio.NewWriter(os.Stdout)
+	var file *os.File
+	if *output != "" {
+		file, err = os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create export file: %v\n", err)
+			os.Exit(1)
+		}
+		writer = bufio.NewWriter(file)
+	}
+
+	switch {
+	case exportFormat == formatJSON:
+		err = queue.WriteDocument(writer, albums, entries, time.Now())
+	case exportFormat == formatCSV && *history:
+		err = queue.WriteHistoryCSV(writer, entries, csvOptions.Delimiter)
+	case exportFormat == formatCSV:
+		err = queue.WriteAlbumsCSV(writer, albums, csvOptions.Delimiter)
+	case *history:
+		for _, entry := range entries {
+			fmt.Fprintln(writer, queue.FormatHistoryRecord(entry))
 		}
-		if duplicates > 0 {
-			resultParts = append(resultParts, fmt.Sprintf("Skipped %d duplicates", duplicates))
+	default:
+		for _, album := range albums {
+			fmt.Fprintln(writer, queue.FormatRecord(album))
 		}
-		if formatErrors > 0 {
-			resultParts = append(resultParts, fmt.Sprintf("%d format errors", formatErrors))
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
 		}
-		
-		fmt.Printf("Import complete! %s\n", strings.Join(resultParts, ", "))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to export: %v\n", err)
+		os.Exit(1)
+	}
 
-		// Show queue file location
-		absQueuePath, err := filepath.Abs(*queuePath)
+	if *output != "" {
+		absOutput, err := filepath.Abs(*output)
 		if err != nil {
-			absQueuePath = *queuePath
+			absOutput = *output
+		}
+		switch {
+		case exportFormat == formatJSON:
+			fmt.Printf("Exported %d albums and %d history entries to: %s\n", len(albums), len(entries), absOutput)
+		case *history:
+			fmt.Printf("Exported %d history entries to: %s\n", len(entries), absOutput)
+		default:
+			fmt.Printf("Exported %d albums to: %s\n", len(albums), absOutput)
 		}
-		fmt.Printf("Queue saved to: %s\n", absQueuePath)
 	}
 }
 
@@ -127,17 +635,29 @@ func handleAddCommand() {
 	// Set up flag parsing for add command
 	addFlags := flag.NewFlagSet("add", flag.ExitOnError)
 	queuePath := addFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := addFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	year := addFlags.Int("year", 0, "Release year of the album")
+	notes := addFlags.String("notes", "", "Free-form notes about the album")
+	priority := addFlags.Int("priority", 0, "Selection weight; an album with priority 5 is picked five times as often as one with the default of 1")
+	var tags stringsFlag
+	addFlags.Var(&tags, "tag", "Tag the album, e.g. with a genre or mood (repeatable, or comma-separated)")
+	similarity := addFlags.String("similarity", "", "Ask before adding an album at least this similar to one in the queue, e.g. 0.9 (default from config)")
 
 	addFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s add [flags] \"Artist - Album\"\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Add a single album to the queue.\n\n")
 		fmt.Fprintf(os.Stderr, "Arguments:\n")
-		fmt.Fprintf(os.Stderr, "  \"Artist - Album\"  Album to add in 'Artist - Album' format\n\n")
+		fmt.Fprintf(os.Stderr, "  \"Artist - Album\"  Album to add in 'Artist - Album' format; quote an artist that contains ' - '\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		addFlags.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s add \"The Beatles - Abbey Road\"\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s add --queue /custom/path/queue.txt \"Pink Floyd - The Wall\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s add --year 1969 --notes \"recommended by Sam\" \"The Beatles - Abbey Road\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s add --priority 5 \"Radiohead - In Rainbows\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s add --tag jazz --tag vinyl \"Miles Davis - Kind of Blue\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s add --similarity 0.85 \"Beatles - Abey Road\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s add '\"Everything - Everything\" - Get to Heaven'\n", os.Args[0])
 	}
 
 	// Parse add command arguments
@@ -155,12 +675,41 @@ func handleAddCommand() {
 
 	albumTitle := addFlags.Arg(0)
 
+	album, err := queue.ParseAlbum(albumTitle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *priority < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --priority must be zero or greater\n")
+		os.Exit(1)
+	}
+	album.Year = *year
+	album.Notes = *notes
+	album.Priority = *priority
+	album.Tags = queue.NormalizeTags(tags...)
+
 	// Create storage and queue service
 	queueStorage := storage.NewFileStorage(*queuePath)
-	queueService := queue.NewQueue(queueStorage)
+	options, err := similarityOptions(*similarity, queueStorage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	queueService := queue.NewQueue(queueStorage, append(options, queue.WithLockTimeout(*lockTimeout))...)
 
 	// Add the album
-	err = queueService.AddAlbum(albumTitle)
+	err = queueService.Add(album)
+
+	// Let the user decide whether a near duplicate is a different album
+	var nearDuplicate *queue.NearDuplicateError
+	if errors.As(err, &nearDuplicate) {
+		if !confirm(fmt.Sprintf("'%s' looks like '%s' already in the queue (%.0f%% similar). Add anyway?", nearDuplicate.Album, nearDuplicate.Match, nearDuplicate.Similarity*100)) {
+			fmt.Println("Nothing added.")
+			return
+		}
+		err = queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout)).Add(album)
+	}
 	if err != nil {
 		// Handle duplicate album as an informational message, not an error
 		if strings.Contains(err.Error(), "already exists") {
@@ -188,15 +737,27 @@ func handleNextCommand() {
 	// Set up flag parsing for next command
 	nextFlags := flag.NewFlagSet("next", flag.ExitOnError)
 	queuePath := nextFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := nextFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	selection := addSelectionFlags(nextFlags)
+	dryRun := nextFlags.Bool("dry-run", false, "Show the album that would be picked without removing it")
+	interactive := nextFlags.Bool("interactive", false, "Ask before taking the album, and offer another one when it is rejected")
 
 	nextFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s next [flags]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Get a random album from the queue and remove it.\n\n")
+		fmt.Fprintf(os.Stderr, "Get a random album from the queue and remove it, or pick one with another --mode.\n\n")
+		printSelectionHelp()
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		nextFlags.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s next\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s next --queue /custom/path/queue.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --mode fifo\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --seed 42\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --avoid-recent 3\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --avoid-within 2w\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --tag jazz --exclude-tag long\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --dry-run\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s next --interactive\n", os.Args[0])
 	}
 
 	// Parse next command arguments
@@ -205,9 +766,35 @@ func handleNextCommand() {
 		os.Exit(1)
 	}
 
+	if *dryRun && *interactive {
+		fmt.Fprintf(os.Stderr, "Error: --dry-run and --interactive cannot be combined\n")
+		os.Exit(1)
+	}
+
 	// Create storage and queue service
 	queueStorage := storage.NewFileStorage(*queuePath)
-	queueService := queue.NewQueue(queueStorage)
+	options, err := selection.options(queueStorage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	queueService := queue.NewQueue(queueStorage, append(options, queue.WithLockTimeout(*lockTimeout))...)
+
+	if *dryRun {
+		pick, err := queueService.Suggest()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Would pick: %s (%s)\n", pick.Album, pickDetails(pick.Method, pick.Seed))
+		return
+	}
+
+	if *interactive {
+		runInteractiveNext(queueService)
+		return
+	}
 
 	// Get next album
 	album, err := queueService.GetNextAlbum()
@@ -220,74 +807,1157 @@ func handleNextCommand() {
 	fmt.Printf("Now listening: %s\n", album)
 }
 
-func handleListCommand() {
-	// Set up flag parsing for list command
-	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
-	queuePath := listFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+// runInteractiveNext offers suggestions one at a time on standard input until
+// one is accepted, every album has been rejected, or the user quits
+func runInteractiveNext(queueService *queue.QueueService) {
+	var rejected []queue.Album
 
-	listFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s list [flags]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "List all albums currently in the queue.\n\n")
+	for {
+		pick, err := queueService.Suggest(rejected...)
+		if errors.Is(err, queue.ErrNoMoreSuggestions) {
+			fmt.Println("No more albums to suggest; the queue is unchanged.")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Suggestion: %s\n", pick.Album)
+		fmt.Print("[a]ccept, [r]eroll or [q]uit? ")
+
+		if !stdin.Scan() {
+			fmt.Println()
+			return
+		}
+
+		switch strings.ToLower(strings.TrimSpace(stdin.Text())) {
+		case "a", "accept", "y", "yes":
+			album, err := queueService.Accept(pick)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Now listening: %s\n", album)
+			return
+		case "r", "reroll", "n", "no":
+			rejected = append(rejected, pick.Album)
+		case "q", "quit":
+			return
+		default:
+			fmt.Println("Please answer a, r or q.")
+		}
+	}
+}
+
+func handlePeekCommand() {
+	// Set up flag parsing for peek command
+	peekFlags := flag.NewFlagSet("peek", flag.ExitOnError)
+	queuePath := peekFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	count := peekFlags.Int("n", 1, "Number of suggestions to show")
+	selection := addSelectionFlags(peekFlags)
+
+	peekFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s peek [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show the album next would pick, without changing the queue or history.\n")
+		fmt.Fprintf(os.Stderr, "With -n, further suggestions show what rerolling would offer in turn.\n\n")
+		printSelectionHelp()
 		fmt.Fprintf(os.Stderr, "Flags:\n")
-		listFlags.PrintDefaults()
+		peekFlags.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  %s list\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s list --queue /custom/path/queue.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s peek\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s peek -n 5 --mode shuffle\n", os.Args[0])
 	}
 
-	// Parse list command arguments
-	err := listFlags.Parse(os.Args[2:])
+	// Parse peek command arguments
+	err := peekFlags.Parse(os.Args[2:])
 	if err != nil {
 		os.Exit(1)
 	}
 
+	if *count < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -n must be at least 1\n")
+		os.Exit(1)
+	}
+
 	// Create storage and queue service
 	queueStorage := storage.NewFileStorage(*queuePath)
-	queueService := queue.NewQueue(queueStorage)
-
-	// Get the album list
-	albums, err := queueService.ListAlbums()
+	options, err := selection.options(queueStorage)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	queueService := queue.NewQueue(queueStorage, options...)
 
-	// Check if queue is empty
-	if len(albums) == 0 {
-		fmt.Println("The queue is empty.")
-		return
+	picks, err := queueService.Peek(*count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Print the numbered list
-	for i, album := range albums {
-		fmt.Printf("%d. %s\n", i+1, album)
+	for i, pick := range picks {
+		fmt.Printf("%d. %s (%s)\n", i+1, pick.Album, pickDetails(pick.Method, pick.Seed))
 	}
 }
 
-func handleCountCommand() {
-	// Set up flag parsing for count command
-	countFlags := flag.NewFlagSet("count", flag.ExitOnError)
-	queuePath := countFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+// stdin reads answers to questions; it is shared so that buffered input is
+// not lost between questions
+var stdin = bufio.NewScanner(os.Stdin)
 
-	countFlags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s count [flags]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Show the number of albums currently in the queue.\n\n")
-		fmt.Fprintf(os.Stderr, "Flags:\n")
-		countFlags.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  %s count\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s count --queue /custom/path/queue.txt\n", os.Args[0])
+// confirm asks a yes/no question on standard input and reports whether the
+// answer was yes. No answer counts as no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer := ""
+	if stdin.Scan() {
+		answer = strings.ToLower(strings.TrimSpace(stdin.Text()))
+	} else {
+		fmt.Println()
 	}
+	return answer == "y" || answer == "yes"
+}
 
-	// Parse count command arguments
-	err := countFlags.Parse(os.Args[2:])
+// similarityOptions validates a --similarity value and returns the matching
+// queue options. Without a value, the threshold configured for the queue is
+// used.
+func similarityOptions(value string, queueStorage storage.Store) ([]queue.Option, error) {
+	similarity, err := queue.ParseSimilarity(value)
 	if err != nil {
-		os.Exit(1)
+		return nil, fmt.Errorf("invalid --similarity %q: %w", value, err)
 	}
 
-	// Create storage and queue service
-	queueStorage := storage.NewFileStorage(*queuePath)
-	queueService := queue.NewQueue(queueStorage)
+	if value == "" {
+		config, err := queue.NewQueue(queueStorage).LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+		similarity = config.Similarity
+	}
+
+	if similarity == 0 {
+		return nil, nil
+	}
+	return []queue.Option{queue.WithSimilarity(similarity)}, nil
+}
+
+// selectionFlags holds the flags shared by the commands that pick albums
+type selectionFlags struct {
+	mode        *string
+	seed        *string
+	avoidRecent *int
+	avoidWithin *string
+	tags        *stringsFlag
+	excludeTags *stringsFlag
+}
+
+// addSelectionFlags registers the selection flags on a command's flag set
+func addSelectionFlags(flags *flag.FlagSet) selectionFlags {
+	selection := selectionFlags{
+		mode:        flags.String("mode", "", "Selection mode: "+strings.Join(queue.Modes(), ", ")+" (default from config, else random)"),
+		seed:        flags.String("seed", "", "Seed for the random choice, e.g. one shown by history to replay a pick"),
+		avoidRecent: flags.Int("avoid-recent", 0, "Avoid artists picked within the last N picks"),
+		avoidWithin: flags.String("avoid-within", "", "Avoid artists picked within this long, e.g. 3d or 1w"),
+		tags:        &stringsFlag{},
+		excludeTags: &stringsFlag{},
+	}
+	flags.Var(selection.tags, "tag", "Only pick albums with this tag (repeatable; all must match)")
+	flags.Var(selection.excludeTags, "exclude-tag", "Never pick albums with this tag (repeatable)")
+	return selection
+}
+
+// options validates the selection flags and builds the matching queue
+// options. Without --mode, the mode configured for the queue is used.
+func (f selectionFlags) options(queueStorage storage.Store) ([]queue.Option, error) {
+	if *f.avoidRecent < 0 {
+		return nil, fmt.Errorf("--avoid-recent must be zero or greater")
+	}
+
+	var within time.Duration
+	if *f.avoidWithin != "" {
+		var err error
+		within, err = parseDuration(*f.avoidWithin)
+		if err != nil {
+			return nil, fmt.Errorf("--avoid-within: %w", err)
+		}
+	}
+
+	// Fall back to the configured default mode
+	mode := *f.mode
+	if mode == "" {
+		config, err := queue.NewQueue(queueStorage).LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+		mode = config.Mode
+	}
+	if mode == "" {
+		mode = queue.ModeRandom
+	}
+
+	selector, err := queue.NewSelector(mode, queueStorage)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []queue.Option{queue.WithSelector(selector)}
+	if *f.seed != "" {
+		seed, err := strconv.ParseInt(*f.seed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --seed %q: must be a whole number", *f.seed)
+		}
+		options = append(options, queue.WithSeed(seed))
+	}
+	if *f.avoidRecent > 0 || within > 0 {
+		options = append(options, queue.WithRules(queue.ArtistDiversityRule{RecentPicks: *f.avoidRecent, Within: within}))
+	}
+	if len(*f.tags) > 0 || len(*f.excludeTags) > 0 {
+		options = append(options, queue.WithTagFilter(queue.TagFilter{Include: *f.tags, Exclude: *f.excludeTags}))
+	}
+
+	return options, nil
+}
+
+// printSelectionHelp describes the selection modes and rules in command help
+func printSelectionHelp() {
+	fmt.Fprintf(os.Stderr, "Modes:\n")
+	fmt.Fprintf(os.Stderr, "  random       Random, in proportion to priority (see the prioritize command)\n")
+	fmt.Fprintf(os.Stderr, "  fifo         First album in the queue\n")
+	fmt.Fprintf(os.Stderr, "  lifo         Last album in the queue\n")
+	fmt.Fprintf(os.Stderr, "  oldest       Album with the earliest added date\n")
+	fmt.Fprintf(os.Stderr, "  shuffle      Shuffled pass through the whole queue before any new order\n")
+	fmt.Fprintf(os.Stderr, "  round-robin  One album per artist, artists in alphabetical order\n\n")
+	fmt.Fprintf(os.Stderr, "Set the default mode with '%s config mode <mode>'.\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "With --avoid-recent or --avoid-within, artists picked recently are skipped\n")
+	fmt.Fprintf(os.Stderr, "unless every remaining album is by one of them.\n")
+	fmt.Fprintf(os.Stderr, "With --tag or --exclude-tag, only albums with (or without) those tags are picked.\n\n")
+}
+
+func handlePrioritizeCommand() {
+	// Set up flag parsing for prioritize command
+	prioritizeFlags := flag.NewFlagSet("prioritize", flag.ExitOnError)
+	queuePath := prioritizeFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := prioritizeFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+
+	prioritizeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s prioritize [flags] <album> <priority>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Set the selection weight of an album in the queue.\n")
+		fmt.Fprintf(os.Stderr, "An album with priority 5 is picked five times as often as one with the default of 1.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <album>     Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n")
+		fmt.Fprintf(os.Stderr, "  <priority>  New priority; 0 resets it to the default\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		prioritizeFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s prioritize 3 10\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s prioritize \"Radiohead - In Rainbows\" 5\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s prioritize rainbows 0\n", os.Args[0])
+	}
+
+	// Parse prioritize command arguments
+	err := prioritizeFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if prioritizeFlags.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Error: Album and priority must be specified\n\n")
+		prioritizeFlags.Usage()
+		os.Exit(1)
+	}
+
+	priority, err := strconv.Atoi(prioritizeFlags.Arg(1))
+	if err != nil || priority < 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid priority %q: must be a whole number, zero or greater\n", prioritizeFlags.Arg(1))
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	album, err := queueService.SetPriority(prioritizeFlags.Arg(0), priority)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Priority of '%s' set to %d\n", album, album.EffectivePriority())
+}
+
+func handleSkipCommand() {
+	// Set up flag parsing for skip command
+	skipFlags := flag.NewFlagSet("skip", flag.ExitOnError)
+	queuePath := skipFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := skipFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	demote := skipFlags.Bool("demote", false, "Halve the album's chance of being picked again")
+
+	skipFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s skip [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Put the album last picked by next back at the end of the queue.\n")
+		fmt.Fprintf(os.Stderr, "The pick is marked as skipped in the history and no longer counts as played.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		skipFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s skip\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s skip --demote\n", os.Args[0])
+	}
+
+	// Parse skip command arguments
+	err := skipFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	album, err := queueService.Skip(*demote)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *demote {
+		fmt.Printf("Skipped: %s is back in the queue with weight %g\n", album, album.Weight())
+	} else {
+		fmt.Printf("Skipped: %s is back in the queue\n", album)
+	}
+}
+
+func handleSnoozeCommand() {
+	// Set up flag parsing for snooze command
+	snoozeFlags := flag.NewFlagSet("snooze", flag.ExitOnError)
+	queuePath := snoozeFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := snoozeFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	snoozeFor := snoozeFlags.String("for", "", "How long to snooze, e.g. 3d, 2w or 12h")
+	snoozeUntil := snoozeFlags.String("until", "", "Snooze until this date (YYYY-MM-DD) or RFC 3339 time")
+	wake := snoozeFlags.Bool("wake", false, "Make a snoozed album eligible again now")
+
+	snoozeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s snooze [flags] <album>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Hide an album from next until a later date. It becomes eligible again on its own.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <album>  Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		snoozeFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s snooze --for 2w \"Radiohead - In Rainbows\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s snooze --until 2024-12-01 3\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s snooze --wake rainbows\n", os.Args[0])
+	}
+
+	// Parse snooze command arguments
+	err := snoozeFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if snoozeFlags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Album not specified\n\n")
+		snoozeFlags.Usage()
+		os.Exit(1)
+	}
+
+	given := 0
+	for _, set := range []bool{*snoozeFor != "", *snoozeUntil != "", *wake} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		fmt.Fprintf(os.Stderr, "Error: exactly one of --for, --until or --wake is required\n")
+		os.Exit(1)
+	}
+
+	var until time.Time
+	now := time.Now()
+	switch {
+	case *snoozeFor != "":
+		duration, err := parseDuration(*snoozeFor)
+		if err != nil || duration == 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --for %q: use a duration such as 3d, 2w or 12h\n", *snoozeFor)
+			os.Exit(1)
+		}
+		until = now.Add(duration)
+	case *snoozeUntil != "":
+		until, err = parseTimeFlag(*snoozeUntil, now, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --until: %v\n", err)
+			os.Exit(1)
+		}
+		if !until.After(now) {
+			fmt.Fprintf(os.Stderr, "Error: --until must be in the future\n")
+			os.Exit(1)
+		}
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	album, err := queueService.Snooze(snoozeFlags.Arg(0), until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if until.IsZero() {
+		fmt.Printf("Woke up: %s\n", album)
+	} else {
+		fmt.Printf("Snoozed: %s until %s\n", album, until.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func handleRemoveCommand() {
+	// Set up flag parsing for remove command
+	removeFlags := flag.NewFlagSet("remove", flag.ExitOnError)
+	queuePath := removeFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := removeFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	yes := removeFlags.Bool("yes", false, "Remove several matching albums without asking for confirmation")
+
+	removeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s remove [flags] <index|pattern>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Remove albums from the queue without listening to them.\n")
+		fmt.Fprintf(os.Stderr, "When the pattern matches more than one album, you are asked to confirm.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <index|pattern>  Position shown by 'list', full \"Artist - Album\" text,\n")
+		fmt.Fprintf(os.Stderr, "                   part of it, or a wildcard pattern such as \"Radiohead - *\"\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		removeFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s remove 3\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s remove \"Radiohead - In Rainbows\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s remove --yes \"Radiohead - *\"\n", os.Args[0])
+	}
+
+	// Parse remove command arguments
+	err := removeFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if removeFlags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Album not specified\n\n")
+		removeFlags.Usage()
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	matches, err := queueService.Match(removeFlags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(matches) > 1 && !*yes {
+		fmt.Printf("'%s' matches %d albums:\n", removeFlags.Arg(0), len(matches))
+		for _, album := range matches {
+			fmt.Printf("  %s\n", album)
+		}
+		if !confirm(fmt.Sprintf("Remove %d albums?", len(matches))) {
+			fmt.Println("Nothing removed.")
+			return
+		}
+	}
+
+	removed, err := queueService.Remove(matches...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, album := range removed {
+		fmt.Printf("Removed: %s\n", album)
+	}
+}
+
+func handleEditCommand() {
+	// Set up flag parsing for edit command
+	editFlags := flag.NewFlagSet("edit", flag.ExitOnError)
+	queuePath := editFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := editFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	artist := editFlags.String("artist", "", "Change only the artist")
+	title := editFlags.String("title", "", "Change only the album title")
+
+	editFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s edit [flags] <album> [\"New Artist - New Album\"]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Correct the artist or title of an album in the queue.\n")
+		fmt.Fprintf(os.Stderr, "The album keeps its position, priority and other details. 'rename' is an alias.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <album>  Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		editFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s edit 3 \"Radiohead - In Rainbows\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s edit --title \"Kid A\" \"Radiohead - Kid B\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s rename --artist \"The Beatles\" abbey\n", os.Args[0])
+	}
+
+	// Parse edit command arguments
+	err := editFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	byFlags := *artist != "" || *title != ""
+	switch {
+	case editFlags.NArg() == 0:
+		fmt.Fprintf(os.Stderr, "Error: Album not specified\n\n")
+		editFlags.Usage()
+		os.Exit(1)
+	case byFlags && editFlags.NArg() != 1:
+		fmt.Fprintf(os.Stderr, "Error: give either the new \"Artist - Album\" text or --artist/--title, not both\n")
+		os.Exit(1)
+	case !byFlags && editFlags.NArg() != 2:
+		fmt.Fprintf(os.Stderr, "Error: New \"Artist - Album\" text not specified\n\n")
+		editFlags.Usage()
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	var album queue.Album
+	if byFlags {
+		album, err = queueService.Rename(editFlags.Arg(0), *artist, *title)
+	} else {
+		album, err = queueService.Edit(editFlags.Arg(0), editFlags.Arg(1))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Updated: %s\n", album)
+}
+
+func handleMoveCommand() {
+	// Set up flag parsing for move command
+	moveFlags := flag.NewFlagSet("move", flag.ExitOnError)
+	queuePath := moveFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := moveFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+
+	moveFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s move [flags] <album> <position>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Move an album to another position in the queue.\n")
+		fmt.Fprintf(os.Stderr, "The order decides what the fifo and lifo modes of next pick.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <album>     Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n")
+		fmt.Fprintf(os.Stderr, "  <position>  New position, 1 being the top of the queue\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		moveFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s move 7 1\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s move rainbows 3\n", os.Args[0])
+	}
+
+	// Parse move command arguments
+	err := moveFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if moveFlags.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Error: Album and position must be specified\n\n")
+		moveFlags.Usage()
+		os.Exit(1)
+	}
+
+	position, err := strconv.Atoi(moveFlags.Arg(1))
+	if err != nil || position < 1 {
+		fmt.Fprintf(os.Stderr, "Error: invalid position %q: must be a whole number, 1 or greater\n", moveFlags.Arg(1))
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	album, err := queueService.Move(moveFlags.Arg(0), position)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Moved '%s' to position %d\n", album, position)
+}
+
+// handlePinCommand handles both pin and unpin, which differ only in the
+// state they set
+func handlePinCommand(command string) {
+	// Set up flag parsing for pin command
+	pinFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	queuePath := pinFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := pinFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+
+	pinFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] <album>\n\n", os.Args[0], command)
+		if command == "pin" {
+			fmt.Fprintf(os.Stderr, "Move an album to the top of the queue and have next pick it regardless of the mode.\n")
+			fmt.Fprintf(os.Stderr, "With several pinned albums, the most recently pinned comes first.\n\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Let next choose a pinned album by its mode again. The album keeps its position.\n\n")
+		}
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <album>  Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		pinFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s %s \"Radiohead - In Rainbows\"\n", os.Args[0], command)
+		fmt.Fprintf(os.Stderr, "  %s %s 4\n", os.Args[0], command)
+	}
+
+	// Parse pin command arguments
+	err := pinFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if pinFlags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Album not specified\n\n")
+		pinFlags.Usage()
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	album, err := queueService.Pin(pinFlags.Arg(0), command == "pin")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if album.Pinned {
+		fmt.Printf("Pinned: %s will be picked next\n", album)
+	} else {
+		fmt.Printf("Unpinned: %s\n", album)
+	}
+}
+
+func handlePlayCommand() {
+	// Set up flag parsing for play command
+	playFlags := flag.NewFlagSet("play", flag.ExitOnError)
+	queuePath := playFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := playFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+
+	playFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s play [flags] <index|query>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Take a specific album out of the queue and record it in the history as a manual pick.\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <index|query>  Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		playFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s play 2\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s play \"abbey road\"\n", os.Args[0])
+	}
+
+	// Parse play command arguments
+	err := playFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if playFlags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Album not specified\n\n")
+		playFlags.Usage()
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	album, err := queueService.Play(playFlags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Now listening: %s\n", album)
+}
+
+// handleTagCommand handles both tag and untag, which differ only in whether
+// the given tags are added or removed
+func handleTagCommand(command string) {
+	// Set up flag parsing for tag command
+	tagFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	queuePath := tagFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := tagFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+
+	tagFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] <album> <tag>...\n\n", os.Args[0], command)
+		if command == "tag" {
+			fmt.Fprintf(os.Stderr, "Add free-form tags, such as genres or moods, to an album in the queue.\n")
+			fmt.Fprintf(os.Stderr, "Tags are case-insensitive; use 'next --tag' to pick among tagged albums.\n\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Remove tags from an album in the queue.\n\n")
+		}
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  <album>  Position shown by 'list', full \"Artist - Album\" text, or a unique part of it\n")
+		fmt.Fprintf(os.Stderr, "  <tag>    One or more tags; commas also separate tags\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		tagFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s %s \"Miles Davis - Kind of Blue\" jazz 1959 vinyl\n", os.Args[0], command)
+		fmt.Fprintf(os.Stderr, "  %s %s 4 late-night\n", os.Args[0], command)
+	}
+
+	// Parse tag command arguments
+	err := tagFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if tagFlags.NArg() < 2 {
+		fmt.Fprintf(os.Stderr, "Error: Album and at least one tag must be specified\n\n")
+		tagFlags.Usage()
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	retag := queueService.Tag
+	if command == "untag" {
+		retag = queueService.Untag
+	}
+
+	album, err := retag(tagFlags.Arg(0), tagFlags.Args()[1:]...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(album.Tags) == 0 {
+		fmt.Printf("Tags of '%s': none\n", album)
+	} else {
+		fmt.Printf("Tags of '%s': %s\n", album, strings.Join(album.Tags, ", "))
+	}
+}
+
+func handleTagsCommand() {
+	// Set up flag parsing for tags command
+	tagsFlags := flag.NewFlagSet("tags", flag.ExitOnError)
+	queuePath := tagsFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+
+	tagsFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s tags [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List the tags used in the queue with the number of albums carrying each.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		tagsFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s tags\n", os.Args[0])
+	}
+
+	// Parse tags command arguments
+	err := tagsFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage)
+
+	counts, err := queueService.Tags()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(counts) == 0 {
+		fmt.Println("No album in the queue is tagged.")
+		return
+	}
+
+	for _, count := range counts {
+		fmt.Printf("%-20s %d\n", count.Tag, count.Count)
+	}
+}
+
+func handleDedupeCommand() {
+	// Set up flag parsing for dedupe command
+	dedupeFlags := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	queuePath := dedupeFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := dedupeFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	similarity := dedupeFlags.String("similarity", "", "Also group albums at least this similar, e.g. 0.9 (default from config)")
+	auto := dedupeFlags.Bool("auto", false, "Merge every group of duplicates without asking")
+	removeListened := dedupeFlags.Bool("remove-listened", false, "With --auto, also remove albums already listened to")
+
+	dedupeFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s dedupe [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Find duplicate albums in the queue and merge each group into one album.\n")
+		fmt.Fprintf(os.Stderr, "The album added first is kept; tags are combined and missing details filled in\n")
+		fmt.Fprintf(os.Stderr, "from the others. Albums already in the listening history are listed too.\n")
+		fmt.Fprintf(os.Stderr, "You are asked about each group unless --auto is given. 'undo' reverses the merge.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		dedupeFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s dedupe\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s dedupe --similarity 0.9\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s dedupe --auto --remove-listened\n", os.Args[0])
+	}
+
+	// Parse dedupe command arguments
+	err := dedupeFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	options, err := similarityOptions(*similarity, queueStorage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	queueService := queue.NewQueue(queueStorage, append(options, queue.WithLockTimeout(*lockTimeout))...)
+
+	groups, err := queueService.Duplicates()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	listened, err := queueService.Listened()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(groups) == 0 && len(listened) == 0 {
+		fmt.Println("No duplicates found.")
+		return
+	}
+
+	var merge [][]queue.Album
+	for i, group := range groups {
+		if group.Similarity < 1 {
+			fmt.Printf("Duplicates %d (%.0f%% similar):\n", i+1, group.Similarity*100)
+		} else {
+			fmt.Printf("Duplicates %d:\n", i+1)
+		}
+		for _, album := range group.Albums {
+			fmt.Printf("  %s\n", album)
+		}
+
+		if *auto || confirm(fmt.Sprintf("Merge into '%s'?", queue.MergeAlbums(group.Albums))) {
+			merge = append(merge, group.Albums)
+		}
+	}
+
+	if len(merge) > 0 {
+		merged, err := queueService.Merge(merge...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for i, album := range merged {
+			fmt.Printf("Merged %d albums into: %s\n", len(merge[i]), album)
+		}
+	}
+
+	if len(listened) == 0 {
+		return
+	}
+
+	fmt.Printf("Already listened to:\n")
+	albums := make([]queue.Album, len(listened))
+	for i, entry := range listened {
+		albums[i] = entry.Album
+		if entry.Played.PickedAt.IsZero() {
+			fmt.Printf("  %s\n", entry.Album)
+		} else {
+			fmt.Printf("  %s (picked %s)\n", entry.Album, entry.Played.PickedAt.Local().Format("2006-01-02"))
+		}
+	}
+
+	remove := *removeListened
+	if !*auto {
+		remove = confirm(fmt.Sprintf("Remove %d albums already listened to?", len(albums)))
+	}
+	if !remove {
+		return
+	}
+
+	// Merging may have replaced some of these albums; remove them by their
+	// current version
+	current, err := queueService.ListAlbums()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var stillQueued []queue.Album
+	for _, album := range current {
+		for _, listenedAlbum := range albums {
+			if album.MatchKey() == listenedAlbum.MatchKey() {
+				stillQueued = append(stillQueued, album)
+				break
+			}
+		}
+	}
+
+	if len(stillQueued) == 0 {
+		return
+	}
+
+	removed, err := queueService.Remove(stillQueued...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, album := range removed {
+		fmt.Printf("Removed: %s\n", album)
+	}
+}
+
+func handleListCommand() {
+	// Set up flag parsing for list command
+	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
+	queuePath := listFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	details := listFlags.Bool("details", false, "Show album metadata (added date, source, year, priority, tags, notes)")
+	artist := listFlags.String("artist", "", "Only show albums whose artist matches this pattern")
+	var filters stringsFlag
+	listFlags.Var(&filters, "filter", "Only show albums matching a condition such as title~live or year=1997 (repeatable)")
+	display := addDisplayFlags(listFlags)
+
+	listFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s list [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List all albums currently in the queue.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		listFlags.PrintDefaults()
+		printFilterHelp()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s list\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list --queue /custom/path/queue.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list --details\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list --artist radiohead --sort added\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list --filter \"title~live\" --filter \"year=199?\" --limit 10\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s list --filter tag=jazz\n", os.Args[0])
+	}
+
+	// Parse list command arguments
+	err := listFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	var conditions []queue.Condition
+	if *artist != "" {
+		conditions = append(conditions, queue.Condition{Field: queue.FilterArtist, Pattern: *artist})
+	}
+	for _, text := range filters {
+		condition, err := queue.ParseCondition(text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	listAlbums(*queuePath, display.filter(conditions), *details, "The queue is empty.")
+}
+
+func handleSearchCommand() {
+	// Set up flag parsing for search command
+	searchFlags := flag.NewFlagSet("search", flag.ExitOnError)
+	queuePath := searchFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	details := searchFlags.Bool("details", false, "Show album metadata (added date, source, year, priority, tags, notes)")
+	display := addDisplayFlags(searchFlags)
+
+	searchFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s search [flags] <query>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List the albums whose \"Artist - Album\" text or notes match the query.\n")
+		fmt.Fprintf(os.Stderr, "The query matches as a case-insensitive substring, a glob with *, ? or [...],\n")
+		fmt.Fprintf(os.Stderr, "or a regular expression written as /expr/.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		searchFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s search radiohead\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s search \"*live*\" --sort artist\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s search \"/^the (beatles|kinks)/\"\n", os.Args[0])
+	}
+
+	// Parse search command arguments
+	err := searchFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if searchFlags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Query not specified\n\n")
+		searchFlags.Usage()
+		os.Exit(1)
+	}
+
+	conditions := []queue.Condition{{Pattern: searchFlags.Arg(0)}}
+	listAlbums(*queuePath, display.filter(conditions), *details, fmt.Sprintf("No album in the queue matches '%s'.", searchFlags.Arg(0)))
+}
+
+// displayFlags holds the ordering flags shared by list and search
+type displayFlags struct {
+	sort  *string
+	limit *int
+}
+
+// addDisplayFlags registers --sort and --limit on flags
+func addDisplayFlags(flags *flag.FlagSet) displayFlags {
+	return displayFlags{
+		sort:  flags.String("sort", queue.SortQueue, "Order of the albums: "+strings.Join(queue.SortOrders(), ", ")),
+		limit: flags.Int("limit", 0, "Show at most this many albums (0 for all)"),
+	}
+}
+
+// filter returns the album filter for conditions and the parsed flags
+func (f displayFlags) filter(conditions []queue.Condition) queue.AlbumFilter {
+	return queue.AlbumFilter{Conditions: conditions, Sort: *f.sort, Limit: *f.limit}
+}
+
+// printFilterHelp describes the conditions accepted by --filter
+func printFilterHelp() {
+	fmt.Fprintf(os.Stderr, "\nFilters:\n")
+	fmt.Fprintf(os.Stderr, "  field~pattern   Field matches the pattern; field!~pattern for the opposite\n")
+	fmt.Fprintf(os.Stderr, "  field=value     Field equals the value, ignoring case; field!=value for the opposite\n")
+	fmt.Fprintf(os.Stderr, "  Fields: %s\n", strings.Join(queue.FilterFields(), ", "))
+	fmt.Fprintf(os.Stderr, "  Patterns match as a case-insensitive substring, a glob with *, ? or [...],\n")
+	fmt.Fprintf(os.Stderr, "  or a regular expression written as /expr/.\n")
+}
+
+// stringsFlag collects the values of a flag that may be given several times
+type stringsFlag []string
+
+// String implements flag.Value
+func (s *stringsFlag) String() string { return strings.Join(*s, ", ") }
+
+// Set implements flag.Value
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// listAlbums prints the albums of the queue at queuePath selected by filter,
+// numbered by queue position so that the numbers can be used to refer to
+// albums. Snoozed albums are listed separately. empty is printed when
+// nothing is selected.
+func listAlbums(queuePath string, filter queue.AlbumFilter, details bool, empty string) {
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(queuePath)
+	queueService := queue.NewQueue(queueStorage)
+
+	// Get the album list
+	albums, err := queueService.ListAlbums()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Check if queue is empty
+	if len(albums) == 0 {
+		fmt.Println("The queue is empty.")
+		return
+	}
+
+	selected, err := queue.FilterAlbums(albums, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(selected) == 0 {
+		fmt.Println(empty)
+		return
+	}
+
+	now := time.Now()
+	var snoozed []int
+	for _, i := range selected {
+		album := albums[i]
+		if album.Snoozed(now) {
+			snoozed = append(snoozed, i)
+			continue
+		}
+		if album.Pinned {
+			fmt.Printf("%d. %s [pinned]\n", i+1, album)
+		} else {
+			fmt.Printf("%d. %s\n", i+1, album)
+		}
+		if details {
+			printAlbumDetails(album)
+		}
+	}
+
+	if len(snoozed) > 0 {
+		if len(snoozed) < len(selected) {
+			fmt.Println()
+		}
+		fmt.Printf("Snoozed:\n")
+		for _, i := range snoozed {
+			fmt.Printf("%d. %s (until %s)\n", i+1, albums[i], albums[i].SnoozedUntil.Local().Format("2006-01-02 15:04"))
+			if details {
+				printAlbumDetails(albums[i])
+			}
+		}
+	}
+}
+
+// printAlbumDetails prints the metadata of an album, indented under its list entry
+func printAlbumDetails(album queue.Album) {
+	if album.Year != 0 {
+		fmt.Printf("   Year:   %d\n", album.Year)
+	}
+	if !album.AddedAt.IsZero() {
+		fmt.Printf("   Added:  %s\n", album.AddedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if album.Source != "" {
+		fmt.Printf("   Source: %s\n", album.Source)
+	}
+	if album.Priority != 0 {
+		fmt.Printf("   Priority: %d\n", album.Priority)
+	}
+	if album.Skips != 0 {
+		fmt.Printf("   Skipped: %d times (weight %g)\n", album.Skips, album.Weight())
+	}
+	if len(album.Tags) > 0 {
+		fmt.Printf("   Tags:   %s\n", strings.Join(album.Tags, ", "))
+	}
+	if album.Notes != "" {
+		fmt.Printf("   Notes:  %s\n", album.Notes)
+	}
+}
+
+func handleCountCommand() {
+	// Set up flag parsing for count command
+	countFlags := flag.NewFlagSet("count", flag.ExitOnError)
+	queuePath := countFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+
+	countFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s count [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show the number of albums currently in the queue.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		countFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s count\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s count --queue /custom/path/queue.txt\n", os.Args[0])
+	}
+
+	// Parse count command arguments
+	err := countFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage)
 
 	// Get the album count
 	count, err := queueService.CountAlbums()
@@ -300,20 +1970,302 @@ func handleCountCommand() {
 	fmt.Printf("There are %d albums in the queue.\n", count)
 }
 
+func handleHistoryCommand() {
+	// Set up flag parsing for history command
+	historyFlags := flag.NewFlagSet("history", flag.ExitOnError)
+	queuePath := historyFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	since := historyFlags.String("since", "", "Only show albums picked on or after this date (YYYY-MM-DD) or age (e.g. 7d, 2w)")
+	until := historyFlags.String("until", "", "Only show albums picked before the end of this date (YYYY-MM-DD) or age (e.g. 1d)")
+
+	historyFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s history [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List the albums you have picked from the queue, oldest first.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		historyFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s history\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s history --since 30d\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s history --since 2024-03-01 --until 2024-03-31\n", os.Args[0])
+	}
+
+	// Parse history command arguments
+	err := historyFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	sinceTime, err := parseTimeFlag(*since, now, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --since value: %v\n", err)
+		os.Exit(1)
+	}
+
+	untilTime, err := parseTimeFlag(*until, now, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --until value: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage)
+
+	// Get the history entries
+	entries, err := queueService.History(sinceTime, untilTime)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No listening history found.")
+		return
+	}
+
+	for _, entry := range entries {
+		pickedAt := "unknown date    "
+		if !entry.PickedAt.IsZero() {
+			pickedAt = entry.PickedAt.Local().Format("2006-01-02 15:04")
+		}
+
+		if entry.Event == queue.EventEdited && entry.From != "" {
+			fmt.Printf("%s  %s [%s from %s]\n", pickedAt, entry.Album, entry.Event, entry.From)
+			continue
+		}
+		if entry.Event != "" {
+			fmt.Printf("%s  %s [%s]\n", pickedAt, entry.Album, entry.Event)
+			continue
+		}
+
+		if how := pickDetails(entry.Method, entry.Seed); how != "" {
+			fmt.Printf("%s  %s (%s)\n", pickedAt, entry.Album, how)
+		} else {
+			fmt.Printf("%s  %s\n", pickedAt, entry.Album)
+		}
+	}
+}
+
+// pickDetails describes how an album was picked, such as "random, seed 42";
+// the seed is left out when the pick was not seeded
+func pickDetails(method string, seed *int64) string {
+	var how []string
+	if method != "" {
+		how = append(how, method)
+	}
+	if seed != nil {
+		how = append(how, fmt.Sprintf("seed %d", *seed))
+	}
+	return strings.Join(how, ", ")
+}
+
+func handleConfigCommand() {
+	// Set up flag parsing for config command
+	configFlags := flag.NewFlagSet("config", flag.ExitOnError)
+	queuePath := configFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := configFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+
+	configFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s config [flags] [key [value]]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show or change preferences stored next to the queue.\n")
+		fmt.Fprintf(os.Stderr, "Without arguments, all settings are shown. An empty value restores the default.\n\n")
+		fmt.Fprintf(os.Stderr, "Keys:\n")
+		fmt.Fprintf(os.Stderr, "  mode        Default selection mode for next (%s)\n", strings.Join(queue.Modes(), ", "))
+		fmt.Fprintf(os.Stderr, "  similarity  How similar (0 to 1, or a percentage) an album must be to one in the queue\n")
+		fmt.Fprintf(os.Stderr, "              for add to ask and import to skip it; empty only catches duplicates\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		configFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s config\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s config mode shuffle\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s config mode \"\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s config similarity 0.9\n", os.Args[0])
+	}
+
+	// Parse config command arguments
+	err := configFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if configFlags.NArg() > 2 {
+		configFlags.Usage()
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	var config queue.Config
+	if configFlags.NArg() == 2 {
+		config, err = queueService.SetConfig(configFlags.Arg(0), configFlags.Arg(1))
+	} else {
+		config, err = queueService.LoadConfig()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	keys := queue.ConfigKeys()
+	if configFlags.NArg() > 0 {
+		keys = []string{configFlags.Arg(0)}
+	}
+
+	for _, key := range keys {
+		value, err := config.Get(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s=%s\n", key, value)
+	}
+}
+
+func handleJournalCommand(command string) {
+	// Set up flag parsing for undo and redo commands
+	journalFlags := flag.NewFlagSet(command, flag.ExitOnError)
+	queuePath := journalFlags.String("queue", queue.GetDefaultQueuePath(), "Path to queue file")
+	lockTimeout := journalFlags.Duration("lock-timeout", queue.DefaultLockTimeout, "How long to wait for another queue command to finish")
+	steps := journalFlags.Int("n", 1, "Number of operations to "+command)
+
+	journalFlags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags]\n\n", os.Args[0], command)
+		if command == "undo" {
+			fmt.Fprintf(os.Stderr, "Reverse the most recent change to the queue, restoring the queue and history.\n")
+			fmt.Fprintf(os.Stderr, "Every command that changes the queue can be undone: add, import, next, play, prioritize,\n")
+			fmt.Fprintf(os.Stderr, "skip, snooze, remove, edit, move, pin, unpin, tag, untag and dedupe.\n\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Reapply the most recently undone operation.\n\n")
+		}
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		journalFlags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s %s\n", os.Args[0], command)
+		fmt.Fprintf(os.Stderr, "  %s %s -n 3\n", os.Args[0], command)
+	}
+
+	// Parse command arguments
+	err := journalFlags.Parse(os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	if *steps < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -n must be at least 1\n")
+		os.Exit(1)
+	}
+
+	// Create storage and queue service
+	queueStorage := storage.NewFileStorage(*queuePath)
+	queueService := queue.NewQueue(queueStorage, queue.WithLockTimeout(*lockTimeout))
+
+	replay, verb := queueService.Undo, "Undid"
+	if command == "redo" {
+		replay, verb = queueService.Redo, "Redid"
+	}
+
+	for i := 0; i < *steps; i++ {
+		entry, err := replay()
+		if err != nil {
+			if i > 0 && (errors.Is(err, queue.ErrNothingToUndo) || errors.Is(err, queue.ErrNothingToRedo)) {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s %s: %s\n", verb, entry.Op, entry.Description)
+	}
+}
+
+// parseTimeFlag parses a date flag value: an absolute date ("2006-01-02"),
+// an RFC 3339 timestamp, or an age such as "7d", "2w" or "36h" counted back
+// from now. With endOfDay set, a plain date means the end of that day so that
+// it can be used as an inclusive upper bound. An empty value yields the zero time.
+func parseTimeFlag(value string, now time.Time, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			return date.AddDate(0, 0, 1), nil
+		}
+		return date, nil
+	}
+
+	if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamp, nil
+	}
+
+	age, err := parseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (YYYY-MM-DD) or age (e.g. 7d, 2w)", value)
+	}
+
+	return now.Add(-age), nil
+}
+
+// parseDuration is time.ParseDuration with additional "d" (day) and "w" (week)
+// units, e.g. "2w" or "10d"
+func parseDuration(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, found := strings.CutSuffix(value, suffix); found {
+			count, err := strconv.Atoi(number)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	return duration, nil
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "Go Music Queue - Manage your music listening queue\n\n")
 	fmt.Fprintf(os.Stderr, "Usage: %s <command> [arguments]\n\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "Commands:\n")
 	fmt.Fprintf(os.Stderr, "  add \"Artist - Album\"  Add a single album to the queue\n")
-	fmt.Fprintf(os.Stderr, "  import <file>         Import albums from a text file\n")
-	fmt.Fprintf(os.Stderr, "  list                  List all albums in the queue\n")
+	fmt.Fprintf(os.Stderr, "  import <file>         Import albums from a text, CSV or JSON file, an M3U or PLS\n")
+	fmt.Fprintf(os.Stderr, "                        playlist or a service's data export (--format <service>),\n")
+	fmt.Fprintf(os.Stderr, "                        or from a music library with --from-dir <dir>\n")
+	fmt.Fprintf(os.Stderr, "  export                Write the queue or history as text, CSV or JSON\n")
+	fmt.Fprintf(os.Stderr, "  skip                  Put the last picked album back in the queue\n")
+	fmt.Fprintf(os.Stderr, "  snooze <album>        Hide an album from next for a while\n")
+	fmt.Fprintf(os.Stderr, "  remove <album>        Remove albums from the queue by position or pattern\n")
+	fmt.Fprintf(os.Stderr, "  edit <album> \"A - B\"  Correct the artist or title of an album\n")
+	fmt.Fprintf(os.Stderr, "  move <album> <n>      Move an album to position n in the queue\n")
+	fmt.Fprintf(os.Stderr, "  pin <album>           Have next pick an album first (unpin to undo)\n")
+	fmt.Fprintf(os.Stderr, "  play <album>          Take a specific album out of the queue\n")
+	fmt.Fprintf(os.Stderr, "  tag <album> <tag>...  Tag an album with genres or moods (untag to remove)\n")
+	fmt.Fprintf(os.Stderr, "  tags                  List the tags in use with album counts\n")
+	fmt.Fprintf(os.Stderr, "  dedupe                Find and merge duplicate albums in the queue\n")
+	fmt.Fprintf(os.Stderr, "  list                  List the albums in the queue, optionally filtered and sorted\n")
+	fmt.Fprintf(os.Stderr, "  search <query>        List the albums matching a query\n")
 	fmt.Fprintf(os.Stderr, "  next                  Get the next album in the queue\n")
+	fmt.Fprintf(os.Stderr, "  peek                  Show what next would pick, without taking it\n")
+	fmt.Fprintf(os.Stderr, "  prioritize <album> <n> Set how often an album is picked by next\n")
 	fmt.Fprintf(os.Stderr, "  count                 Show the number of albums in the queue\n")
+	fmt.Fprintf(os.Stderr, "  history               Show the albums you have picked, with dates\n")
+	fmt.Fprintf(os.Stderr, "  config [key [value]]  Show or change preferences such as the default next mode\n")
+	fmt.Fprintf(os.Stderr, "  undo                  Undo the last change to the queue\n")
+	fmt.Fprintf(os.Stderr, "  redo                  Redo the last undone operation\n")
 	fmt.Fprintf(os.Stderr, "  help                  Show this help message\n\n")
 	fmt.Fprintf(os.Stderr, "For command-specific help:\n")
 	fmt.Fprintf(os.Stderr, "  %s <command> --help\n\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "Examples:\n")
 	fmt.Fprintf(os.Stderr, "  %s add \"The Beatles - Abbey Road\"\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s import my-albums.txt\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s import playlist.m3u8\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s import --format spotify YourLibrary.json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s import --from-dir ~/Music\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s add --help\n", os.Args[0])
 }
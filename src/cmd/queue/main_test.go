@@ -1,12 +1,17 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"music-queue/src/internal/queue"
+	"music-queue/src/internal/storage"
 )
 
 // TestCLI_Import_Success tests successful album import
@@ -51,7 +56,7 @@ func TestCLI_Import_Success(t *testing.T) {
 	}
 
 	expectedAlbums := []string{"Pink Floyd - Dark Side of the Moon", "The Beatles - Abbey Road", "Pink Floyd - The Wall"}
-	queueLines := strings.Split(strings.TrimSpace(string(queueContent)), "\n")
+	queueLines := albumLines(queueContent)
 
 	if len(queueLines) != len(expectedAlbums) {
 		t.Errorf("Expected %d albums in queue, got %d", len(expectedAlbums), len(queueLines))
@@ -276,7 +281,7 @@ func TestCLI_Add_Success(t *testing.T) {
 		t.Fatalf("Failed to read queue file: %v", err)
 	}
 
-	queueLines := strings.Split(strings.TrimSpace(string(queueContent)), "\n")
+	queueLines := albumLines(queueContent)
 	if len(queueLines) != 1 {
 		t.Errorf("Expected 1 album in queue, got %d", len(queueLines))
 	}
@@ -308,7 +313,7 @@ func TestCLI_Add_Success(t *testing.T) {
 	}
 
 	expectedAlbums := []string{"The Beatles - Abbey Road", "Pink Floyd - The Wall"}
-	queueLines = strings.Split(strings.TrimSpace(string(queueContent)), "\n")
+	queueLines = albumLines(queueContent)
 
 	if len(queueLines) != len(expectedAlbums) {
 		t.Errorf("Expected %d albums in queue, got %d", len(expectedAlbums), len(queueLines))
@@ -385,7 +390,7 @@ func TestCLI_Add_Duplicate(t *testing.T) {
 		t.Fatalf("Failed to read queue file: %v", err)
 	}
 
-	queueLines := strings.Split(strings.TrimSpace(string(queueContent)), "\n")
+	queueLines := albumLines(queueContent)
 	if len(queueLines) != 1 {
 		t.Errorf("Expected 1 album in queue after duplicates, got %d", len(queueLines))
 	}
@@ -405,6 +410,7 @@ func TestCLI_Add_InvalidFormat(t *testing.T) {
 		album string
 	}{
 		{"no dash", "No Dash Here"},
+		{"unspaced dash", "Sleater-Kinney"},
 		{"missing artist", "Missing Artist"},
 		{"dash at end", "Missing Album -"},
 		{"whitespace before dash", "   - Album"},
@@ -529,7 +535,7 @@ func TestCLI_Add_WithExistingQueue(t *testing.T) {
 	}
 
 	expectedAlbums := []string{"Pink Floyd - Dark Side of the Moon", "Led Zeppelin - IV", "The Beatles - Abbey Road"}
-	queueLines := strings.Split(strings.TrimSpace(string(queueContent)), "\n")
+	queueLines := albumLines(queueContent)
 
 	if len(queueLines) != len(expectedAlbums) {
 		t.Errorf("Expected %d albums in queue, got %d", len(expectedAlbums), len(queueLines))
@@ -1060,7 +1066,7 @@ func TestCLI_Next_CreatesArchiveFile(t *testing.T) {
 		t.Fatalf("Failed to read archive file: %v", err)
 	}
 
-	archiveLines := strings.Split(strings.TrimSpace(string(archiveContent)), "\n")
+	archiveLines := albumLines(archiveContent)
 	if len(archiveLines) != 1 {
 		t.Errorf("Expected 1 album in archive, got %d", len(archiveLines))
 	}
@@ -1119,7 +1125,7 @@ func TestCLI_Next_ArchiveFileInSameDirectory(t *testing.T) {
 		t.Fatalf("Failed to read archive file: %v", err)
 	}
 
-	archiveLines := strings.Split(strings.TrimSpace(string(archiveContent)), "\n")
+	archiveLines := albumLines(archiveContent)
 	if len(archiveLines) != 1 {
 		t.Errorf("Expected 1 album in archive, got %d", len(archiveLines))
 	}
@@ -1160,7 +1166,7 @@ func TestCLI_Next_ArchiveAppendsToExistingArchive(t *testing.T) {
 		t.Fatalf("Failed to read archive file: %v", err)
 	}
 
-	archiveLines := strings.Split(strings.TrimSpace(string(archiveContent)), "\n")
+	archiveLines := albumLines(archiveContent)
 	if len(archiveLines) != 3 {
 		t.Errorf("Expected 3 albums in archive, got %d", len(archiveLines))
 	}
@@ -1221,7 +1227,7 @@ func TestCLI_Next_ArchiveHandlesEmptyArchiveFile(t *testing.T) {
 		t.Fatalf("Failed to read archive file: %v", err)
 	}
 
-	archiveLines := strings.Split(strings.TrimSpace(string(archiveContent)), "\n")
+	archiveLines := albumLines(archiveContent)
 	if len(archiveLines) != 1 {
 		t.Errorf("Expected 1 album in archive, got %d", len(archiveLines))
 	}
@@ -1266,7 +1272,7 @@ func TestCLI_Next_ArchiveMultipleAlbums(t *testing.T) {
 		t.Fatalf("Failed to read archive file: %v", err)
 	}
 
-	archiveLines := strings.Split(strings.TrimSpace(string(archiveContent)), "\n")
+	archiveLines := albumLines(archiveContent)
 	if len(archiveLines) != 2 {
 		t.Errorf("Expected 2 albums in archive, got %d", len(archiveLines))
 	}
@@ -1282,3 +1288,1241 @@ func TestCLI_Next_ArchiveMultipleAlbums(t *testing.T) {
 		t.Errorf("Expected 1 album remaining in queue, got %d", len(queueLines))
 	}
 }
+
+// albumLines splits queue or archive file content into lines and drops the
+// tab-separated metadata fields, leaving the "Artist - Album" text of each record
+func albumLines(content []byte) []string {
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	for i, line := range lines {
+		lines[i], _, _ = strings.Cut(line, "\t")
+	}
+	return lines
+}
+
+// TestCLI_Add_WithMetadata tests that add stores year and notes and list --details shows them
+func TestCLI_Add_WithMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	cmd := exec.Command("go", "run", "main.go", "add", "--queue", queueFile, "--year", "1969", "--notes", "side B first", "The Beatles - Abbey Road")
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	// Plain list output is unchanged
+	cmd = exec.Command("go", "run", "main.go", "list", "--queue", queueFile)
+	cmd.Dir = "."
+
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr := string(output)
+	if strings.TrimSpace(outputStr) != "1. The Beatles - Abbey Road" {
+		t.Errorf("Expected plain list entry. Output: %s", outputStr)
+	}
+
+	// Detailed list shows the metadata
+	cmd = exec.Command("go", "run", "main.go", "list", "--queue", queueFile, "--details")
+	cmd.Dir = "."
+
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr = string(output)
+	for _, expected := range []string{"1. The Beatles - Abbey Road", "Year:   1969", "Source: add", "Notes:  side B first", "Added:"} {
+		if !strings.Contains(outputStr, expected) {
+			t.Errorf("Expected output to contain %q. Output: %s", expected, outputStr)
+		}
+	}
+}
+
+// TestCLI_Prioritize tests setting a priority at add time and with the prioritize command
+func TestCLI_Prioritize(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	commands := [][]string{
+		{"add", "--queue", queueFile, "--priority", "3", "Radiohead - In Rainbows"},
+		{"add", "--queue", queueFile, "Pink Floyd - The Wall"},
+		{"prioritize", "--queue", queueFile, "wall", "7"},
+	}
+	for _, args := range commands {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", args, err, output)
+		}
+
+		if args[0] == "prioritize" && !strings.Contains(string(output), "Priority of 'Pink Floyd - The Wall' set to 7") {
+			t.Errorf("Expected prioritize message. Output: %s", output)
+		}
+	}
+
+	content, err := os.ReadFile(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Radiohead - In Rainbows\t", "priority=3", "priority=7"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected queue file to contain %q, got %q", expected, content)
+		}
+	}
+
+	// Unknown albums and invalid priorities are errors
+	for _, args := range [][]string{{"prioritize", "--queue", queueFile, "Beatles", "2"}, {"prioritize", "--queue", queueFile, "1", "high"}} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Errorf("Expected CLI command %v to fail. Output: %s", args, output)
+		}
+		if !strings.Contains(string(output), "Error:") {
+			t.Errorf("Expected error message. Output: %s", output)
+		}
+	}
+}
+
+// TestCLI_Next_AvoidRecent tests that next skips the artist picked last
+func TestCLI_Next_AvoidRecent(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	archiveFile := filepath.Join(tempDir, "archive.txt")
+
+	err := os.WriteFile(queueFile, []byte("Radiohead - In Rainbows\nPink Floyd - The Wall\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(archiveFile, []byte("Radiohead - Kid A\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "next", "--queue", queueFile, "--avoid-recent", "1")
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	if !strings.Contains(string(output), "Now listening: Pink Floyd - The Wall") {
+		t.Errorf("Expected the other artist to be picked. Output: %s", output)
+	}
+
+	cmd = exec.Command("go", "run", "main.go", "next", "--queue", queueFile, "--avoid-within", "soon")
+	cmd.Dir = "."
+
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("Expected invalid --avoid-within to fail. Output: %s", output)
+	}
+}
+
+// TestCLI_Next_Mode tests choosing a selection mode per command and through config
+func TestCLI_Next_Mode(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("Radiohead - In Rainbows\nPink Floyd - The Wall\nBjörk - Homogenic\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"next", "--queue", queueFile, "--mode", "fifo"}, "Now listening: Radiohead - In Rainbows"},
+		{[]string{"config", "--queue", queueFile, "mode", "lifo"}, "mode=lifo"},
+		{[]string{"config", "--queue", queueFile}, "mode=lifo"},
+		{[]string{"next", "--queue", queueFile}, "Now listening: Björk - Homogenic"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	// The history records the mode used
+	archiveContent, err := os.ReadFile(filepath.Join(tempDir, "archive.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"method=fifo", "method=lifo"} {
+		if !strings.Contains(string(archiveContent), expected) {
+			t.Errorf("Expected archive to contain %q, got %q", expected, archiveContent)
+		}
+	}
+
+	for _, args := range [][]string{{"next", "--queue", queueFile, "--mode", "alphabetical"}, {"config", "--queue", queueFile, "mode", "alphabetical"}} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "alphabetical") {
+			t.Errorf("Expected CLI command %v to reject the mode. Output: %s", args, output)
+		}
+	}
+}
+
+// TestCLI_Next_Seed tests that a seed shown by history replays the same pick
+func TestCLI_Next_Seed(t *testing.T) {
+	tempDir := t.TempDir()
+	content := []byte("A - One\nB - Two\nC - Three\nD - Four\nE - Five\n")
+
+	var picks []string
+	for i := 0; i < 3; i++ {
+		queueFile := filepath.Join(tempDir, fmt.Sprintf("queue%d.txt", i))
+		if err := os.WriteFile(queueFile, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command("go", "run", "main.go", "next", "--queue", queueFile, "--seed", "12345")
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+		}
+		picks = append(picks, strings.TrimSpace(string(output)))
+
+		cmd = exec.Command("go", "run", "main.go", "history", "--queue", queueFile)
+		cmd.Dir = "."
+
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(string(output), "(random, seed 12345)") {
+			t.Errorf("Expected history to show the seed. Output: %s", output)
+		}
+	}
+
+	if picks[0] != picks[1] || picks[1] != picks[2] {
+		t.Errorf("Expected the same pick for the same seed, got %v", picks)
+	}
+
+	// A zero seed is recorded too, so that its pick can be replayed
+	queueFile := filepath.Join(tempDir, "queue0.txt")
+	for _, args := range [][]string{{"next", "--queue", queueFile, "--seed", "0"}, {"history", "--queue", queueFile}} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", args, err, output)
+		}
+		if args[0] == "history" && !strings.Contains(string(output), "(random, seed 0)") {
+			t.Errorf("Expected history to show seed 0. Output: %s", output)
+		}
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "next", "--queue", filepath.Join(tempDir, "queue0.txt"), "--seed", "lucky")
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "invalid --seed") {
+		t.Errorf("Expected invalid seed error. Output: %s", output)
+	}
+}
+
+// TestCLI_Next_DryRunAndPeek tests that previews leave the queue and history untouched
+func TestCLI_Next_DryRunAndPeek(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	content := []byte("A - One\nB - Two\nC - Three\n")
+
+	err := os.WriteFile(queueFile, content, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"next", "--queue", queueFile, "--dry-run", "--mode", "fifo"}, []string{"Would pick: A - One (fifo, seed "}},
+		{[]string{"peek", "--queue", queueFile, "-n", "5", "--mode", "lifo"}, []string{"1. C - Three (lifo", "2. B - Two", "3. A - One"}},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		for _, expected := range step.expected {
+			if !strings.Contains(string(output), expected) {
+				t.Errorf("Expected %v output to contain %q. Output: %s", step.args, expected, output)
+			}
+		}
+	}
+
+	queueContent, err := os.ReadFile(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(queueContent) != string(content) {
+		t.Errorf("Expected queue to be unchanged, got %q", queueContent)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "archive.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected no archive to be written, got %v", err)
+	}
+}
+
+// TestCLI_Next_Interactive tests rejecting a suggestion and accepting the next one
+func TestCLI_Next_Interactive(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("A - One\nB - Two\nC - Three\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "next", "--queue", queueFile, "--mode", "fifo", "--interactive")
+	cmd.Dir = "."
+	cmd.Stdin = strings.NewReader("r\na\n")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr := string(output)
+	for _, expected := range []string{"Suggestion: A - One", "Suggestion: B - Two", "Now listening: B - Two"} {
+		if !strings.Contains(outputStr, expected) {
+			t.Errorf("Expected output to contain %q. Output: %s", expected, outputStr)
+		}
+	}
+
+	queueContent, err := os.ReadFile(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := albumLines(queueContent); strings.Join(lines, ",") != "A - One,C - Three" {
+		t.Errorf("Expected only the accepted album to be removed, got %v", lines)
+	}
+}
+
+// TestCLI_SkipAndSnooze tests putting a pick back and hiding an album for a while
+func TestCLI_SkipAndSnooze(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("A - One\nB - Two\nC - Three\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"next", "--queue", queueFile, "--mode", "fifo"}, "Now listening: A - One"},
+		{[]string{"skip", "--queue", queueFile, "--demote"}, "Skipped: A - One is back in the queue with weight 0.5"},
+		{[]string{"snooze", "--queue", queueFile, "--for", "2w", "two"}, "Snoozed: B - Two until"},
+		{[]string{"list", "--queue", queueFile}, "2. C - Three\n3. A - One\n\nSnoozed:\n1. B - Two (until"},
+		{[]string{"next", "--queue", queueFile, "--mode", "fifo"}, "Now listening: C - Three"},
+		{[]string{"history", "--queue", queueFile}, "A - One [skipped]"},
+		{[]string{"snooze", "--queue", queueFile, "--wake", "two"}, "Woke up: B - Two"},
+		{[]string{"next", "--queue", queueFile, "--mode", "fifo"}, "Now listening: B - Two"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"snooze", "--queue", queueFile, "one"},
+		{"snooze", "--queue", queueFile, "--for", "soon", "one"},
+		{"snooze", "--queue", queueFile, "--until", "2001-01-01", "one"},
+	} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "Error:") {
+			t.Errorf("Expected CLI command %v to fail. Output: %s", args, output)
+		}
+	}
+}
+
+// TestCLI_RemoveAndEdit tests removing albums by pattern with confirmation and correcting their names
+func TestCLI_RemoveAndEdit(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("Radiohed - In Rainbows\nPink Floyd - The Wall\nRadiohead - OK Computer\nRadiohead - Kid A\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{[]string{"edit", "--queue", queueFile, "1", "Radiohead - In Rainbows"}, "", "Updated: Radiohead - In Rainbows"},
+		{[]string{"rename", "--queue", queueFile, "--title", "Kid A (Remaster)", "kid"}, "", "Updated: Radiohead - Kid A (Remaster)"},
+		{[]string{"remove", "--queue", queueFile, "Radiohead - *"}, "n\n", "Nothing removed."},
+		{[]string{"remove", "--queue", queueFile, "Radiohead - *"}, "y\n", "Removed: Radiohead - OK Computer"},
+		{[]string{"list", "--queue", queueFile}, "", "1. Pink Floyd - The Wall"},
+		{[]string{"undo", "--queue", queueFile}, "", "Undid"},
+		{[]string{"remove", "--queue", queueFile, "--yes", "radiohead"}, "", "Removed: Radiohead - Kid A (Remaster)"},
+		{[]string{"remove", "--queue", queueFile, "1"}, "", "Removed: Pink Floyd - The Wall"},
+		{[]string{"history", "--queue", queueFile}, "", "Radiohead - In Rainbows [edited from Radiohed - In Rainbows]"},
+		{[]string{"history", "--queue", queueFile}, "", "Pink Floyd - The Wall [removed]"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+		cmd.Stdin = strings.NewReader(step.stdin)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"remove", "--queue", queueFile, "nothing like it"},
+		{"edit", "--queue", queueFile, "1"},
+		{"edit", "--queue", queueFile, "1", "no separator"},
+	} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "Error:") {
+			t.Errorf("Expected CLI command %v to fail. Output: %s", args, output)
+		}
+	}
+}
+
+// TestCLI_MovePinAndPlay tests manual ordering of the queue
+func TestCLI_MovePinAndPlay(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("A - One\nB - Two\nC - Three\nD - Four\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"move", "--queue", queueFile, "four", "1"}, "Moved 'D - Four' to position 1"},
+		{[]string{"list", "--queue", queueFile}, "1. D - Four\n2. A - One\n3. B - Two\n4. C - Three"},
+		{[]string{"pin", "--queue", queueFile, "three"}, "Pinned: C - Three will be picked next"},
+		{[]string{"list", "--queue", queueFile}, "1. C - Three [pinned]\n2. D - Four"},
+		{[]string{"next", "--queue", queueFile, "--mode", "lifo"}, "Now listening: C - Three"},
+		{[]string{"play", "--queue", queueFile, "3"}, "Now listening: B - Two"},
+		{[]string{"pin", "--queue", queueFile, "one"}, "Pinned: A - One"},
+		{[]string{"unpin", "--queue", queueFile, "one"}, "Unpinned: A - One"},
+		{[]string{"next", "--queue", queueFile, "--mode", "lifo"}, "Now listening: D - Four"},
+		{[]string{"history", "--queue", queueFile}, "C - Three (pinned)"},
+		{[]string{"history", "--queue", queueFile}, "B - Two (manual)"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"move", "--queue", queueFile, "one", "5"},
+		{"move", "--queue", queueFile, "one", "first"},
+		{"play", "--queue", queueFile, "nothing like it"},
+		{"pin", "--queue", queueFile},
+	} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "Error:") {
+			t.Errorf("Expected CLI command %v to fail. Output: %s", args, output)
+		}
+	}
+}
+
+// TestCLI_SearchAndFilter tests finding albums with search and list filters
+func TestCLI_SearchAndFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	content := "Radiohead - In Rainbows\tadded=2024-03-03T00:00:00Z\tyear=2007\n" +
+		"Nirvana - MTV Unplugged in New York (Live)\tadded=2024-03-01T00:00:00Z\tnotes=acoustic\n" +
+		"AC/DC - Live at River Plate\tadded=2024-03-02T00:00:00Z\n" +
+		"Radiohead - OK Computer\tyear=1997\n"
+	if err := os.WriteFile(queueFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+		excluded string
+	}{
+		{[]string{"search", "--queue", queueFile, "radiohead"}, "1. Radiohead - In Rainbows\n4. Radiohead - OK Computer\n", "Nirvana"},
+		{[]string{"search", "--queue", queueFile, "acoustic"}, "2. Nirvana", "Radiohead"},
+		{[]string{"search", "--queue", queueFile, "/^ac.dc/"}, "3. AC/DC", "Nirvana"},
+		{[]string{"search", "--queue", queueFile, "beatles"}, "No album in the queue matches 'beatles'.", ""},
+		{[]string{"list", "--queue", queueFile, "--filter", "title~live", "--sort", "artist"}, "3. AC/DC - Live at River Plate\n2. Nirvana", "Radiohead"},
+		{[]string{"list", "--queue", queueFile, "--artist", "radio*", "--filter", "year!=2007"}, "4. Radiohead - OK Computer", "In Rainbows"},
+		{[]string{"list", "--queue", queueFile, "--sort", "added", "--limit", "2"}, "4. Radiohead - OK Computer\n2. Nirvana", "AC/DC"},
+	}
+
+	for _, tt := range tests {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, tt.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", tt.args, err, output)
+		}
+		if !strings.Contains(string(output), tt.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", tt.args, tt.expected, output)
+		}
+		if tt.excluded != "" && strings.Contains(string(output), tt.excluded) {
+			t.Errorf("Expected %v output not to contain %q. Output: %s", tt.args, tt.excluded, output)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"list", "--queue", queueFile, "--filter", "label~xl"},
+		{"list", "--queue", queueFile, "--sort", "year"},
+		{"search", "--queue", queueFile, "/(/"},
+		{"search", "--queue", queueFile},
+	} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "Error:") {
+			t.Errorf("Expected CLI command %v to fail. Output: %s", args, output)
+		}
+	}
+}
+
+// TestCLI_Tags tests tagging albums and picking by tag
+func TestCLI_Tags(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("A - One\nB - Two\nC - Three\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"add", "--queue", queueFile, "--tag", "Jazz,long", "D - Four"}, "Successfully added album"},
+		{[]string{"tag", "--queue", queueFile, "two", "jazz", "vinyl"}, "Tags of 'B - Two': jazz, vinyl"},
+		{[]string{"tag", "--queue", queueFile, "three", "vinyl"}, "Tags of 'C - Three': vinyl"},
+		{[]string{"untag", "--queue", queueFile, "three", "vinyl"}, "Tags of 'C - Three': none"},
+		{[]string{"tags", "--queue", queueFile}, "jazz                 2\nlong                 1\nvinyl                1\n"},
+		{[]string{"list", "--queue", queueFile, "--filter", "tag=vinyl", "--details"}, "2. B - Two\n   Tags:   jazz, vinyl"},
+		{[]string{"next", "--queue", queueFile, "--tag", "jazz", "--exclude-tag", "long"}, "Now listening: B - Two"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"next", "--queue", queueFile, "--tag", "jazz", "--exclude-tag", "long"},
+		{"tag", "--queue", queueFile, "one"},
+	} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "Error:") {
+			t.Errorf("Expected CLI command %v to fail. Output: %s", args, output)
+		}
+	}
+}
+
+// TestCLI_Duplicates tests normalized duplicate detection and near-duplicate confirmation
+func TestCLI_Duplicates(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	err := os.WriteFile(queueFile, []byte("The Beatles - Abbey Road\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importFile := filepath.Join(tempDir, "albums.txt")
+	err = os.WriteFile(importFile, []byte("Beatles – Abbey Road (Remastered)\nBeatles - Abey Road\nPink Floyd - The Wall\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{[]string{"add", "--queue", queueFile, "Beatles - Abbey Road"}, "", "Info: Album 'Beatles - Abbey Road' already exists as 'The Beatles - Abbey Road'"},
+		{[]string{"config", "--queue", queueFile, "similarity", "0.9"}, "", "similarity=0.9"},
+		{[]string{"import", "--queue", queueFile, importFile}, "", "Added 1 albums, Skipped 2 duplicates"},
+		{[]string{"add", "--queue", queueFile, "Pink Floid - The Wall"}, "n\n", "Nothing added."},
+		{[]string{"add", "--queue", queueFile, "Pink Floid - The Wall"}, "y\n", "Successfully added album"},
+		{[]string{"add", "--queue", queueFile, "--similarity", "0", "Beatles - Abey Road"}, "", "Successfully added album"},
+		{[]string{"count", "--queue", queueFile}, "", "There are 4 albums in the queue."},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+		cmd.Stdin = strings.NewReader(step.stdin)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "add", "--queue", queueFile, "--similarity", "2", "A - B")
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "invalid --similarity") {
+		t.Errorf("Expected invalid similarity to fail. Output: %s", output)
+	}
+}
+
+// TestCLI_Dedupe tests merging duplicates and finding albums already listened to
+func TestCLI_ImportReport(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	rejectsFile := filepath.Join(tempDir, "rejects.txt")
+
+	importFile := filepath.Join(tempDir, "albums.txt")
+	err := os.WriteFile(importFile, []byte("Pink Floyd - The Wall\n\nSleater-Kinney\npink floyd - the wall\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "import", "--queue", queueFile, "--rejects", rejectsFile, importFile)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI import failed: %v\nOutput: %s", err, output)
+	}
+
+	for _, expected := range []string{
+		"Added 1 albums, Skipped 1 duplicates, 1 format errors",
+		"line 3, format: missing ' - ' between artist and album",
+		"line 4, duplicate: duplicate of 'Pink Floyd - The Wall' on line 1",
+		"Rejected lines written to:",
+	} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected import output to contain %q. Output: %s", expected, output)
+		}
+	}
+
+	rejects, err := os.ReadFile(rejectsFile)
+	if err != nil {
+		t.Fatalf("Failed to read rejects file: %v", err)
+	}
+	if string(rejects) != "Sleater-Kinney\npink floyd - the wall\n" {
+		t.Errorf("Unexpected rejects file %q", rejects)
+	}
+
+	// The JSON report is the only output
+	cmd = exec.Command("go", "run", "main.go", "import", "--queue", queueFile, "--report", "json", importFile)
+	cmd.Dir = "."
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("CLI import --report json failed: %v", err)
+	}
+
+	var report queue.ImportReport
+	if err := json.Unmarshal(output, &report); err != nil {
+		t.Fatalf("Failed to parse JSON report: %v\nOutput: %s", err, output)
+	}
+	if report.Added != 0 || report.Duplicates != 2 || report.FormatErrors != 1 || len(report.Rejected) != 3 || report.Rejected[0].Line != 1 {
+		t.Errorf("Unexpected JSON report %+v", report)
+	}
+}
+
+func TestCLI_CSV(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	otherQueueFile := filepath.Join(tempDir, "other.txt")
+	exportFile := filepath.Join(tempDir, "queue.csv")
+
+	importFile := filepath.Join(tempDir, "albums.txt")
+	err := os.WriteFile(importFile, []byte("Year;Record;Band\n1969;Abbey Road;The Beatles\n1997;OK Computer;\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"import", "--queue", queueFile, "--format", "csv", "--delimiter", ";", "--artist-col", "band", "--album-col", "Record", importFile}, "line 3, format: missing artist"},
+		{[]string{"tag", "--queue", queueFile, "1", "rock"}, "Tags of 'The Beatles - Abbey Road': rock"},
+		{[]string{"export", "--queue", queueFile, "--format", "csv"}, "artist,album,year,priority,tags"},
+		{[]string{"export", "--queue", queueFile, "--output", exportFile}, "Exported 1 albums to:"},
+		{[]string{"import", "--queue", otherQueueFile, exportFile}, "Added 1 albums"},
+		{[]string{"list", "--queue", otherQueueFile, "--details"}, "Tags:   rock"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "import", "--queue", queueFile, "--artist-col", "2", importFile)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "only apply to --format csv") {
+		t.Errorf("Expected CSV flags on a text import to fail. Output: %s", output)
+	}
+}
+
+func TestCLI_JSON(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	otherQueueFile := filepath.Join(tempDir, "other.txt")
+	exportFile := filepath.Join(tempDir, "queue.json")
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"add", "--queue", queueFile, "--priority", "4", "The Beatles - Abbey Road"}, "Successfully added album"},
+		{[]string{"add", "--queue", queueFile, "Pink Floyd - The Wall"}, "Successfully added album"},
+		{[]string{"play", "--queue", queueFile, "Pink Floyd - The Wall"}, "Pink Floyd - The Wall"},
+		{[]string{"export", "--queue", queueFile, "--format", "json"}, `"format": "music-queue"`},
+		{[]string{"export", "--queue", queueFile, "--output", exportFile}, "Exported 1 albums and 1 history entries to:"},
+		{[]string{"import", "--queue", otherQueueFile, exportFile}, "Added 1 albums, Added 1 history entries"},
+		{[]string{"list", "--queue", otherQueueFile, "--details"}, "Priority: 4"},
+		{[]string{"history", "--queue", otherQueueFile}, "Pink Floyd - The Wall (manual)"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	newerFile := filepath.Join(tempDir, "newer.json")
+	err := os.WriteFile(newerFile, []byte(`{"format": "music-queue", "version": 99, "queue": []}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "import", "--queue", otherQueueFile, newerFile)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "version 99 is newer than this program supports") {
+		t.Errorf("Expected a newer document version to be rejected. Output: %s", output)
+	}
+}
+
+func TestCLI_Dedupe(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	content := "The Beatles - Abbey Road\tadded=2024-03-01T00:00:00Z\n" +
+		"Miles Davis - Kind of Blue\n" +
+		"Beatles - Abbey Road (Remastered)\tadded=2024-03-02T00:00:00Z\ttags=vinyl\n" +
+		"Miles Davis - Kind of Blu\n" +
+		"Pink Floyd - The Wall\n"
+	if err := os.WriteFile(queueFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archiveFile := filepath.Join(tempDir, "archive.txt")
+	if err := os.WriteFile(archiveFile, []byte("Pink Floyd - The Wall\tpicked=2024-02-01T00:00:00Z\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{[]string{"dedupe", "--queue", queueFile, "--similarity", "0.9"}, "n\nn\nn\n", "Duplicates 2 (96% similar):\n  Miles Davis - Kind of Blue\n  Miles Davis - Kind of Blu\n"},
+		{[]string{"dedupe", "--queue", queueFile}, "y\nn\n", "Merged 2 albums into: The Beatles - Abbey Road"},
+		{[]string{"list", "--queue", queueFile, "--details"}, "", "1. The Beatles - Abbey Road\n   Added:  2024-03-01"},
+		{[]string{"list", "--queue", queueFile, "--filter", "tag=vinyl"}, "", "1. The Beatles - Abbey Road"},
+		{[]string{"dedupe", "--queue", queueFile, "--similarity", "0.9", "--auto", "--remove-listened"}, "", "Removed: Pink Floyd - The Wall"},
+		{[]string{"count", "--queue", queueFile}, "", "There are 2 albums in the queue."},
+		{[]string{"dedupe", "--queue", queueFile}, "", "No duplicates found."},
+		{[]string{"history", "--queue", queueFile}, "", "Beatles - Abbey Road (Remastered) [merged]"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+		cmd.Stdin = strings.NewReader(step.stdin)
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+}
+
+// TestCLI_Add_QueueLocked tests that a command waiting on a locked queue reports the lock holder
+func TestCLI_Add_QueueLocked(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	// Hold the queue lock from this process
+	unlock, err := storage.NewFileStorage(queueFile).Lock(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	cmd := exec.Command("go", "run", "main.go", "add", "--queue", queueFile, "--lock-timeout", "100ms", "The Beatles - Abbey Road")
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Expected CLI to fail while the queue is locked. Output: %s", output)
+	}
+
+	expectedMsg := fmt.Sprintf("queue is locked by PID %d", os.Getpid())
+	if !strings.Contains(string(output), expectedMsg) {
+		t.Errorf("Expected %q in output. Output: %s", expectedMsg, output)
+	}
+}
+
+// TestCLI_History tests listing and filtering the listening history
+func TestCLI_History(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	archiveFile := filepath.Join(tempDir, "archive.txt")
+
+	archiveContent := "Legacy Artist - Legacy Album\n" +
+		"Old Artist - Old Album\tpicked=2024-03-01T12:00:00Z\tmethod=random\n" +
+		"New Artist - New Album\tpicked=2024-04-15T12:00:00Z\tmethod=manual\n"
+	err := os.WriteFile(archiveFile, []byte(archiveContent), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "history", "--queue", queueFile)
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr := string(output)
+	for _, expected := range []string{"unknown date", "Legacy Artist - Legacy Album", "2024-03-01", "Old Artist - Old Album (random)", "New Artist - New Album (manual)"} {
+		if !strings.Contains(outputStr, expected) {
+			t.Errorf("Expected output to contain %q. Output: %s", expected, outputStr)
+		}
+	}
+
+	// Filter to April only
+	cmd = exec.Command("go", "run", "main.go", "history", "--queue", queueFile, "--since", "2024-04-01", "--until", "2024-04-30")
+	cmd.Dir = "."
+
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr = string(output)
+	if !strings.Contains(outputStr, "New Artist - New Album") {
+		t.Errorf("Expected April entry in output. Output: %s", outputStr)
+	}
+
+	if strings.Contains(outputStr, "Old Artist") || strings.Contains(outputStr, "Legacy Artist") {
+		t.Errorf("Expected entries outside the range to be filtered out. Output: %s", outputStr)
+	}
+}
+
+// TestCLI_History_InvalidDate tests error handling for an invalid --since value
+func TestCLI_History_InvalidDate(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	cmd := exec.Command("go", "run", "main.go", "history", "--queue", queueFile, "--since", "last tuesday")
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("Expected CLI to fail for invalid date. Output: %s", output)
+	}
+
+	if !strings.Contains(string(output), "invalid --since value") {
+		t.Errorf("Expected invalid date message. Output: %s", output)
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		endOfDay bool
+		expected time.Time
+	}{
+		{"", false, time.Time{}},
+		{"2024-03-01", false, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"2024-03-01", true, time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local)},
+		{"7d", false, now.Add(-7 * 24 * time.Hour)},
+		{"2w", false, now.Add(-14 * 24 * time.Hour)},
+		{"36h", false, now.Add(-36 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		result, err := parseTimeFlag(tt.value, now, tt.endOfDay)
+		if err != nil {
+			t.Errorf("parseTimeFlag(%q) returned error: %v", tt.value, err)
+			continue
+		}
+
+		if !result.Equal(tt.expected) {
+			t.Errorf("parseTimeFlag(%q) = %v, want %v", tt.value, result, tt.expected)
+		}
+	}
+
+	if _, err := parseTimeFlag("soon", now, false); err == nil {
+		t.Error("Expected error for invalid value")
+	}
+}
+
+// TestCLI_UndoRedo tests undoing and redoing next through the CLI
+func TestCLI_UndoRedo(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	archiveFile := filepath.Join(tempDir, "archive.txt")
+
+	err := os.WriteFile(queueFile, []byte("Pink Floyd - The Wall\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"next"}, {"undo"}} {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, append(args, "--queue", queueFile)...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", args, err, output)
+		}
+
+		if args[0] == "undo" && !strings.Contains(string(output), "Undid next: Pink Floyd - The Wall") {
+			t.Errorf("Expected undo message. Output: %s", output)
+		}
+	}
+
+	// Album is back in the queue and gone from the archive
+	queueContent, err := os.ReadFile(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(queueContent)) != "Pink Floyd - The Wall" {
+		t.Errorf("Expected album back in queue, got %q", queueContent)
+	}
+
+	archiveContent, err := os.ReadFile(archiveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(archiveContent)) != "" {
+		t.Errorf("Expected empty archive after undo, got %q", archiveContent)
+	}
+
+	// Redo takes it again
+	cmd := exec.Command("go", "run", "main.go", "redo", "--queue", queueFile)
+	cmd.Dir = "."
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+	}
+
+	if !strings.Contains(string(output), "Redid next: Pink Floyd - The Wall") {
+		t.Errorf("Expected redo message. Output: %s", output)
+	}
+
+	// Nothing left to redo
+	cmd = exec.Command("go", "run", "main.go", "redo", "--queue", queueFile)
+	cmd.Dir = "."
+
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("Expected redo with empty journal to fail. Output: %s", output)
+	}
+
+	if !strings.Contains(string(output), "nothing to redo") {
+		t.Errorf("Expected 'nothing to redo' message. Output: %s", output)
+	}
+}
+
+func TestCLI_ImportFromDir(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	rejectsFile := filepath.Join(tempDir, "rejects.txt")
+	musicDir := filepath.Join(tempDir, "Music")
+
+	// Untagged tracks, placed by their Artist/Album folders
+	for _, track := range []string{
+		"Radiohead/1997 - OK Computer/01.mp3",
+		"Radiohead/1997 - OK Computer/02.mp3",
+		"The Beatles/Abbey Road/CD1/01.flac",
+		"loose.mp3",
+	} {
+		path := filepath.Join(musicDir, filepath.FromSlash(track))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"add", "--queue", queueFile, "Beatles - Abbey Road"}, "Successfully added album"},
+		{[]string{"import", "--queue", queueFile, "--from-dir", musicDir}, "Found 4 audio files in 2 albums (2 named from their folders)"},
+		{[]string{"import", "--queue", queueFile, "--from-dir", musicDir}, "duplicate: duplicate of 'Radiohead - OK Computer' already in the queue"},
+		{[]string{"list", "--queue", queueFile, "--details"}, "Source: library"},
+		{[]string{"list", "--queue", queueFile, "--details"}, "Year:   1997"},
+		// Rejected albums are written as records, which import again
+		{[]string{"import", "--queue", queueFile, "--from-dir", musicDir, "--rejects", rejectsFile}, "Rejected lines written to"},
+		{[]string{"import", "--queue", filepath.Join(tempDir, "other.txt"), rejectsFile}, "Added 2 albums"},
+		{[]string{"list", "--queue", filepath.Join(tempDir, "other.txt"), "--details"}, "Year:   1997"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "import", "--queue", queueFile, "--from-dir", musicDir, "albums.txt")
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "instead of an import file") {
+		t.Errorf("Expected --from-dir with an import file to fail. Output: %s", output)
+	}
+}
+
+func TestCLI_ImportServiceExport(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	rejectsFile := filepath.Join(tempDir, "rejects.csv")
+
+	spotifyFile := filepath.Join(tempDir, "YourLibrary.json")
+	err := os.WriteFile(spotifyFile, []byte(`{"albums": [{"artist": "Radiohead", "album": "OK Computer"}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discogsFile := filepath.Join(tempDir, "collection.csv")
+	err = os.WriteFile(discogsFile, []byte("Catalog#,Artist,Title,Released\nNODATA,Radiohead,OK Computer,1997\nDGC-24425,Nirvana (2),Nevermind,1991\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"import", "--queue", queueFile, "--format", "spotify", spotifyFile}, "Added 1 albums"},
+		{[]string{"import", "--queue", queueFile, "--format", "discogs", "--rejects", rejectsFile, discogsFile}, "line 2, duplicate: duplicate of 'Radiohead - OK Computer' already in the queue"},
+		{[]string{"list", "--queue", queueFile, "--details"}, "Source: discogs"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+
+	// The rejects keep the header, so they can be imported again
+	rejects, err := os.ReadFile(rejectsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rejects) != "Catalog#,Artist,Title,Released\nNODATA,Radiohead,OK Computer,1997\n" {
+		t.Errorf("Unexpected rejects file %q", rejects)
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "import", "--queue", queueFile, "--format", "napster", spotifyFile)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "spotify, lastfm, bandcamp, discogs, rym") {
+		t.Errorf("Expected an unknown format to fail. Output: %s", output)
+	}
+}
+
+func TestCLI_ImportPlaylist(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	m3uFile := filepath.Join(tempDir, "mix.m3u8")
+	content := "#EXTM3U\n" +
+		"#EXTINF:254,Radiohead - Airbag\n" +
+		"Radiohead/OK Computer/01 Airbag.mp3\n" +
+		"#EXTINF:387,Radiohead - Paranoid Android\n" +
+		"Radiohead/OK Computer/02 Paranoid Android.mp3\n" +
+		"#EXTINF:100,Unknown track\n" +
+		"loose.mp3\n"
+	if err := os.WriteFile(m3uFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plsFile := filepath.Join(tempDir, "mix.txt")
+	if err := os.WriteFile(plsFile, []byte("[playlist]\nFile1=Radiohead/OK Computer/03.mp3\nFile2=Slowdive/1993 - Souvlaki/01.mp3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"import", "--queue", queueFile, m3uFile}, []string{"Found 3 tracks in 1 albums", "Added 1 albums", "line 7, format: no album in the playlist entry, and it is not in an album folder below the playlist"}},
+		{[]string{"import", "--queue", queueFile, "--format", "pls", plsFile}, []string{"Added 1 albums", "line 2, duplicate: duplicate of 'Radiohead - OK Computer' already in the queue"}},
+		{[]string{"list", "--queue", queueFile, "--details"}, []string{"Slowdive - Souvlaki", "Year:   1993", "Source: playlist"}},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		for _, expected := range step.expected {
+			if !strings.Contains(string(output), expected) {
+				t.Errorf("Expected %v output to contain %q. Output: %s", step.args, expected, output)
+			}
+		}
+	}
+
+	cmd := exec.Command("go", "run", "main.go", "import", "--queue", queueFile, "--delimiter", ";", m3uFile)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "--delimiter only applies to --format csv") {
+		t.Errorf("Expected --delimiter with a playlist to fail. Output: %s", output)
+	}
+}
+
+// TestCLI_LegacyRecords tests that queue and archive files written when a
+// bare dash separated artist and title still work with every command
+func TestCLI_LegacyRecords(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+	archiveFile := filepath.Join(tempDir, "archive.txt")
+
+	if err := os.WriteFile(queueFile, []byte("Pink Floyd - The Wall\nSleater-Kinney\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archiveFile, []byte("Radiohead-OK Computer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"list", "--queue", queueFile}, "2. Sleater - Kinney"},
+		{[]string{"add", "--queue", queueFile, "Blur - Parklife"}, "Successfully added album"},
+		{[]string{"history", "--queue", queueFile}, "Radiohead - OK Computer"},
+		{[]string{"next", "--queue", queueFile, "--mode", "fifo"}, "Pink Floyd - The Wall"},
+	}
+	for _, step := range steps {
+		cmd := exec.Command("go", append([]string{"run", "main.go"}, step.args...)...)
+		cmd.Dir = "."
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command %v failed: %v\nOutput: %s", step.args, err, output)
+		}
+		if !strings.Contains(string(output), step.expected) {
+			t.Errorf("Expected %v output to contain %q. Output: %s", step.args, step.expected, output)
+		}
+	}
+}
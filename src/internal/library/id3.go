@@ -0,0 +1,280 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// ID3v2 header flags
+const (
+	id3Unsynchronisation = 0x80
+	id3ExtendedHeader    = 0x40
+	id3Footer            = 0x10
+)
+
+// id3Header is the size of the ID3v2 header and of a v2.3/v2.4 frame header
+const id3Header = 10
+
+// readMP3 reads the ID3v2 tag at the start of an MP3 file, filling in what it
+// lacks from an ID3v1 tag at the end
+func readMP3(r io.ReadSeeker) (Tags, error) {
+	tags, err := readID3v2(r)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	if tags.Album == "" || (tags.Artist == "" && tags.AlbumArtist == "") || tags.Year == 0 {
+		v1, err := readID3v1(r)
+		if err != nil {
+			return Tags{}, err
+		}
+		setFirst(&tags.Artist, v1.Artist)
+		setFirst(&tags.Album, v1.Album)
+		if tags.Year == 0 {
+			tags.Year = v1.Year
+		}
+	}
+
+	return tags, nil
+}
+
+// readID3v2 reads an ID3v2.2, v2.3 or v2.4 tag at the current position of r,
+// returning empty Tags when there is none
+func readID3v2(r io.Reader) (Tags, error) {
+	header := make([]byte, id3Header)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Tags{}, nil
+	}
+	if string(header[:3]) != "ID3" {
+		return Tags{}, nil
+	}
+
+	version, flags := header[3], header[5]
+	if version < 2 || version > 4 {
+		return Tags{}, fmt.Errorf("unsupported ID3v2.%d tag", version)
+	}
+
+	data := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Tags{}, errTruncated("ID3v2")
+	}
+
+	// Before v2.4, unsynchronisation applies to the whole tag
+	if flags&id3Unsynchronisation != 0 && version < 4 {
+		data = removeUnsynchronisation(data)
+	}
+
+	if flags&id3ExtendedHeader != 0 {
+		if version == 2 {
+			// The flag means compression in v2.2, which has no defined scheme
+			return Tags{}, nil
+		}
+		if len(data) < 4 {
+			return Tags{}, errTruncated("ID3v2")
+		}
+		size := uint64(syncsafe(data[:4]))
+		if version == 3 {
+			// The v2.3 size excludes the size field itself
+			size = uint64(binary.BigEndian.Uint32(data[:4])) + 4
+		}
+		if size > uint64(len(data)) {
+			return Tags{}, errTruncated("ID3v2")
+		}
+		data = data[size:]
+	}
+
+	return parseID3Frames(data, version), nil
+}
+
+// parseID3Frames reads the album frames of an ID3v2 tag body
+func parseID3Frames(data []byte, version byte) Tags {
+	var tags Tags
+
+	for len(data) > 0 {
+		var id string
+		var size uint64
+		var headerSize int
+		var formatFlags byte
+		if version == 2 {
+			if len(data) < 6 {
+				break
+			}
+			id = string(data[:3])
+			size = uint64(data[3])<<16 | uint64(data[4])<<8 | uint64(data[5])
+			headerSize = 6
+		} else {
+			if len(data) < id3Header {
+				break
+			}
+			id = string(data[:4])
+			size = uint64(binary.BigEndian.Uint32(data[4:8]))
+			if version == 4 {
+				size = uint64(syncsafe(data[4:8]))
+			}
+			formatFlags = data[9]
+			headerSize = id3Header
+		}
+
+		// Padding fills the rest of the tag with zero bytes
+		if id[0] == 0 || size > uint64(len(data)-headerSize) {
+			break
+		}
+		end := headerSize + int(size)
+		frame := data[headerSize:end]
+		data = data[end:]
+
+		frame, ok := frameContent(frame, version, formatFlags)
+		if !ok {
+			continue
+		}
+
+		switch id {
+		case "TPE1", "TP1":
+			setFirst(&tags.Artist, decodeID3Text(frame))
+		case "TPE2", "TP2":
+			setFirst(&tags.AlbumArtist, decodeID3Text(frame))
+		case "TALB", "TAL":
+			setFirst(&tags.Album, decodeID3Text(frame))
+		case "TDRC", "TYER", "TYE", "TDOR", "TORY":
+			tags.setYear(decodeID3Text(frame))
+		}
+	}
+
+	return tags
+}
+
+// frameContent returns the content of a v2.3 or v2.4 frame after applying its
+// format flags, or false for compressed or encrypted frames
+func frameContent(frame []byte, version, flags byte) ([]byte, bool) {
+	switch version {
+	case 3:
+		if flags&0xc0 != 0 {
+			return nil, false
+		}
+		if flags&0x20 != 0 {
+			// Grouping identity
+			if len(frame) < 1 {
+				return nil, false
+			}
+			frame = frame[1:]
+		}
+	case 4:
+		if flags&0x0c != 0 {
+			return nil, false
+		}
+		if flags&0x40 != 0 {
+			// Grouping identity
+			if len(frame) < 1 {
+				return nil, false
+			}
+			frame = frame[1:]
+		}
+		if flags&0x01 != 0 {
+			// Data length indicator
+			if len(frame) < 4 {
+				return nil, false
+			}
+			frame = frame[4:]
+		}
+		if flags&0x02 != 0 {
+			frame = removeUnsynchronisation(frame)
+		}
+	}
+	return frame, true
+}
+
+// decodeID3Text decodes the first string of a text frame in any of the ID3v2
+// encodings: ISO-8859-1, UTF-16 with a byte order mark, UTF-16BE or UTF-8
+func decodeID3Text(frame []byte) string {
+	if len(frame) == 0 {
+		return ""
+	}
+	encoding, text := frame[0], frame[1:]
+
+	switch encoding {
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(text) >= 2 {
+			switch {
+			case text[0] == 0xff && text[1] == 0xfe:
+				order, text = binary.LittleEndian, text[2:]
+			case text[0] == 0xfe && text[1] == 0xff:
+				text = text[2:]
+			default:
+				order = binary.LittleEndian
+			}
+		}
+		var units []uint16
+		for i := 0; i+1 < len(text); i += 2 {
+			unit := order.Uint16(text[i:])
+			if unit == 0 {
+				break
+			}
+			units = append(units, unit)
+		}
+		return strings.TrimSpace(string(utf16.Decode(units)))
+	case 3:
+		text, _, _ = bytes.Cut(text, []byte{0})
+		return strings.TrimSpace(string(text))
+	default:
+		text, _, _ = bytes.Cut(text, []byte{0})
+		return strings.TrimSpace(latin1(text))
+	}
+}
+
+// readID3v1 reads the ID3v1 tag in the last 128 bytes of r, returning empty
+// Tags when there is none
+func readID3v1(r io.ReadSeeker) (Tags, error) {
+	if _, err := r.Seek(-128, io.SeekEnd); err != nil {
+		// Shorter than a tag
+		return Tags{}, nil
+	}
+	tag := make([]byte, 128)
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return Tags{}, fmt.Errorf("failed to read ID3v1 tag: %w", err)
+	}
+	if string(tag[:3]) != "TAG" {
+		return Tags{}, nil
+	}
+
+	field := func(b []byte) string {
+		b, _, _ = bytes.Cut(b, []byte{0})
+		return strings.TrimSpace(latin1(b))
+	}
+	return Tags{
+		Artist: field(tag[33:63]),
+		Album:  field(tag[63:93]),
+		Year:   parseYear(field(tag[93:97])),
+	}, nil
+}
+
+// syncsafe decodes a 28-bit integer stored in the low 7 bits of four bytes
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+// removeUnsynchronisation drops the zero byte that ID3v2 inserts after each
+// 0xff byte to hide false MPEG frame syncs
+func removeUnsynchronisation(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		result = append(result, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return result
+}
+
+// latin1 decodes ISO-8859-1 text, whose bytes are the first 256 code points
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
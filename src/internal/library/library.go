@@ -0,0 +1,228 @@
+// Package library finds the albums in a local music library by reading the
+// tags of its audio files, or the names of their folders when they have none.
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// VariousArtists is the artist of an album whose tracks have different
+// artists and no album artist
+const VariousArtists = "Various Artists"
+
+// Album is an album found in a library: the tracks sharing an artist and
+// album title
+type Album struct {
+	Artist string
+	Title  string
+	Year   int    // zero when no track has one
+	Dir    string // the directory of its first track
+	Tracks int
+
+	// FromFolders is set when the artist or title was taken from the names
+	// of the folders holding the tracks, for lack of tags
+	FromFolders bool
+}
+
+// Problem is an audio file that could not be assigned to an album
+type Problem struct {
+	Path string
+	Err  error
+}
+
+// Result is what Scan found in a library
+type Result struct {
+	Files    int     // audio files found
+	Albums   []Album // in the order their first track was found
+	Problems []Problem
+}
+
+// discFolder matches the folders that split an album into discs
+var discFolder = regexp.MustCompile(`(?i)^(cd|disc|disk)\s*\d+$`)
+
+// yearPrefix matches a year before the title in an album folder name, as in
+// "1997 - OK Computer" or "[1997] OK Computer"
+var yearPrefix = regexp.MustCompile(`^(?:(\d{4})\s+-\s+|[\[(](\d{4})[\])]\s*)`)
+
+// track is an audio file assigned to an album
+type track struct {
+	Tags
+	dir         string
+	fromFolders bool
+}
+
+// Scan walks the directory tree at root, reads the tags of the supported
+// audio files (see ReadTags) and groups the tracks into albums by album
+// artist, or artist, and album title. Tracks without those tags are placed
+// by their folders: Artist/Album/track, Artist/Album/CD1/track, or a single
+// "Artist - Album" folder. Hidden files and directories are skipped, and
+// files that cannot be placed are reported as problems.
+func Scan(root string) (Result, error) {
+	info, err := os.Stat(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return Result{}, fmt.Errorf("directory not found: %s", root)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read directory: %w", err)
+	}
+	if !info.IsDir() {
+		return Result{}, fmt.Errorf("not a directory: %s", root)
+	}
+
+	var result Result
+	var tracks []track
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			result.Problems = append(result.Problems, Problem{Path: path, Err: err})
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !IsAudioFile(path) {
+			return nil
+		}
+
+		result.Files++
+		track, err := readTrack(root, path)
+		if err != nil {
+			result.Problems = append(result.Problems, Problem{Path: path, Err: err})
+			return nil
+		}
+		tracks = append(tracks, track)
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	result.Albums = groupTracks(tracks)
+	return result, nil
+}
+
+// readTrack reads the tags of the audio file at path and fills in a missing
+// artist or album title from its folders under root
+func readTrack(root, path string) (track, error) {
+	tags, err := ReadTags(path)
+	if err != nil {
+		tags = Tags{}
+	}
+
+	t := track{Tags: tags, dir: filepath.Dir(path)}
+	if (t.Artist != "" || t.AlbumArtist != "") && t.Album != "" {
+		return t, nil
+	}
+
+	// An artist from the tags is kept
+	artist, title, year, found := folderAlbum(root, path)
+	if t.Artist == "" && t.AlbumArtist == "" {
+		t.AlbumArtist = artist
+	}
+	if !found || t.AlbumArtist == "" && t.Artist == "" {
+		if err != nil {
+			return track{}, err
+		}
+		return track{}, fmt.Errorf("no artist and album tags, and not in an Artist/Album folder")
+	}
+
+	if t.Album == "" {
+		t.Album = title
+	}
+	if t.Year == 0 {
+		t.Year = year
+	}
+	t.fromFolders = true
+	return t, nil
+}
+
+// folderAlbum returns the artist, title and year named by the folders of the
+// audio file at path below root: the album folder, skipping a disc folder,
+// and the artist folder above it, or an "Artist - Album" folder. The artist
+// is empty when the folders name only the album. Files outside root, or
+// directly in it, are not placed: root is the scanned library, or the
+// playlist's folder for playlist entries.
+func folderAlbum(root, path string) (artist, title string, year int, found bool) {
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", 0, false
+	}
+	folders := strings.Split(rel, string(filepath.Separator))
+	if len(folders) > 1 && discFolder.MatchString(folders[len(folders)-1]) {
+		folders = folders[:len(folders)-1]
+	}
+
+	title = folders[len(folders)-1]
+	if match := yearPrefix.FindStringSubmatch(title); match != nil {
+		year = parseYear(match[1] + match[2])
+		title = title[len(match[0]):]
+	}
+
+	// The album folder may name both, as in "Radiohead - OK Computer"
+	if folderArtist, folderTitle, split := strings.Cut(title, " - "); split {
+		artist, title = folderArtist, folderTitle
+	} else if len(folders) > 1 {
+		artist = folders[len(folders)-2]
+	}
+
+	artist, title = strings.TrimSpace(artist), strings.TrimSpace(title)
+	if title == "" {
+		return "", "", 0, false
+	}
+	return artist, title, year, true
+}
+
+// groupTracks groups tracks into albums by album artist, or artist, and
+// album title, compared case-insensitively. Tracks of one album title in the
+// same directory with different artists and no album artist form a single
+// album by VariousArtists.
+func groupTracks(tracks []track) []Album {
+	type compilationKey struct{ dir, title string }
+	artists := map[compilationKey]map[string]bool{}
+	for _, t := range tracks {
+		if t.AlbumArtist == "" {
+			key := compilationKey{t.dir, strings.ToLower(t.Album)}
+			if artists[key] == nil {
+				artists[key] = map[string]bool{}
+			}
+			artists[key][strings.ToLower(t.Artist)] = true
+		}
+	}
+
+	var albums []Album
+	indices := map[string]int{}
+	for _, t := range tracks {
+		artist := t.AlbumArtist
+		if artist == "" {
+			artist = t.Artist
+			if len(artists[compilationKey{t.dir, strings.ToLower(t.Album)}]) > 1 {
+				artist = VariousArtists
+			}
+		}
+
+		key := strings.ToLower(artist) + "\x00" + strings.ToLower(t.Album)
+		index, found := indices[key]
+		if !found {
+			index = len(albums)
+			indices[key] = index
+			albums = append(albums, Album{Artist: artist, Title: t.Album, Dir: t.dir})
+		}
+
+		album := &albums[index]
+		album.Tracks++
+		if album.Year == 0 {
+			album.Year = t.Year
+		}
+		album.FromFolders = album.FromFolders || t.fromFolders
+	}
+
+	return albums
+}
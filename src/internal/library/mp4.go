@@ -0,0 +1,140 @@
+package library
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxMoovAtom limits the size of the movie atom read from an MP4 file, which
+// holds the metadata and may embed cover art
+const maxMoovAtom = 64 << 20
+
+// mp4DataUTF8 is the type of a data atom holding UTF-8 text
+const mp4DataUTF8 = 1
+
+// readMP4 reads the iTunes metadata of an MP4 audio file, found in the
+// moov/udta/meta/ilst atoms
+func readMP4(r io.ReadSeeker) (Tags, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	header := make([]byte, 16)
+	for position, first := int64(0), true; position+8 <= end; first = false {
+		if _, err := r.Seek(position, io.SeekStart); err != nil {
+			return Tags{}, err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return Tags{}, errTruncated("MP4")
+		}
+		size, kind, headerSize := int64(binary.BigEndian.Uint32(header)), string(header[4:8]), int64(8)
+		switch size {
+		case 0:
+			size = end - position
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return Tags{}, errTruncated("MP4")
+			}
+			size, headerSize = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+		if first && kind != "ftyp" {
+			return Tags{}, fmt.Errorf("not an MP4 file")
+		}
+		if size < headerSize || position+size > end {
+			return Tags{}, errTruncated("MP4")
+		}
+
+		if kind == "moov" {
+			if size > maxMoovAtom {
+				return Tags{}, fmt.Errorf("invalid MP4 tag: movie atom too large")
+			}
+			moov := make([]byte, size-headerSize)
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return Tags{}, errTruncated("MP4")
+			}
+			return parseMP4Metadata(moov), nil
+		}
+		position += size
+	}
+
+	return Tags{}, nil
+}
+
+// parseMP4Metadata reads the metadata items of a moov atom's content, kept in
+// udta/meta or, by some writers, directly in meta
+func parseMP4Metadata(moov []byte) Tags {
+	meta, found := findAtom(moov, "meta")
+	if udta, ok := findAtom(moov, "udta"); ok {
+		if udtaMeta, ok := findAtom(udta, "meta"); ok {
+			meta, found = udtaMeta, true
+		}
+	}
+	// meta is a full atom: its children follow a version and flags
+	if !found || len(meta) < 4 {
+		return Tags{}
+	}
+	list, found := findAtom(meta[4:], "ilst")
+	if !found {
+		return Tags{}
+	}
+
+	var tags Tags
+	eachAtom(list, func(kind string, item []byte) {
+		data, found := findAtom(item, "data")
+		// A data atom holds a type, a locale and the value
+		if !found || len(data) < 8 || binary.BigEndian.Uint32(data) != mp4DataUTF8 {
+			return
+		}
+		value := string(data[8:])
+
+		switch kind {
+		case "\xa9ART":
+			setFirst(&tags.Artist, value)
+		case "aART":
+			setFirst(&tags.AlbumArtist, value)
+		case "\xa9alb":
+			setFirst(&tags.Album, value)
+		case "\xa9day":
+			tags.setYear(value)
+		}
+	})
+	return tags
+}
+
+// findAtom returns the content of the first atom of kind among the atoms in
+// data
+func findAtom(data []byte, kind string) ([]byte, bool) {
+	var content []byte
+	found := false
+	eachAtom(data, func(atomKind string, atom []byte) {
+		if !found && atomKind == kind {
+			content, found = atom, true
+		}
+	})
+	return content, found
+}
+
+// eachAtom calls fn with the kind and content of each atom in data, stopping
+// at the first malformed atom
+func eachAtom(data []byte, fn func(kind string, content []byte)) {
+	for len(data) >= 8 {
+		size, kind, headerSize := uint64(binary.BigEndian.Uint32(data)), string(data[4:8]), uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size, headerSize = binary.BigEndian.Uint64(data[8:16]), 16
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			return
+		}
+
+		fn(kind, data[headerSize:size])
+		data = data[size:]
+	}
+}
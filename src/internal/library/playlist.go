@@ -0,0 +1,369 @@
+package library
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Playlist formats read by ReadPlaylist
+const (
+	PlaylistM3U = "m3u" // M3U and M3U8, with or without extended directives
+	PlaylistPLS = "pls"
+)
+
+// PlaylistAlbum is an album derived from the tracks of a playlist
+type PlaylistAlbum struct {
+	Artist string
+	Title  string
+	Year   int    // zero when unknown
+	Line   int    // the line of its first track in the playlist
+	Text   string // the path of its first track, as written
+	Tracks int
+}
+
+// PlaylistProblem is a playlist entry whose album could not be derived
+type PlaylistProblem struct {
+	Line int
+	Text string // the entry's path, as written
+	Err  error
+}
+
+// PlaylistResult is what ReadPlaylist found in a playlist
+type PlaylistResult struct {
+	Tracks   int             // entries listed
+	Albums   []PlaylistAlbum // in the order their first track is listed
+	Problems []PlaylistProblem
+}
+
+// windowsDrive matches the drive of an absolute Windows path, as in
+// `C:\Music\01.mp3`
+var windowsDrive = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// playlistEntry is a track listed in a playlist, with the details its
+// directives give
+type playlistEntry struct {
+	line        int
+	path        string
+	artist      string // the track artist, from #EXTINF or a PLS title
+	albumArtist string // from #EXTART
+	album       string // from #EXTALB
+}
+
+// ReadPlaylist derives the distinct albums of the tracks listed in an M3U or
+// PLS playlist. The album of each entry comes from the tags of its file when
+// it exists locally (relative paths are relative to the playlist), else from
+// #EXTALB with the artist of #EXTART or #EXTINF, else from the folders of its
+// path below the playlist's directory: Artist/Album/track, or an
+// "Artist - Album" folder, with the artist of the playlist when only an
+// album folder is given or it names one. Entries outside the playlist's
+// directory, such as streams, are not placed by their folders.
+func ReadPlaylist(path, format string) (PlaylistResult, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return PlaylistResult{}, fmt.Errorf("file not found: %s", path)
+	}
+	if err != nil {
+		return PlaylistResult{}, fmt.Errorf("failed to read playlist: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, decodePlaylistLine(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return PlaylistResult{}, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	var entries []playlistEntry
+	switch format {
+	case PlaylistM3U:
+		entries = parseM3U(lines)
+	case PlaylistPLS:
+		entries, err = parsePLS(lines)
+		if err != nil {
+			return PlaylistResult{}, err
+		}
+	default:
+		return PlaylistResult{}, fmt.Errorf("unknown playlist format %q: must be %s or %s", format, PlaylistM3U, PlaylistPLS)
+	}
+
+	return playlistAlbums(entries, filepath.Dir(path)), nil
+}
+
+// decodePlaylistLine returns a playlist line as UTF-8. M3U8 playlists are
+// UTF-8; older M3U and PLS files are usually ISO-8859-1.
+func decodePlaylistLine(line string) string {
+	line = strings.TrimPrefix(line, "\ufeff")
+	if !utf8.ValidString(line) {
+		line = latin1([]byte(line))
+	}
+	return strings.TrimSpace(line)
+}
+
+// parseM3U reads the entries of an M3U playlist. #EXTINF applies to the next
+// entry; #EXTART (the album artist) and #EXTALB apply to the entries after
+// them until changed.
+func parseM3U(lines []string) []playlistEntry {
+	var entries []playlistEntry
+	var artist, albumArtist, album string
+
+	for i, line := range lines {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			artist = extinfArtist(strings.TrimPrefix(line, "#EXTINF:"))
+		case strings.HasPrefix(line, "#EXTART:"):
+			albumArtist = strings.TrimSpace(strings.TrimPrefix(line, "#EXTART:"))
+		case strings.HasPrefix(line, "#EXTALB:"):
+			album = strings.TrimSpace(strings.TrimPrefix(line, "#EXTALB:"))
+		case strings.HasPrefix(line, "#"):
+			// Comments and other directives
+		default:
+			entries = append(entries, playlistEntry{line: i + 1, path: line, artist: artist, albumArtist: albumArtist, album: album})
+			artist = ""
+		}
+	}
+	return entries
+}
+
+// extinfArtist returns the artist of an #EXTINF directive's value, such as
+// `123,Radiohead - Airbag` or `-1 tvg-name="x",Radiohead - Airbag`: the
+// display title after the first comma outside quotes, up to " - "
+func extinfArtist(value string) string {
+	quoted := false
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			return titleArtist(value[i+1:])
+		}
+	}
+	return ""
+}
+
+// titleArtist returns the artist of an "Artist - Track" display title, or ""
+// when the title does not name one
+func titleArtist(title string) string {
+	artist, _, found := strings.Cut(title, " - ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(artist)
+}
+
+// parsePLS reads the entries of a PLS playlist: FileN keys with the paths,
+// and TitleN keys with "Artist - Track" display titles, ordered by N
+func parsePLS(lines []string) ([]playlistEntry, error) {
+	if !slices.ContainsFunc(lines, func(line string) bool { return strings.EqualFold(line, "[playlist]") }) {
+		return nil, fmt.Errorf("invalid PLS playlist: no [playlist] section")
+	}
+
+	entries := map[int]*playlistEntry{}
+	entry := func(number int) *playlistEntry {
+		if entries[number] == nil {
+			entries[number] = &playlistEntry{}
+		}
+		return entries[number]
+	}
+
+	for i, line := range lines {
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		for _, prefix := range []string{"file", "title"} {
+			number, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+			if !strings.HasPrefix(key, prefix) || err != nil {
+				continue
+			}
+			if prefix == "file" {
+				entry(number).line, entry(number).path = i+1, value
+			} else {
+				entry(number).artist = titleArtist(value)
+			}
+		}
+	}
+
+	numbers := make([]int, 0, len(entries))
+	for number, entry := range entries {
+		if entry.path != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	slices.Sort(numbers)
+
+	result := make([]playlistEntry, len(numbers))
+	for i, number := range numbers {
+		result[i] = *entries[number]
+	}
+	return result, nil
+}
+
+// playlistTrack is a playlist entry with the album derived for it
+type playlistTrack struct {
+	playlistEntry
+	artist      string
+	title       string
+	year        int
+	trackArtist bool // the artist is the track's, not the album's
+}
+
+// playlistAlbums derives the album of each entry and groups the entries by
+// artist and album title, compared case-insensitively. Entries of one album
+// title in the same folder whose track artists differ form a single album by
+// VariousArtists. dir is the directory of the playlist, against which
+// relative paths are resolved.
+func playlistAlbums(entries []playlistEntry, dir string) PlaylistResult {
+	result := PlaylistResult{Tracks: len(entries)}
+
+	type compilationKey struct{ folder, title string }
+	var tracks []playlistTrack
+	artists := map[compilationKey]map[string]bool{}
+	for _, entry := range entries {
+		track, err := entryAlbum(entry, dir)
+		if err != nil {
+			result.Problems = append(result.Problems, PlaylistProblem{Line: entry.line, Text: entry.path, Err: err})
+			continue
+		}
+		tracks = append(tracks, track)
+
+		if track.trackArtist {
+			key := compilationKey{entryFolder(entry.path), strings.ToLower(track.title)}
+			if artists[key] == nil {
+				artists[key] = map[string]bool{}
+			}
+			artists[key][strings.ToLower(track.artist)] = true
+		}
+	}
+
+	indices := map[string]int{}
+	for _, track := range tracks {
+		artist := track.artist
+		if track.trackArtist && len(artists[compilationKey{entryFolder(track.path), strings.ToLower(track.title)}]) > 1 {
+			artist = VariousArtists
+		}
+
+		key := strings.ToLower(artist) + "\x00" + strings.ToLower(track.title)
+		index, found := indices[key]
+		if !found {
+			index = len(result.Albums)
+			indices[key] = index
+			result.Albums = append(result.Albums, PlaylistAlbum{Artist: artist, Title: track.title, Line: track.line, Text: track.path})
+		}
+
+		album := &result.Albums[index]
+		album.Tracks++
+		if album.Year == 0 {
+			album.Year = track.year
+		}
+	}
+
+	return result
+}
+
+// entryAlbum derives the album of a playlist entry
+func entryAlbum(entry playlistEntry, dir string) (playlistTrack, error) {
+	var tags Tags
+	if trackPath, local := localPath(entry.path, dir); local && IsAudioFile(trackPath) {
+		// Missing or unreadable files fall back to the playlist's details
+		tags, _ = ReadTags(trackPath)
+	}
+
+	track := playlistTrack{playlistEntry: entry, artist: tags.AlbumArtist, year: tags.Year}
+	if track.artist == "" {
+		track.artist = entry.albumArtist
+	}
+	for _, artist := range []string{tags.Artist, entry.artist} {
+		if track.artist == "" && artist != "" {
+			track.artist, track.trackArtist = artist, true
+		}
+	}
+
+	switch {
+	case tags.Album != "" && track.artist != "":
+		track.title = tags.Album
+		return track, nil
+	case entry.album != "" && track.artist != "":
+		track.title = entry.album
+		return track, nil
+	}
+
+	// Folders are only trusted below the playlist, where they are likely to
+	// be a music library's; an artist from the tags or directives is kept
+	var artist, title string
+	var year int
+	found := false
+	if file, local := playlistFile(entry.path, dir); local {
+		if root, err := filepath.Abs(dir); err == nil {
+			artist, title, year, found = folderAlbum(root, file)
+		}
+	}
+	if track.artist == "" {
+		track.artist = artist
+	}
+	if !found || track.artist == "" {
+		return playlistTrack{}, fmt.Errorf("no album in the playlist entry, and it is not in an album folder below the playlist")
+	}
+	track.title = title
+	if track.year == 0 {
+		track.year = year
+	}
+	return track, nil
+}
+
+// localPath returns the file a playlist entry names, resolving a relative
+// path against dir, or false when it names a URL other than a file URL
+func localPath(path, dir string) (string, bool) {
+	if u, err := url.Parse(path); err == nil && len(u.Scheme) > 1 {
+		return u.Path, u.Scheme == "file"
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, true
+}
+
+// playlistFile returns the absolute path of the file a playlist entry
+// names, resolving a relative path against dir, the playlist's directory.
+// Backslashes separate folders, as in playlists written on Windows. It
+// returns false for URLs other than file URLs and for absolute Windows paths
+// read on another system.
+func playlistFile(path, dir string) (string, bool) {
+	if windowsDrive.MatchString(path) && !filepath.IsAbs(path) {
+		return "", false
+	}
+	file, local := localPath(strings.ReplaceAll(path, `\`, "/"), dir)
+	if !local {
+		return "", false
+	}
+	file, err := filepath.Abs(file)
+	return file, err == nil
+}
+
+// entryFolder returns the folders of a playlist entry's path as written,
+// without the file name; for URLs, those of the URL path
+func entryFolder(path string) string {
+	if u, err := url.Parse(path); err == nil && len(u.Scheme) > 1 {
+		path = u.Path
+	}
+	index := strings.LastIndexAny(path, `/\`)
+	if index == -1 {
+		return ""
+	}
+	return path[:index]
+}
@@ -0,0 +1,148 @@
+package library
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPlaylist_M3U(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "Tagged/01.flac", flacFile("ARTIST=Massive Attack", "ALBUM=Mezzanine", "DATE=1998"))
+
+	playlist := writeFile(t, dir, "mix.m3u8", []byte("\ufeff#EXTM3U\n"+
+		"#EXTINF:254,Radiohead - Airbag\n"+
+		"Radiohead/1997 - OK Computer/01 Airbag.mp3\n"+
+		"#EXTINF:387,Radiohead - Paranoid Android\n"+
+		"/home/me/Music/radiohead/OK Computer/CD1/02 Paranoid Android.mp3\n"+
+		"\n"+
+		"# A local file with tags\n"+
+		"Tagged/01.flac\n"+
+		"#EXTINF:200,Slowdive - Alison\n"+
+		`..\Souvlaki\01 Alison.flac`+"\n"+
+		"#EXTINF:-1 tvg-name=\"Iggy, Pop\",Iggy Pop - Lust for Life\n"+
+		"Trainspotting/01.mp3\n"+
+		"#EXTINF:300,Underworld - Born Slippy\n"+
+		"Trainspotting/02.mp3\n"+
+		"#EXTART:Portishead\n"+
+		"#EXTALB:Dummy\n"+
+		"http://example.com/stream/1.mp3\n"+
+		"http://example.com/stream/2.mp3\n"+
+		"#EXTART:\n"+
+		"#EXTALB:\n"+
+		"#EXTINF:100,Unknown track\n"+
+		"loose.mp3\n"+
+		filepath.Join(dir, "Low", "Hey What", "01.flac")+"\n"+
+		"#EXTINF:250,Jay-Z - Dirt Off Your Shoulder\n"+
+		"Danger Mouse/The Grey Album/01.mp3\n"))
+
+	result, err := ReadPlaylist(playlist, PlaylistM3U)
+	if err != nil {
+		t.Fatalf("ReadPlaylist() error = %v", err)
+	}
+
+	expected := []PlaylistAlbum{
+		{Artist: "Radiohead", Title: "OK Computer", Year: 1997, Line: 3, Text: "Radiohead/1997 - OK Computer/01 Airbag.mp3", Tracks: 1},
+		{Artist: "Massive Attack", Title: "Mezzanine", Year: 1998, Line: 8, Text: "Tagged/01.flac", Tracks: 1},
+		{Artist: VariousArtists, Title: "Trainspotting", Line: 12, Text: "Trainspotting/01.mp3", Tracks: 2},
+		{Artist: "Portishead", Title: "Dummy", Line: 17, Text: "http://example.com/stream/1.mp3", Tracks: 2},
+		{Artist: "Low", Title: "Hey What", Line: 23, Text: filepath.Join(dir, "Low", "Hey What", "01.flac"), Tracks: 1},
+		{Artist: "Jay-Z", Title: "The Grey Album", Line: 25, Text: "Danger Mouse/The Grey Album/01.mp3", Tracks: 1},
+	}
+	if !reflect.DeepEqual(result.Albums, expected) {
+		t.Errorf("ReadPlaylist() albums = %+v, want %+v", result.Albums, expected)
+	}
+	if result.Tracks != 11 {
+		t.Errorf("ReadPlaylist() tracks = %d, want 11", result.Tracks)
+	}
+
+	// Folders outside the playlist's are not taken for an album
+	var lines []int
+	for _, problem := range result.Problems {
+		lines = append(lines, problem.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{5, 10, 22}) {
+		t.Errorf("ReadPlaylist() problems = %+v, want lines 5, 10 and 22", result.Problems)
+	}
+}
+
+func TestReadPlaylist_PLS(t *testing.T) {
+	dir := t.TempDir()
+	// ISO-8859-1, with entries out of order
+	fileURL := "file://" + filepath.ToSlash(dir) + "/Low%20-%20Hey%20What/01.flac"
+	playlist := writeFile(t, dir, "mix.pls", []byte("[playlist]\r\n"+
+		"File2=Sigur R\xf3s/( )/01.mp3\r\n"+
+		"Title2=Sigur R\xf3s - Untitled #1\r\n"+
+		"File1="+fileURL+"\r\n"+
+		"Title1=Low - White Horses\r\n"+
+		"Length1=-1\r\n"+
+		"File3=01.mp3\r\n"+
+		"NumberOfEntries=3\r\n"+
+		"Version=2\r\n"))
+
+	result, err := ReadPlaylist(playlist, PlaylistPLS)
+	if err != nil {
+		t.Fatalf("ReadPlaylist() error = %v", err)
+	}
+
+	expected := []PlaylistAlbum{
+		{Artist: "Low", Title: "Hey What", Line: 4, Text: fileURL, Tracks: 1},
+		{Artist: "Sigur Rós", Title: "( )", Line: 2, Text: "Sigur Rós/( )/01.mp3", Tracks: 1},
+	}
+	if !reflect.DeepEqual(result.Albums, expected) {
+		t.Errorf("ReadPlaylist() albums = %+v, want %+v", result.Albums, expected)
+	}
+	if result.Tracks != 3 || len(result.Problems) != 1 || result.Problems[0].Line != 7 {
+		t.Errorf("ReadPlaylist() = %d tracks with problems %+v, want 3 tracks and a problem on line 7", result.Tracks, result.Problems)
+	}
+}
+
+func TestReadPlaylist_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		path     string
+		format   string
+		expected string
+	}{
+		{filepath.Join(dir, "missing.m3u"), PlaylistM3U, "file not found"},
+		{writeFile(t, dir, "mix.pls", []byte("File1=01.mp3\n")), PlaylistPLS, "no [playlist] section"},
+		{writeFile(t, dir, "mix.xspf", []byte("<playlist/>")), "xspf", `unknown playlist format "xspf"`},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadPlaylist(tt.path, tt.format); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("ReadPlaylist(%s) error = %v, want one containing %q", filepath.Base(tt.path), err, tt.expected)
+		}
+	}
+}
+
+func TestPlaylistFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "playlists")
+
+	tests := []struct {
+		path string
+		file string // relative to dir; empty when not a local file
+	}{
+		{path: "Artist/Album/01.mp3", file: "Artist/Album/01.mp3"},
+		{path: `Artist\Album\01.mp3`, file: "Artist/Album/01.mp3"},
+		{path: "./Album/../Album/01.mp3", file: "Album/01.mp3"},
+		{path: filepath.Join(dir, "Artist", "Album", "01.mp3"), file: "Artist/Album/01.mp3"},
+		{path: "file://" + filepath.ToSlash(dir) + "/Artist/Album/01.mp3", file: "Artist/Album/01.mp3"},
+		{path: `..\Album\01.mp3`, file: "../Album/01.mp3"},
+		{path: `C:\Users\alice\Music\Artist\Album\01.mp3`},
+		{path: "http://example.com/Artist/Album/01.mp3"},
+	}
+
+	for _, tt := range tests {
+		file, local := playlistFile(tt.path, dir)
+		expected := ""
+		if tt.file != "" {
+			expected = filepath.Join(dir, filepath.FromSlash(tt.file))
+		}
+		if file != expected || local != (tt.file != "") {
+			t.Errorf("playlistFile(%q) = %q, %v, want %q, %v", tt.path, file, local, expected, tt.file != "")
+		}
+	}
+}
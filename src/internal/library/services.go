@@ -0,0 +1,341 @@
+package library
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Services whose data export files ReadServiceExport reads
+const (
+	ServiceSpotify  = "spotify"  // saved albums: YourLibrary.json, or a saved albums API response
+	ServiceLastFM   = "lastfm"   // scrobbles CSV, with or without a header
+	ServiceBandcamp = "bandcamp" // collection CSV or JSON
+	ServiceDiscogs  = "discogs"  // collection CSV
+	ServiceRYM      = "rym"      // RateYourMusic ratings CSV
+)
+
+// Services lists the services whose exports ReadServiceExport reads
+var Services = []string{ServiceSpotify, ServiceLastFM, ServiceBandcamp, ServiceDiscogs, ServiceRYM}
+
+// ServiceAlbum is an album listed in a service's data export. Artist or
+// Title is empty when the entry does not name one.
+type ServiceAlbum struct {
+	Artist string
+	Title  string
+	Year   int    // zero when unknown
+	Line   int    // the line of its entry in CSV exports, or its position in JSON exports
+	Text   string // the entry's row in CSV exports, as written; empty for JSON exports
+}
+
+// ServiceExport is what ReadServiceExport found in a data export
+type ServiceExport struct {
+	Albums []ServiceAlbum // in the order of their entries
+	Header string         // the header row of CSV exports, as written
+}
+
+// serviceReaders read the albums of each service's export
+var serviceReaders = map[string]func([]byte) (ServiceExport, error){
+	ServiceSpotify:  readSpotify,
+	ServiceLastFM:   readLastFM,
+	ServiceBandcamp: readBandcamp,
+	ServiceDiscogs:  readDiscogs,
+	ServiceRYM:      readRYM,
+}
+
+// discogsSuffix matches the number Discogs appends to artists sharing a name,
+// as in "Nirvana (2)", and the star marking an artist name variation
+var discogsSuffix = regexp.MustCompile(`(\s+\(\d+\)|\*)$`)
+
+// ReadServiceExport reads the albums of a data export file downloaded from
+// service, one of Services. Exports listing tracks (Last.fm scrobbles,
+// Bandcamp track purchases) list each album once, at its first track.
+func ReadServiceExport(path, service string) (ServiceExport, error) {
+	read, found := serviceReaders[service]
+	if !found {
+		return ServiceExport{}, fmt.Errorf("unknown service %q: must be one of %s", service, strings.Join(Services, ", "))
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ServiceExport{}, fmt.Errorf("file not found: %s", path)
+	}
+	if err != nil {
+		return ServiceExport{}, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	export, err := read(data)
+	if err != nil {
+		return ServiceExport{}, fmt.Errorf("invalid %s export: %w", service, err)
+	}
+	return export, nil
+}
+
+// readSpotify reads the "albums" list of the YourLibrary.json file in a
+// Spotify account data export, or the "items" of a saved albums response
+// from the Spotify Web API
+func readSpotify(data []byte) (ServiceExport, error) {
+	var export struct {
+		Albums []struct {
+			Artist string `json:"artist"`
+			Album  string `json:"album"`
+		} `json:"albums"`
+		Items []struct {
+			Album struct {
+				Name    string `json:"name"`
+				Artists []struct {
+					Name string `json:"name"`
+				} `json:"artists"`
+				ReleaseDate string `json:"release_date"`
+			} `json:"album"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return ServiceExport{}, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	var albums []ServiceAlbum
+	for i, saved := range export.Albums {
+		albums = append(albums, serviceAlbum(i+1, "", saved.Artist, saved.Album, 0))
+	}
+	for i, saved := range export.Items {
+		var artists []string
+		for _, artist := range saved.Album.Artists {
+			artists = append(artists, artist.Name)
+		}
+		albums = append(albums, serviceAlbum(i+1, "", strings.Join(artists, ", "), saved.Album.Name, parseYear(saved.Album.ReleaseDate)))
+	}
+	return ServiceExport{Albums: albums}, nil
+}
+
+// readLastFM reads a CSV of scrobbles, with a header naming the artist and
+// album columns or, as written by common export tools, without a header in
+// the columns artist, album, track and date
+func readLastFM(data []byte) (ServiceExport, error) {
+	rows, header, err := readServiceCSV(data, []string{"artist", "album"}, []string{"artist", "album", "track", "date"})
+	if err != nil {
+		return ServiceExport{}, err
+	}
+
+	var albums []ServiceAlbum
+	for _, row := range rows {
+		albums = append(albums, serviceAlbum(row.line, row.text, row.cell("artist", "artist name"), row.cell("album", "album name"), 0))
+	}
+	return ServiceExport{Albums: firstOfAlbum(albums), Header: header}, nil
+}
+
+// readBandcamp reads a Bandcamp collection as CSV or as the JSON of the fan
+// collection API, a list of items or an object with an "items" list. Albums
+// and packages are listed by title; tracks by the album they are on, and
+// tracks released on their own without an album title.
+func readBandcamp(data []byte) (ServiceExport, error) {
+	var rows []serviceRow
+	var header string
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		rows, err = readServiceJSON(trimmed)
+	} else {
+		rows, header, err = readServiceCSV(data, []string{"band_name", "band name", "artist"}, nil)
+	}
+	if err != nil {
+		return ServiceExport{}, err
+	}
+
+	var albums []ServiceAlbum
+	for _, row := range rows {
+		var title string
+		if kind := strings.ToLower(row.cell("item_type", "item type", "type")); kind == "track" || kind == "t" {
+			title = row.cell("album_title", "album title", "album")
+		} else {
+			title = row.cell("album_title", "album title", "album", "item_title", "item title", "title")
+		}
+		albums = append(albums, serviceAlbum(row.line, row.text, row.cell("band_name", "band name", "artist", "band"), title, 0))
+	}
+	return ServiceExport{Albums: firstOfAlbum(albums), Header: header}, nil
+}
+
+// readDiscogs reads a Discogs collection CSV export. The numbers and stars
+// Discogs adds to artist names are dropped.
+func readDiscogs(data []byte) (ServiceExport, error) {
+	rows, header, err := readServiceCSV(data, []string{"artist", "title"}, nil)
+	if err != nil {
+		return ServiceExport{}, err
+	}
+
+	var albums []ServiceAlbum
+	for _, row := range rows {
+		artist := discogsSuffix.ReplaceAllString(row.cell("artist"), "")
+		albums = append(albums, serviceAlbum(row.line, row.text, artist, row.cell("title"), parseYear(row.cell("released"))))
+	}
+	return ServiceExport{Albums: albums, Header: header}, nil
+}
+
+// readRYM reads a RateYourMusic CSV export, whose artists are split into a
+// first and last name
+func readRYM(data []byte) (ServiceExport, error) {
+	rows, header, err := readServiceCSV(data, []string{"last name", "title"}, nil)
+	if err != nil {
+		return ServiceExport{}, err
+	}
+
+	var albums []ServiceAlbum
+	for _, row := range rows {
+		artist := row.cell("first name") + " " + row.cell("last name")
+		albums = append(albums, serviceAlbum(row.line, row.text, artist, row.cell("title"), parseYear(row.cell("release_date", "release date"))))
+	}
+	return ServiceExport{Albums: albums, Header: header}, nil
+}
+
+// serviceAlbum makes the album of an export entry, with the runs of spaces
+// in its artist and title collapsed
+func serviceAlbum(line int, text, artist, title string, year int) ServiceAlbum {
+	return ServiceAlbum{
+		Artist: strings.Join(strings.Fields(artist), " "),
+		Title:  strings.Join(strings.Fields(title), " "),
+		Year:   year,
+		Line:   line,
+		Text:   text,
+	}
+}
+
+// firstOfAlbum drops the albums repeating an earlier album's artist and
+// title exactly (ignoring case), so that exports listing tracks list each
+// album once. Entries without an artist or title are kept.
+func firstOfAlbum(albums []ServiceAlbum) []ServiceAlbum {
+	seen := map[string]bool{}
+	return slices.DeleteFunc(albums, func(album ServiceAlbum) bool {
+		if album.Artist == "" || album.Title == "" {
+			return false
+		}
+		key := strings.ToLower(album.Artist) + "\x00" + strings.ToLower(album.Title)
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		return false
+	})
+}
+
+// serviceRow is an entry of a service export with its cells by normalized
+// column name, and its position and text
+type serviceRow struct {
+	line  int
+	text  string
+	cells map[string]string
+}
+
+// cell returns the first non-empty cell among the columns names
+func (r serviceRow) cell(names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(r.cells[name]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// columnName folds a header name for comparison
+func columnName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// readServiceCSV reads the rows of a comma-separated export and its header
+// row as written. The first row is the header when it names one of columns;
+// otherwise the columns are named by fallback and the header text is empty,
+// or without fallback the export is rejected.
+func readServiceCSV(data []byte, columns, fallback []string) ([]serviceRow, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	// rowText returns the row read last as written, from offset on
+	rowText := func(offset int64) string {
+		return strings.TrimRight(string(data[offset:reader.InputOffset()]), "\r\n")
+	}
+
+	first, err := reader.Read()
+	if err == io.EOF {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	header := make([]string, len(first))
+	isHeader := false
+	for i, cell := range first {
+		header[i] = columnName(cell)
+		isHeader = isHeader || slices.Contains(columns, header[i])
+	}
+
+	row, headerText, offset := first, "", int64(0)
+	if isHeader {
+		headerText = rowText(0)
+		offset = reader.InputOffset()
+		row, err = reader.Read()
+	} else if fallback != nil {
+		header = fallback
+	} else {
+		return nil, "", fmt.Errorf("no header row naming the %s columns", strings.Join(columns, " and "))
+	}
+
+	var rows []serviceRow
+	for ; err != io.EOF; row, err = reader.Read() {
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read CSV: %w", err)
+		}
+		text := rowText(offset)
+		offset = reader.InputOffset()
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		cells := make(map[string]string, len(row))
+		for i, cell := range row {
+			if i < len(header) {
+				if _, found := cells[header[i]]; !found {
+					cells[header[i]] = cell
+				}
+			}
+		}
+		rows = append(rows, serviceRow{line: line, text: strings.TrimLeft(text, "\r\n"), cells: cells})
+	}
+	return rows, headerText, nil
+}
+
+// readServiceJSON reads the entries of a JSON export, a list of objects or
+// an object with an "items" list of them. String values are kept as cells.
+func readServiceJSON(data []byte) ([]serviceRow, error) {
+	var entries []map[string]any
+	if data[0] == '{' {
+		var export struct {
+			Items []map[string]any `json:"items"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("failed to read JSON: %w", err)
+		}
+		entries = export.Items
+	} else if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	rows := make([]serviceRow, len(entries))
+	for i, entry := range entries {
+		cells := map[string]string{}
+		for key, value := range entry {
+			if text, ok := value.(string); ok {
+				cells[columnName(key)] = text
+			}
+		}
+		rows[i] = serviceRow{line: i + 1, cells: cells}
+	}
+	return rows, nil
+}
@@ -0,0 +1,155 @@
+package library
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadServiceExport(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  string
+		content  string
+		expected []string
+		years    []int
+	}{
+		{
+			name:    "Spotify library",
+			service: ServiceSpotify,
+			content: `{"tracks": [{"artist": "Radiohead", "album": "Kid A", "track": "Idioteque"}],
+				"albums": [{"artist": "Radiohead", "album": "OK Computer", "uri": "spotify:album:1"},
+				{"artist": "Sleater-Kinney", "album": "Dig Me Out", "uri": "spotify:album:2"}]}`,
+			expected: []string{"Radiohead - OK Computer", "Sleater-Kinney - Dig Me Out"},
+			years:    []int{0, 0},
+		},
+		{
+			name:    "Spotify saved albums API",
+			service: ServiceSpotify,
+			content: `{"items": [{"added_at": "2024-01-01T00:00:00Z", "album": {"name": "Watch the Throne",
+				"artists": [{"name": "Jay-Z"}, {"name": "Kanye West"}], "release_date": "2011-08-08"}}]}`,
+			expected: []string{"Jay-Z, Kanye West - Watch the Throne"},
+			years:    []int{2011},
+		},
+		{
+			name:     "Last.fm without header",
+			service:  ServiceLastFM,
+			content:  "Radiohead,OK Computer,Airbag,31 Jan 2024 20:00\nRadiohead,OK Computer,Paranoid Android,31 Jan 2024 20:05\nSlowdive,Souvlaki,Alison,01 Feb 2024 09:00\n",
+			expected: []string{"Radiohead - OK Computer", "Slowdive - Souvlaki"},
+			years:    []int{0, 0},
+		},
+		{
+			name:     "Last.fm with header",
+			service:  ServiceLastFM,
+			content:  "uts,utc_time,artist,artist_mbid,album,album_mbid,track,track_mbid\n1706731200,31 Jan 2024,Radiohead,,OK Computer,,Airbag,\n",
+			expected: []string{"Radiohead - OK Computer"},
+			years:    []int{0},
+		},
+		{
+			name:     "Bandcamp CSV",
+			service:  ServiceBandcamp,
+			content:  "band_name,item_title,item_type,album_title\nBlack Country New Road,Ants From Up There,album,\nBlack Country New Road,Concorde,track,Ants From Up There\nLow,Hey What,package,\n",
+			expected: []string{"Black Country New Road - Ants From Up There", "Low - Hey What"},
+			years:    []int{0, 0},
+		},
+		{
+			name:     "Bandcamp JSON",
+			service:  ServiceBandcamp,
+			content:  `{"items": [{"band_name": "Low", "item_title": "Hey What", "item_type": "album", "item_id": 1}, {"band_name": "Low", "item_title": "Days Like These", "item_type": "track", "album_title": "Hey What"}]}`,
+			expected: []string{"Low - Hey What"},
+			years:    []int{0},
+		},
+		{
+			name:     "Discogs",
+			service:  ServiceDiscogs,
+			content:  "Catalog#,Artist,Title,Label,Format,Rating,Released,release_id,CollectionFolder,Date Added\nDGC-24425,Nirvana (2),Nevermind,DGC,\"LP, Album\",,1991,123,Uncategorized,2024-01-01 10:00:00\nMCA-1,Beck*,Odelay,DGC,CD,,1996-06-18,456,Uncategorized,2024-01-02 10:00:00\n",
+			expected: []string{"Nirvana - Nevermind", "Beck - Odelay"},
+			years:    []int{1991, 1996},
+		},
+		{
+			name:     "RateYourMusic",
+			service:  ServiceRYM,
+			content:  "RYM Album, First Name,Last Name,First Name localized, Last Name localized,Title,Release_Date,Rating,Ownership,Purchase Date,Media Type,Review\n1,The,Beatles,,,Abbey Road,1969,10,,,,\n2,,Radiohead,,,OK Computer,1997,9,,,,\n",
+			expected: []string{"The Beatles - Abbey Road", "Radiohead - OK Computer"},
+			years:    []int{1969, 1997},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export, err := ReadServiceExport(writeFile(t, t.TempDir(), "export", []byte(tt.content)), tt.service)
+			if err != nil {
+				t.Fatalf("ReadServiceExport() error = %v", err)
+			}
+
+			var texts []string
+			var years []int
+			for _, album := range export.Albums {
+				texts = append(texts, album.Artist+" - "+album.Title)
+				years = append(years, album.Year)
+			}
+			if !reflect.DeepEqual(texts, tt.expected) || !reflect.DeepEqual(years, tt.years) {
+				t.Errorf("ReadServiceExport() = %v with years %v, want %v with %v", texts, years, tt.expected, tt.years)
+			}
+		})
+	}
+}
+
+func TestReadServiceExport_Entries(t *testing.T) {
+	dir := t.TempDir()
+
+	// Later tracks of an album are dropped; entries without an album are kept
+	lastFM := writeFile(t, dir, "scrobbles.csv", []byte("\ufeffartist,album,track\r\n"+
+		"Radiohead,  OK   Computer ,Airbag\r\n"+
+		"\r\n"+
+		"Burial,,Archangel\r\n"+
+		"radiohead,ok computer,Lucky\r\n"))
+
+	export, err := ReadServiceExport(lastFM, ServiceLastFM)
+	if err != nil {
+		t.Fatalf("ReadServiceExport() error = %v", err)
+	}
+	expected := ServiceExport{
+		Albums: []ServiceAlbum{
+			{Artist: "Radiohead", Title: "OK Computer", Line: 2, Text: "Radiohead,  OK   Computer ,Airbag"},
+			{Artist: "Burial", Line: 4, Text: "Burial,,Archangel"},
+		},
+		Header: "artist,album,track",
+	}
+	if !reflect.DeepEqual(export, expected) {
+		t.Errorf("ReadServiceExport() = %+v, want %+v", export, expected)
+	}
+
+	// JSON entries are numbered by position, without text
+	spotify := writeFile(t, dir, "YourLibrary.json", []byte(`{"albums": [{"artist": "", "album": "Untitled"}]}`))
+	export, err = ReadServiceExport(spotify, ServiceSpotify)
+	if err != nil {
+		t.Fatalf("ReadServiceExport() error = %v", err)
+	}
+	expected = ServiceExport{Albums: []ServiceAlbum{{Title: "Untitled", Line: 1}}}
+	if !reflect.DeepEqual(export, expected) {
+		t.Errorf("ReadServiceExport() = %+v, want %+v", export, expected)
+	}
+}
+
+func TestReadServiceExport_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		path     string
+		service  string
+		expected string
+	}{
+		{writeFile(t, dir, "a.csv", []byte("Artist,Title\nNirvana,Nevermind\n")), "napster", `unknown service "napster"`},
+		{writeFile(t, dir, "b.json", []byte("not json")), ServiceSpotify, "invalid spotify export: failed to read JSON"},
+		{writeFile(t, dir, "c.csv", []byte("Nirvana,Nevermind\n")), ServiceDiscogs, "invalid discogs export: no header row naming the artist and title columns"},
+		{filepath.Join(dir, "missing.csv"), ServiceRYM, "file not found"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadServiceExport(tt.path, tt.service); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("ReadServiceExport(%s, %s) error = %v, want one containing %q", filepath.Base(tt.path), tt.service, err, tt.expected)
+		}
+	}
+}
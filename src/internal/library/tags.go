@@ -0,0 +1,101 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tags are the album details embedded in an audio file. Fields the file does
+// not carry are empty.
+type Tags struct {
+	Artist      string // the track artist
+	AlbumArtist string
+	Album       string
+	Year        int // zero when unknown
+}
+
+// ErrUnsupported is returned by ReadTags for files that are not in a
+// supported audio format
+var ErrUnsupported = errors.New("unsupported audio format")
+
+// tagReaders read the tags of each supported audio format, by file extension
+var tagReaders = map[string]func(io.ReadSeeker) (Tags, error){
+	".mp3":  readMP3,
+	".flac": readFLAC,
+	".ogg":  readOgg,
+	".oga":  readOgg,
+	".opus": readOgg,
+	".m4a":  readMP4,
+	".m4b":  readMP4,
+}
+
+// IsAudioFile reports whether path has the extension of a supported audio
+// format
+func IsAudioFile(path string) bool {
+	_, found := tagReaders[strings.ToLower(filepath.Ext(path))]
+	return found
+}
+
+// ReadTags reads the tags of an audio file: ID3v2 (falling back to ID3v1) for
+// MP3, Vorbis comments for FLAC and Ogg Vorbis or Opus, and iTunes metadata
+// for M4A. The format is chosen by file extension. A file without tags
+// returns empty Tags and no error.
+func ReadTags(path string) (Tags, error) {
+	read, found := tagReaders[strings.ToLower(filepath.Ext(path))]
+	if !found {
+		return Tags{}, ErrUnsupported
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Tags{}, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	tags, err := read(file)
+	if err != nil {
+		return Tags{}, err
+	}
+	return tags, nil
+}
+
+// setFirst stores a tag value in field unless it already holds one, so the
+// first value of a repeated tag wins. Runs of whitespace, including line
+// breaks, become single spaces.
+func setFirst(field *string, value string) {
+	if *field == "" {
+		*field = strings.Join(strings.Fields(value), " ")
+	}
+}
+
+// setYear stores the year of a date tag such as "1997" or "1997-05-21",
+// keeping the first year found
+func (t *Tags) setYear(value string) {
+	if t.Year == 0 {
+		t.Year = parseYear(value)
+	}
+}
+
+// parseYear returns the year at the start of a date, or zero when there is
+// none
+func parseYear(value string) int {
+	value = strings.TrimSpace(value)
+	if len(value) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(value[:4])
+	if err != nil || year < 1000 {
+		return 0
+	}
+	return year
+}
+
+// errTruncated reports a tag that ends before its declared size
+func errTruncated(format string) error {
+	return fmt.Errorf("invalid %s tag: truncated", format)
+}
@@ -0,0 +1,274 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// id3Frame builds an ID3v2.3 or v2.4 text frame in UTF-8 (v2.4) or
+// ISO-8859-1 (v2.3)
+func id3Frame(version byte, id, text string) []byte {
+	content := append([]byte{0}, text...)
+	if version == 4 {
+		content[0] = 3
+	}
+	frame := append([]byte(id), 0, 0, 0, 0, 0, 0)
+	size := len(content)
+	if version == 4 {
+		size = size&0x7f | (size>>7&0x7f)<<8 | (size>>14&0x7f)<<16 | (size>>21&0x7f)<<24
+	}
+	binary.BigEndian.PutUint32(frame[4:], uint32(size))
+	return append(frame, content...)
+}
+
+// id3Tag builds an ID3v2 tag holding frames, followed by padding
+func id3Tag(version byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	body = append(body, make([]byte, 16)...)
+	size := len(body)
+	header := []byte{'I', 'D', '3', version, 0, 0,
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(header, body...)
+}
+
+// id3v1Tag builds the 128-byte ID3v1 tag
+func id3v1Tag(artist, album, year string) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[33:63], artist)
+	copy(tag[63:93], album)
+	copy(tag[93:97], year)
+	return tag
+}
+
+// vorbisComment builds a Vorbis comment from KEY=value entries
+func vorbisComment(comments ...string) []byte {
+	var buffer bytes.Buffer
+	write := func(s string) {
+		binary.Write(&buffer, binary.LittleEndian, uint32(len(s)))
+		buffer.WriteString(s)
+	}
+	write("test vendor")
+	binary.Write(&buffer, binary.LittleEndian, uint32(len(comments)))
+	for _, comment := range comments {
+		write(comment)
+	}
+	return buffer.Bytes()
+}
+
+// flacFile builds a FLAC stream with a STREAMINFO block and a Vorbis comment
+func flacFile(comments ...string) []byte {
+	block := func(last bool, kind byte, content []byte) []byte {
+		if last {
+			kind |= 0x80
+		}
+		size := len(content)
+		return append([]byte{kind, byte(size >> 16), byte(size >> 8), byte(size)}, content...)
+	}
+	data := []byte("fLaC")
+	data = append(data, block(false, 0, make([]byte, 34))...)
+	return append(data, block(true, flacVorbisComment, vorbisComment(comments...))...)
+}
+
+// oggFile builds an Ogg stream whose packets are each laced into one page
+func oggFile(packets ...[]byte) []byte {
+	var data []byte
+	for i, packet := range packets {
+		var segments []byte
+		for size := len(packet); ; size -= 255 {
+			if size < 255 {
+				segments = append(segments, byte(size))
+				break
+			}
+			segments = append(segments, 255)
+		}
+		header := make([]byte, 27)
+		copy(header, "OggS")
+		binary.LittleEndian.PutUint32(header[14:], 1234)
+		binary.LittleEndian.PutUint32(header[18:], uint32(i))
+		header[26] = byte(len(segments))
+		data = append(data, header...)
+		data = append(data, segments...)
+		data = append(data, packet...)
+	}
+	return data
+}
+
+// mp4Atom builds an atom of kind holding content
+func mp4Atom(kind string, content ...[]byte) []byte {
+	body := bytes.Join(content, nil)
+	atom := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(atom, uint32(8+len(body)))
+	copy(atom[4:], kind)
+	return append(atom, body...)
+}
+
+// mp4File builds an MP4 file with iTunes metadata items
+func mp4File(items map[string]string) []byte {
+	var list [][]byte
+	for kind, value := range items {
+		data := append([]byte{0, 0, 0, mp4DataUTF8, 0, 0, 0, 0}, value...)
+		list = append(list, mp4Atom(kind, mp4Atom("data", data)))
+	}
+	meta := mp4Atom("meta", []byte{0, 0, 0, 0}, mp4Atom("hdlr", make([]byte, 25)), mp4Atom("ilst", list...))
+	return append(mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4Atom("moov", mp4Atom("mvhd", make([]byte, 100)), mp4Atom("udta", meta))...)
+}
+
+// writeFile writes data to name in dir, creating its directories
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadTags(t *testing.T) {
+	utf16Frame := func(id, text string) []byte {
+		content := []byte{1, 0xff, 0xfe}
+		for _, unit := range utf16.Encode([]rune(text)) {
+			content = binary.LittleEndian.AppendUint16(content, unit)
+		}
+		frame := append([]byte(id), 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(frame[4:], uint32(len(content)))
+		return append(frame, content...)
+	}
+	audio := make([]byte, 256)
+
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		expected Tags
+	}{
+		{
+			name: "ID3v2.4",
+			file: "track.mp3",
+			data: append(id3Tag(4,
+				id3Frame(4, "TIT2", "Airbag"),
+				id3Frame(4, "TPE1", "Radiohead"),
+				id3Frame(4, "TALB", "OK Computer"),
+				id3Frame(4, "TDRC", "1997-05-21"),
+			), audio...),
+			expected: Tags{Artist: "Radiohead", Album: "OK Computer", Year: 1997},
+		},
+		{
+			name: "ID3v2.3 with UTF-16 and album artist",
+			file: "track.MP3",
+			data: append(id3Tag(3,
+				utf16Frame("TPE1", "Björk"),
+				id3Frame(3, "TPE2", "Bjork & Friends"),
+				utf16Frame("TALB", "Homogenic"),
+				id3Frame(3, "TYER", "1997"),
+			), audio...),
+			expected: Tags{Artist: "Björk", AlbumArtist: "Bjork & Friends", Album: "Homogenic", Year: 1997},
+		},
+		{
+			name:     "ID3v1 fills in",
+			file:     "track.mp3",
+			data:     append(append(id3Tag(3, id3Frame(3, "TPE1", "Portishead")), audio...), id3v1Tag("Ignored", "Dummy", "1994")...),
+			expected: Tags{Artist: "Portishead", Album: "Dummy", Year: 1994},
+		},
+		{
+			// Sizes past the end of the tag must not wrap around to fit
+			name:     "ID3v2.3 with a huge frame size",
+			file:     "track.mp3",
+			data:     append(id3Tag(3, id3Frame(3, "TPE1", "Low"), []byte("TALB\xff\xff\xff\xff\x00\x00Hey What")), audio...),
+			expected: Tags{Artist: "Low"},
+		},
+		{
+			name:     "no tags",
+			file:     "track.mp3",
+			data:     audio,
+			expected: Tags{},
+		},
+		{
+			name:     "FLAC",
+			file:     "track.flac",
+			data:     flacFile("TITLE=Teardrop", "artist=Massive Attack", "ALBUMARTIST=Massive Attack", "ALBUM=Mezzanine", "DATE=1998"),
+			expected: Tags{Artist: "Massive Attack", AlbumArtist: "Massive Attack", Album: "Mezzanine", Year: 1998},
+		},
+		{
+			name:     "FLAC after ID3v2",
+			file:     "track.flac",
+			data:     append(id3Tag(3, id3Frame(3, "TPE1", "Ignored")), flacFile("ARTIST=Slowdive", "ALBUM=Souvlaki")...),
+			expected: Tags{Artist: "Slowdive", Album: "Souvlaki"},
+		},
+		{
+			name: "Ogg Vorbis",
+			file: "track.ogg",
+			data: oggFile(
+				append([]byte("\x01vorbis"), make([]byte, 23)...),
+				append([]byte("\x03vorbis"), vorbisComment("ARTIST=Stereolab", "ALBUM=Dots and Loops", "YEAR=1997")...),
+			),
+			expected: Tags{Artist: "Stereolab", Album: "Dots and Loops", Year: 1997},
+		},
+		{
+			name: "Opus with a long comment packet",
+			file: "track.opus",
+			data: oggFile(
+				append([]byte("OpusHead"), make([]byte, 11)...),
+				append([]byte("OpusTags"), vorbisComment("ARTIST=Low", "ALBUM=Double Negative", "COMMENT="+string(bytes.Repeat([]byte("x"), 600)))...),
+			),
+			expected: Tags{Artist: "Low", Album: "Double Negative"},
+		},
+		{
+			name: "M4A",
+			file: "track.m4a",
+			data: mp4File(map[string]string{
+				"\xa9nam": "Hyperballad",
+				"\xa9ART": "Björk",
+				"aART":    "Björk",
+				"\xa9alb": "Post",
+				"\xa9day": "1995-06-13T07:00:00Z",
+			}),
+			expected: Tags{Artist: "Björk", AlbumArtist: "Björk", Album: "Post", Year: 1995},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), tt.file, tt.data)
+
+			tags, err := ReadTags(path)
+			if err != nil {
+				t.Fatalf("ReadTags() error = %v", err)
+			}
+			if tags != tt.expected {
+				t.Errorf("ReadTags() = %+v, want %+v", tags, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadTags_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := ReadTags(writeFile(t, dir, "cover.jpg", []byte("jpeg"))); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ReadTags(cover.jpg) error = %v, want ErrUnsupported", err)
+	}
+
+	tests := map[string][]byte{
+		"not-flac.flac":  []byte("RIFF0000WAVE"),
+		"truncated.flac": flacFile("ARTIST=Slowdive", "ALBUM=Souvlaki")[:50],
+		"not-ogg.ogg":    []byte("ID3 not an ogg"),
+		"not-mp4.m4a":    mp4Atom("free", make([]byte, 8)),
+		"truncated.mp3":  id3Tag(4, id3Frame(4, "TALB", "Souvlaki"))[:20],
+		"huge-extended-header.mp3": append([]byte("ID3\x03\x00\x40\x00\x00\x00\x10\xff\xff\xff\xfc"),
+			make([]byte, 12)...),
+	}
+	for name, data := range tests {
+		if _, err := ReadTags(writeFile(t, dir, name, data)); err == nil {
+			t.Errorf("ReadTags(%s) error = nil, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package library
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FLAC metadata block type of the Vorbis comment
+const flacVorbisComment = 4
+
+// maxOggPacket limits the size of the comment packet read from an Ogg file,
+// which may embed cover art
+const maxOggPacket = 16 << 20
+
+// readFLAC reads the Vorbis comment block of a FLAC file
+func readFLAC(r io.ReadSeeker) (Tags, error) {
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker); err != nil {
+		return Tags{}, fmt.Errorf("not a FLAC file")
+	}
+
+	// Some taggers put an ID3v2 tag before the stream
+	if string(marker[:3]) == "ID3" {
+		header := make([]byte, id3Header)
+		copy(header, marker)
+		if _, err := io.ReadFull(r, header[4:]); err != nil {
+			return Tags{}, errTruncated("ID3v2")
+		}
+		skip := int64(syncsafe(header[6:10]))
+		if header[5]&id3Footer != 0 {
+			skip += id3Header
+		}
+		if _, err := r.Seek(skip, io.SeekCurrent); err != nil {
+			return Tags{}, err
+		}
+		if _, err := io.ReadFull(r, marker); err != nil {
+			return Tags{}, fmt.Errorf("not a FLAC file")
+		}
+	}
+	if string(marker) != "fLaC" {
+		return Tags{}, fmt.Errorf("not a FLAC file")
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return Tags{}, errTruncated("FLAC")
+		}
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7f
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		if blockType == flacVorbisComment {
+			block := make([]byte, size)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return Tags{}, errTruncated("FLAC")
+			}
+			return parseVorbisComment(block)
+		}
+		if last {
+			return Tags{}, nil
+		}
+		if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+			return Tags{}, err
+		}
+	}
+}
+
+// readOgg reads the Vorbis comment of an Ogg Vorbis or Opus file, carried in
+// the second packet of the first logical stream
+func readOgg(r io.ReadSeeker) (Tags, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 27)
+	var serial uint32
+	var packet []byte
+
+	for pages, packets := 0, 0; ; pages++ {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if pages == 0 {
+				return Tags{}, fmt.Errorf("not an Ogg file")
+			}
+			return Tags{}, errTruncated("Ogg")
+		}
+		if string(header[:4]) != "OggS" {
+			return Tags{}, fmt.Errorf("not an Ogg file")
+		}
+
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(reader, segments); err != nil {
+			return Tags{}, errTruncated("Ogg")
+		}
+		body := 0
+		for _, size := range segments {
+			body += int(size)
+		}
+		data := make([]byte, body)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return Tags{}, errTruncated("Ogg")
+		}
+
+		// Pages of other streams may be interleaved with the first
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if pages == 0 {
+			serial = pageSerial
+		} else if pageSerial != serial {
+			continue
+		}
+
+		// A packet ends with a segment shorter than 255 bytes
+		for _, size := range segments {
+			packet = append(packet, data[:size]...)
+			data = data[size:]
+			if len(packet) > maxOggPacket {
+				return Tags{}, fmt.Errorf("invalid Ogg tag: comment packet too large")
+			}
+			if size == 255 {
+				continue
+			}
+
+			packets++
+			if packets == 2 {
+				return parseOggComment(packet)
+			}
+			packet = nil
+		}
+	}
+}
+
+// parseOggComment reads the comment packet of a Vorbis or Opus stream. Other
+// codecs return empty Tags.
+func parseOggComment(packet []byte) (Tags, error) {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		return parseVorbisComment(packet[7:])
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		return parseVorbisComment(packet[8:])
+	}
+	return Tags{}, nil
+}
+
+// parseVorbisComment reads the album fields of a Vorbis comment: a vendor
+// string and a list of KEY=value entries, with case-insensitive keys
+func parseVorbisComment(data []byte) (Tags, error) {
+	next := func() ([]byte, bool) {
+		if len(data) < 4 {
+			return nil, false
+		}
+		size := binary.LittleEndian.Uint32(data)
+		if uint64(size) > uint64(len(data)-4) {
+			return nil, false
+		}
+		value := data[4 : 4+size]
+		data = data[4+size:]
+		return value, true
+	}
+
+	if _, ok := next(); !ok {
+		return Tags{}, errTruncated("Vorbis comment")
+	}
+	if len(data) < 4 {
+		return Tags{}, errTruncated("Vorbis comment")
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	var tags Tags
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return Tags{}, errTruncated("Vorbis comment")
+		}
+		key, value, found := strings.Cut(string(comment), "=")
+		if !found {
+			continue
+		}
+
+		switch strings.ToUpper(key) {
+		case "ARTIST":
+			setFirst(&tags.Artist, value)
+		case "ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST":
+			setFirst(&tags.AlbumArtist, value)
+		case "ALBUM":
+			setFirst(&tags.Album, value)
+		case "DATE", "YEAR", "ORIGINALDATE":
+			tags.setYear(value)
+		}
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,418 @@
+package queue
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Album is a single entry in the queue
+type Album struct {
+	Artist   string
+	Title    string
+	AddedAt  time.Time // zero for entries written before metadata was recorded
+	Source   string    // how the album got into the queue, e.g. "add" or "import"
+	Notes    string
+	Year     int // zero when unknown
+	Priority int // selection weight; zero means DefaultPriority
+	Skips    int // times the album was put back with a demotion; each halves its weight
+
+	// SnoozedUntil hides the album from selection until this time; zero when
+	// the album is not snoozed
+	SnoozedUntil time.Time
+
+	// Pinned albums are picked before any other, in queue order, regardless
+	// of the selection mode
+	Pinned bool
+
+	Tags []string // free-form labels such as genres or moods, normalized by NormalizeTags
+}
+
+// DefaultPriority is the selection weight of albums without an explicit priority
+const DefaultPriority = 1
+
+// Record field keys used when persisting album metadata
+const (
+	fieldAdded    = "added"
+	fieldSource   = "source"
+	fieldNotes    = "notes"
+	fieldYear     = "year"
+	fieldPriority = "priority"
+	fieldSkips    = "skips"
+	fieldSnoozed  = "snoozed"
+	fieldPinned   = "pinned"
+	fieldTags     = "tags"
+)
+
+// separatorDashes separate artist from title when written with spaces around
+// them: a hyphen, en dash or em dash. A dash inside a word, as in "Jay-Z", is
+// part of the name.
+var separatorDashes = []rune{'-', '\u2013', '\u2014'}
+
+// AlbumFormatError reports text that is not a valid "Artist - Album" string
+type AlbumFormatError struct {
+	Text    string // the text as given, trimmed
+	Problem string // what is wrong, e.g. "missing artist"
+}
+
+// Error implements error
+func (e *AlbumFormatError) Error() string {
+	if e.Text == "" {
+		return "invalid album format: " + e.Problem
+	}
+	return fmt.Sprintf("invalid album format: %s in %q", e.Problem, e.Text)
+}
+
+// ParseAlbum parses an "Artist - Album Title" string into an Album.
+// The first hyphen, en dash or em dash with spaces around it separates artist
+// from title, so the title may contain further separators ("Blur - Parklife -
+// Remastered") but dashes within a word ("Jay-Z") never separate. An artist
+// that contains a separator itself is written in double quotes, inside which
+// \" and \\ stand for a quote and a backslash:
+//
+//	"Everything - Everything" - Get to Heaven
+//
+// Errors are *AlbumFormatError.
+func ParseAlbum(text string) (Album, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Album{}, &AlbumFormatError{Problem: "album is empty"}
+	}
+
+	artist, rest, quoted, err := parseQuotedArtist(text)
+	if err != nil {
+		return Album{}, err
+	}
+	if !quoted {
+		separator, width := findSeparator(text)
+		if separator == -1 {
+			problem := "missing ' - ' between artist and album"
+			if strings.ContainsAny(text, string(separatorDashes)) {
+				problem += " (a dash only separates them with spaces around it)"
+			}
+			return Album{}, &AlbumFormatError{Text: text, Problem: problem}
+		}
+		artist, rest = strings.TrimSpace(text[:separator]), text[separator+width:]
+	}
+	title := strings.TrimSpace(rest)
+
+	if strings.TrimSpace(artist) == "" {
+		return Album{}, &AlbumFormatError{Text: text, Problem: "missing artist"}
+	}
+
+	if title == "" {
+		return Album{}, &AlbumFormatError{Text: text, Problem: "missing album title"}
+	}
+
+	return Album{Artist: artist, Title: title}, nil
+}
+
+// parseLegacyAlbum parses album text like ParseAlbum, falling back to the
+// rule of older versions for text it rejects: the artist ends at the first
+// dash, even without spaces around it, so that "Radiohead-OK Computer" stored
+// back then still reads as an album. The album is written back with a spaced
+// separator the next time its file is saved.
+func parseLegacyAlbum(text string) (Album, error) {
+	album, err := ParseAlbum(text)
+	if err == nil {
+		return album, nil
+	}
+
+	artist, title, found := strings.Cut(strings.TrimSpace(text), "-")
+	artist, title = strings.TrimSpace(artist), strings.TrimSpace(title)
+	if !found || artist == "" || title == "" {
+		return Album{}, err
+	}
+	return Album{Artist: artist, Title: title}, nil
+}
+
+// parseQuotedArtist reads a double-quoted artist at the start of text and
+// returns it unescaped with the text after its separator. quoted is false,
+// and text should be parsed as unquoted, when text does not start with a
+// quote or the closing quote is not followed by a separator, as in
+// `"Weird Al" Yankovic - Mandatory Fun`.
+func parseQuotedArtist(text string) (artist, rest string, quoted bool, err error) {
+	if !strings.HasPrefix(text, `"`) {
+		return "", "", false, nil
+	}
+
+	var builder strings.Builder
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 < len(text) && (text[i+1] == '"' || text[i+1] == '\\') {
+				i++
+			}
+			builder.WriteByte(text[i])
+		case '"':
+			separator, width := findSeparator(text[i+1:])
+			if separator == -1 || strings.TrimSpace(text[i+1:i+1+separator]) != "" {
+				return "", "", false, nil
+			}
+			return builder.String(), text[i+1+separator+width:], true, nil
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+
+	if _, width := findSeparator(text); width == 0 {
+		return "", "", false, nil
+	}
+	return "", "", false, &AlbumFormatError{Text: text, Problem: "missing closing quote after the artist"}
+}
+
+// findSeparator returns the byte index and width of the first separator dash
+// in text, together with the spaces around it, or -1 if there is none. A dash
+// at the start or end of text counts as spaced on that side.
+func findSeparator(text string) (index, width int) {
+	for i, r := range text {
+		if !slices.Contains(separatorDashes, r) {
+			continue
+		}
+
+		before := strings.TrimRightFunc(text[:i], unicode.IsSpace)
+		after := strings.TrimLeftFunc(text[i+utf8.RuneLen(r):], unicode.IsSpace)
+		if len(before) == i && i > 0 || len(text)-len(after) == i+utf8.RuneLen(r) && after != "" {
+			// Part of a word, as in "Jay-Z"
+			continue
+		}
+
+		return len(before), len(text) - len(after) - len(before)
+	}
+	return -1, 0
+}
+
+// String returns the album in "Artist - Album Title" format. An artist that
+// would not parse back unchanged, such as one containing " - ", is quoted
+// (see ParseAlbum).
+func (a Album) String() string {
+	return quoteArtist(a.Artist) + " - " + a.Title
+}
+
+// quoteArtist returns artist as written before the separator: unchanged when
+// it parses back as itself, otherwise in double quotes with quotes and
+// backslashes escaped
+func quoteArtist(artist string) string {
+	if parsed, err := ParseAlbum(artist + " - _"); err == nil && parsed.Artist == artist {
+		return artist
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + replacer.Replace(artist) + `"`
+}
+
+// EffectivePriority returns the album's priority, or DefaultPriority when
+// none is set
+func (a Album) EffectivePriority() int {
+	if a.Priority <= 0 {
+		return DefaultPriority
+	}
+	return a.Priority
+}
+
+// Weight returns the album's selection weight: its effective priority,
+// halved for every demoting skip
+func (a Album) Weight() float64 {
+	return math.Ldexp(float64(a.EffectivePriority()), -a.Skips)
+}
+
+// Snoozed reports whether the album is hidden from selection at now
+func (a Album) Snoozed(now time.Time) bool {
+	return !a.SnoozedUntil.IsZero() && now.Before(a.SnoozedUntil)
+}
+
+// HasTag reports whether the album carries tag, ignoring case
+func (a Album) HasTag(tag string) bool {
+	return slices.Contains(a.Tags, strings.ToLower(strings.TrimSpace(tag)))
+}
+
+// NormalizeTags splits each of tags at commas and returns the parts trimmed,
+// lowercased and without empty entries or repeats, in their original order
+func NormalizeTags(tags ...string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		for _, part := range strings.Split(tag, ",") {
+			part = strings.ToLower(strings.Join(strings.Fields(part), " "))
+			if part != "" && !slices.Contains(normalized, part) {
+				normalized = append(normalized, part)
+			}
+		}
+	}
+	return normalized
+}
+
+// Key returns the value used for case-insensitive duplicate detection
+func (a Album) Key() string {
+	return strings.ToLower(a.String())
+}
+
+// FormatRecord encodes an album as a single storage line: the album text
+// followed by tab-separated key=value metadata fields. Unset fields are omitted,
+// so an album without metadata is stored as plain "Artist - Album" text.
+func FormatRecord(a Album) string {
+	fields := []string{escapeField(a.String())}
+
+	if !a.AddedAt.IsZero() {
+		fields = append(fields, fieldAdded+"="+a.AddedAt.UTC().Format(time.RFC3339))
+	}
+	if a.Source != "" {
+		fields = append(fields, fieldSource+"="+escapeField(a.Source))
+	}
+	if a.Year != 0 {
+		fields = append(fields, fieldYear+"="+strconv.Itoa(a.Year))
+	}
+	if a.Priority != 0 {
+		fields = append(fields, fieldPriority+"="+strconv.Itoa(a.Priority))
+	}
+	if a.Skips != 0 {
+		fields = append(fields, fieldSkips+"="+strconv.Itoa(a.Skips))
+	}
+	if !a.SnoozedUntil.IsZero() {
+		fields = append(fields, fieldSnoozed+"="+a.SnoozedUntil.UTC().Format(time.RFC3339))
+	}
+	if a.Pinned {
+		fields = append(fields, fieldPinned+"=true")
+	}
+	if len(a.Tags) > 0 {
+		fields = append(fields, fieldTags+"="+escapeField(strings.Join(a.Tags, ",")))
+	}
+	if a.Notes != "" {
+		fields = append(fields, fieldNotes+"="+escapeField(a.Notes))
+	}
+
+	return strings.Join(fields, "\t")
+}
+
+// ParseRecord decodes a storage line written by FormatRecord. Plain
+// "Artist - Album" lines are accepted as albums without metadata, and
+// unknown metadata keys are ignored.
+func ParseRecord(line string) (Album, error) {
+	return parseRecord(line, ParseAlbum)
+}
+
+// parseStoredRecord is ParseRecord for lines read back from the queue,
+// archive and journal files, which may have been written before ParseAlbum
+// required a spaced separator (see parseLegacyAlbum)
+func parseStoredRecord(line string) (Album, error) {
+	return parseRecord(line, parseLegacyAlbum)
+}
+
+// parseRecord decodes a storage line, parsing its album text with parseAlbum
+func parseRecord(line string, parseAlbum func(string) (Album, error)) (Album, error) {
+	fields := strings.Split(line, "\t")
+
+	album, err := parseAlbum(unescapeField(fields[0]))
+	if err != nil {
+		return Album{}, err
+	}
+
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		if err := album.setField(key, unescapeField(value)); err != nil {
+			return Album{}, err
+		}
+	}
+
+	return album, nil
+}
+
+// setField sets the metadata field key, one of the record field keys, from
+// its stored value. Unknown keys are ignored.
+func (a *Album) setField(key, value string) error {
+	switch key {
+	case fieldAdded:
+		addedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q for %s: %w", key, value, a, err)
+		}
+		a.AddedAt = addedAt
+	case fieldSource:
+		a.Source = value
+	case fieldYear:
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q for %s: %w", key, value, a, err)
+		}
+		a.Year = year
+	case fieldNotes:
+		a.Notes = value
+	case fieldPriority:
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 0 {
+			return fmt.Errorf("invalid %s value %q for %s", key, value, a)
+		}
+		a.Priority = priority
+	case fieldSkips:
+		skips, err := strconv.Atoi(value)
+		if err != nil || skips < 0 {
+			return fmt.Errorf("invalid %s value %q for %s", key, value, a)
+		}
+		a.Skips = skips
+	case fieldSnoozed:
+		snoozedUntil, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q for %s: %w", key, value, a, err)
+		}
+		a.SnoozedUntil = snoozedUntil
+	case fieldPinned:
+		pinned, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s value %q for %s", key, value, a)
+		}
+		a.Pinned = pinned
+	case fieldTags:
+		a.Tags = NormalizeTags(value)
+	}
+
+	return nil
+}
+
+// escapeField escapes characters that would break the line/tab record layout
+func escapeField(value string) string {
+	if !strings.ContainsAny(value, "\\\t\n\r") {
+		return value
+	}
+
+	replacer := strings.NewReplacer("\\", `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	return replacer.Replace(value)
+}
+
+// unescapeField reverses escapeField
+func unescapeField(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case '\\':
+			builder.WriteByte('\\')
+		default:
+			// Not an escape sequence (e.g. a hand-written line); keep it as is
+			builder.WriteByte('\\')
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,222 @@
+package queue
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAlbum(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedArtist string
+		expectedTitle  string
+		expectError    bool
+	}{
+		{"simple", "Artist - Album", "Artist", "Album", false},
+		{"extra whitespace", "  Artist Name  -  Album Title  ", "Artist Name", "Album Title", false},
+		{"spaced separator wins over hyphen in artist", "Jay-Z - The Blueprint", "Jay-Z", "The Blueprint", false},
+		{"hyphen in title", "Blur - Parklife - Remastered", "Blur", "Parklife - Remastered", false},
+		{"hyphenated artist", "A-ha - Hunting High and Low", "A-ha", "Hunting High and Low", false},
+		{"en dash", "Sigur Rós \u2013 Ágætis byrjun", "Sigur Rós", "Ágætis byrjun", false},
+		{"em dash", "Sleater-Kinney \u2014 Dig Me Out", "Sleater-Kinney", "Dig Me Out", false},
+		{"tab around separator", "Artist\t-\tAlbum", "Artist", "Album", false},
+		{"quoted artist", `"Everything - Everything" - Get to Heaven`, "Everything - Everything", "Get to Heaven", false},
+		{"escapes in quoted artist", `"Say \"Hi\" \\ Bye - Band" - Album`, `Say "Hi" \ Bye - Band`, "Album", false},
+		{"quote that is part of the artist", `"Weird Al" Yankovic - Mandatory Fun`, `"Weird Al" Yankovic`, "Mandatory Fun", false},
+		{"quoted title is kept", `David Bowie - "Heroes"`, "David Bowie", `"Heroes"`, false},
+		{"compact separator", "Artist-Album", "", "", true},
+		{"hyphenated name only", "Sleater-Kinney", "", "", true},
+		{"missing separator", "Artist Album", "", "", true},
+		{"empty quoted artist", `"" - Album`, "", "", true},
+		{"unclosed quote", `"Everything - Everything - Get to Heaven`, "", "", true},
+		{"missing artist", "- Album", "", "", true},
+		{"missing title", "Artist -", "", "", true},
+		{"empty", "   ", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			album, err := ParseAlbum(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseAlbum(%q) expected error, got %+v", tt.input, album)
+				} else if !strings.Contains(err.Error(), "invalid album format") {
+					t.Errorf("ParseAlbum(%q) error should mention invalid album format, got: %v", tt.input, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseAlbum(%q) returned error: %v", tt.input, err)
+			}
+
+			if album.Artist != tt.expectedArtist || album.Title != tt.expectedTitle {
+				t.Errorf("ParseAlbum(%q) = %q / %q, want %q / %q", tt.input, album.Artist, album.Title, tt.expectedArtist, tt.expectedTitle)
+			}
+		})
+	}
+}
+
+func TestParseAlbum_ErrorMessages(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "invalid album format: album is empty"},
+		{"Sleater-Kinney", `invalid album format: missing ' - ' between artist and album (a dash only separates them with spaces around it) in "Sleater-Kinney"`},
+		{"Abbey Road", `invalid album format: missing ' - ' between artist and album in "Abbey Road"`},
+		{"- Abbey Road", `invalid album format: missing artist in "- Abbey Road"`},
+		{"The Beatles -", `invalid album format: missing album title in "The Beatles -"`},
+		{`"The Beatles - Abbey Road`, `invalid album format: missing closing quote after the artist in "\"The Beatles - Abbey Road"`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseAlbum(tt.input)
+
+		var formatErr *AlbumFormatError
+		if !errors.As(err, &formatErr) {
+			t.Errorf("ParseAlbum(%q) error = %v, want an *AlbumFormatError", tt.input, err)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("ParseAlbum(%q) error = %q, want %q", tt.input, err.Error(), tt.expected)
+		}
+	}
+}
+
+func TestAlbum_StringQuotesArtist(t *testing.T) {
+	tests := []struct {
+		artist   string
+		expected string
+	}{
+		{"Jay-Z", "Jay-Z - The Album"},
+		{`"Weird Al" Yankovic`, `"Weird Al" Yankovic - The Album`},
+		{"Everything - Everything", `"Everything - Everything" - The Album`},
+		{`"Quoted"`, `"\"Quoted\"" - The Album`},
+		{"Back\\slash \u2013 Band", "\"Back\\\\slash \u2013 Band\" - The Album"},
+	}
+
+	for _, tt := range tests {
+		album := Album{Artist: tt.artist, Title: "The Album"}
+		if album.String() != tt.expected {
+			t.Errorf("String() = %q, want %q", album.String(), tt.expected)
+		}
+
+		parsed, err := ParseRecord(FormatRecord(album))
+		if err != nil {
+			t.Fatalf("ParseRecord returned error for %q: %v", album.String(), err)
+		}
+		if parsed.Artist != tt.artist || parsed.Title != album.Title {
+			t.Errorf("Round trip of %q gave %q / %q", album.String(), parsed.Artist, parsed.Title)
+		}
+	}
+}
+
+func TestAlbum_StringAndKey(t *testing.T) {
+	album := Album{Artist: "The Beatles", Title: "Abbey Road"}
+
+	if album.String() != "The Beatles - Abbey Road" {
+		t.Errorf("Expected 'The Beatles - Abbey Road', got %q", album.String())
+	}
+
+	other := Album{Artist: "THE BEATLES", Title: "abbey road"}
+	if album.Key() != other.Key() {
+		t.Errorf("Expected case-insensitive keys to match, got %q and %q", album.Key(), other.Key())
+	}
+}
+
+func TestFormatRecord_PlainAlbum(t *testing.T) {
+	album := Album{Artist: "Pink Floyd", Title: "The Wall"}
+
+	// Albums without metadata are stored as plain text
+	if record := FormatRecord(album); record != "Pink Floyd - The Wall" {
+		t.Errorf("Expected plain album text, got %q", record)
+	}
+}
+
+func TestFormatRecord_RoundTrip(t *testing.T) {
+	album := Album{
+		Artist:       "Miles Davis",
+		Title:        "Kind of Blue",
+		AddedAt:      time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		Source:       SourceImport,
+		Notes:        "first pressing\tcheck\nthe liner notes \\ sleeve",
+		Year:         1959,
+		Priority:     4,
+		Skips:        2,
+		SnoozedUntil: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		Pinned:       true,
+		Tags:         []string{"jazz", "modal", "vinyl"},
+	}
+
+	record := FormatRecord(album)
+	if strings.Count(record, "\n") != 0 {
+		t.Fatalf("Record must be a single line, got %q", record)
+	}
+
+	parsed, err := ParseRecord(record)
+	if err != nil {
+		t.Fatalf("ParseRecord returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(parsed, album) {
+		t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", parsed, album)
+	}
+}
+
+func TestParseRecord_LegacyLine(t *testing.T) {
+	album, err := ParseRecord("Led Zeppelin - IV")
+	if err != nil {
+		t.Fatalf("ParseRecord returned error: %v", err)
+	}
+
+	if album.Artist != "Led Zeppelin" || album.Title != "IV" || !album.AddedAt.IsZero() || album.Source != "" {
+		t.Errorf("Unexpected album for legacy line: %+v", album)
+	}
+}
+
+func TestParseRecord_UnknownAndInvalidFields(t *testing.T) {
+	album, err := ParseRecord("Led Zeppelin - IV\tfuture=value\tsource=import")
+	if err != nil {
+		t.Fatalf("ParseRecord should ignore unknown fields, got: %v", err)
+	}
+
+	if album.Source != SourceImport {
+		t.Errorf("Expected source %q, got %q", SourceImport, album.Source)
+	}
+
+	if _, err := ParseRecord("Led Zeppelin - IV\tyear=nineteen"); err == nil {
+		t.Error("Expected error for invalid year")
+	}
+
+	if _, err := ParseRecord("Led Zeppelin - IV\tadded=yesterday"); err == nil {
+		t.Error("Expected error for invalid added timestamp")
+	}
+
+	if _, err := ParseRecord("Led Zeppelin - IV\tpriority=-2"); err == nil {
+		t.Error("Expected error for negative priority")
+	}
+
+	if _, err := ParseRecord("Led Zeppelin - IV\tsnoozed=tomorrow"); err == nil {
+		t.Error("Expected error for invalid snooze time")
+	}
+
+	if _, err := ParseRecord("Led Zeppelin - IV\tpinned=maybe"); err == nil {
+		t.Error("Expected error for invalid pinned value")
+	}
+}
+
+func TestParseRecord_KeepsLiteralBackslash(t *testing.T) {
+	album, err := ParseRecord(`AC\DC - Back in Black`)
+	if err != nil {
+		t.Fatalf("ParseRecord returned error: %v", err)
+	}
+
+	if album.Artist != `AC\DC` {
+		t.Errorf("Expected artist %q, got %q", `AC\DC`, album.Artist)
+	}
+}
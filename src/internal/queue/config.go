@@ -0,0 +1,161 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"music-queue/src/internal/storage"
+)
+
+// Config keys accepted by Config.Get and Config.Set
+const (
+	ConfigMode       = "mode"
+	ConfigSimilarity = "similarity"
+)
+
+// ConfigKeys lists the supported config keys
+func ConfigKeys() []string {
+	return []string{ConfigMode, ConfigSimilarity}
+}
+
+// Config holds preferences stored next to the queue in the "config" sibling
+// store, one key=value per line
+type Config struct {
+	Mode       string  // default selection mode for next; empty means ModeRandom
+	Similarity float64 // near-duplicate threshold for add and import (see WithSimilarity); zero disables it
+}
+
+// Get returns the value of a config key, or an error for unknown keys
+func (c Config) Get(key string) (string, error) {
+	switch key {
+	case ConfigMode:
+		return c.Mode, nil
+	case ConfigSimilarity:
+		if c.Similarity == 0 {
+			return "", nil
+		}
+		return strconv.FormatFloat(c.Similarity, 'g', -1, 64), nil
+	default:
+		return "", unknownConfigKey(key)
+	}
+}
+
+// Set validates and stores the value of a config key. An empty value
+// restores the default.
+func (c *Config) Set(key, value string) error {
+	switch key {
+	case ConfigMode:
+		if value != "" && !slices.Contains(Modes(), value) {
+			return fmt.Errorf("invalid %s %q (available: %s)", key, value, strings.Join(Modes(), ", "))
+		}
+		c.Mode = value
+	case ConfigSimilarity:
+		similarity, err := ParseSimilarity(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		c.Similarity = similarity
+	default:
+		return unknownConfigKey(key)
+	}
+	return nil
+}
+
+// ParseSimilarity parses a near-duplicate threshold: a number between 0 and
+// 1, or a percentage such as "90%". An empty value is zero.
+func ParseSimilarity(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	number, percent := strings.CutSuffix(value, "%")
+	similarity, err := strconv.ParseFloat(number, 64)
+	if percent {
+		similarity /= 100
+	}
+	if err != nil || !(similarity >= 0 && similarity <= 1) {
+		return 0, fmt.Errorf("must be between 0 and 1, e.g. 0.9 or 90%%")
+	}
+	return similarity, nil
+}
+
+// unknownConfigKey returns the error for a key that is not in ConfigKeys
+func unknownConfigKey(key string) error {
+	return fmt.Errorf("unknown config key %q (available: %s)", key, strings.Join(ConfigKeys(), ", "))
+}
+
+// LoadConfig reads the stored preferences. A missing config store yields the
+// zero Config, and unknown keys are ignored so that older versions can read
+// newer config files.
+func (qs *QueueService) LoadConfig() (Config, error) {
+	lines, err := qs.configStore().ReadLines()
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var config Config
+	for i, line := range lines {
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return Config{}, fmt.Errorf("failed to read config: line %d: expected key=value", i+1)
+		}
+
+		key = strings.TrimSpace(key)
+		if !slices.Contains(ConfigKeys(), key) {
+			continue
+		}
+
+		if err := config.Set(key, unescapeField(strings.TrimSpace(value))); err != nil {
+			return Config{}, fmt.Errorf("failed to read config: line %d: %w", i+1, err)
+		}
+	}
+
+	return config, nil
+}
+
+// SetConfig changes one stored preference and returns the updated config.
+// Lines for other keys, including ones this version does not know, are kept.
+func (qs *QueueService) SetConfig(key, value string) (Config, error) {
+	var config Config
+	err := qs.withLock(func() error {
+		var err error
+		config, err = qs.LoadConfig()
+		if err != nil {
+			return err
+		}
+
+		if err := config.Set(key, value); err != nil {
+			return err
+		}
+
+		lines, err := qs.configStore().ReadLines()
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+
+		lines = slices.DeleteFunc(lines, func(line string) bool {
+			lineKey, _, _ := strings.Cut(line, "=")
+			return strings.TrimSpace(lineKey) == key
+		})
+		if value != "" {
+			lines = append(lines, key+"="+escapeField(value))
+		}
+
+		if err := qs.configStore().WriteLines(lines); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// configStore returns the store holding the preferences
+func (qs *QueueService) configStore() storage.Store {
+	return qs.storage.Sibling("config")
+}
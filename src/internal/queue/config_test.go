@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_Config(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	config, err := queue.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig on empty store returned error: %v", err)
+	}
+	if config.Mode != "" {
+		t.Errorf("Expected default mode, got %q", config.Mode)
+	}
+
+	if _, err := queue.SetConfig(ConfigMode, ModeShuffle); err != nil {
+		t.Fatalf("SetConfig returned error: %v", err)
+	}
+
+	config, err = queue.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeShuffle {
+		t.Errorf("Expected mode %q, got %q", ModeShuffle, config.Mode)
+	}
+
+	// An empty value restores the default
+	config, err = queue.SetConfig(ConfigMode, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != "" {
+		t.Errorf("Expected mode to be reset, got %q", config.Mode)
+	}
+}
+
+func TestQueueService_SetConfig_Errors(t *testing.T) {
+	queue := NewQueue(storage.NewMemoryStorage("queue"))
+
+	if _, err := queue.SetConfig(ConfigMode, "alphabetical"); err == nil || !strings.Contains(err.Error(), "invalid mode") {
+		t.Errorf("Expected invalid mode error, got %v", err)
+	}
+
+	if _, err := queue.SetConfig("colour", "blue"); err == nil || !strings.Contains(err.Error(), "unknown config key") {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+}
+
+func TestQueueService_SetConfig_KeepsUnknownKeys(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	configStore := memoryStorage.Sibling("config")
+	configStore.WriteLines([]string{"future=value", "mode=fifo"})
+
+	queue := NewQueue(memoryStorage)
+	if _, err := queue.SetConfig(ConfigMode, ModeLIFO); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := configStore.ReadLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{"future=value", "mode=lifo"}) {
+		t.Errorf("Unexpected config lines: %v", lines)
+	}
+}
+
+func TestConfig_SetSimilarity(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      float64
+		expectedError bool
+	}{
+		{"0.9", 0.9, false},
+		{"85%", 0.85, false},
+		{"", 0, false},
+		{"1.5", 0, true},
+		{"high", 0, true},
+		{"NaN", 0, true},
+	}
+
+	for _, tt := range tests {
+		var config Config
+		err := config.Set(ConfigSimilarity, tt.value)
+		if tt.expectedError {
+			if err == nil || !strings.Contains(err.Error(), "invalid similarity") {
+				t.Errorf("Expected error for %q, got %v", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.value, err)
+			continue
+		}
+		if config.Similarity != tt.expected {
+			t.Errorf("Expected similarity %g for %q, got %g", tt.expected, tt.value, config.Similarity)
+		}
+	}
+}
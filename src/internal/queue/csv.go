@@ -0,0 +1,340 @@
+package queue
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header modes of CSVOptions
+const (
+	CSVHeaderAuto    = "auto" // the first row is a header if it names the artist and album columns
+	CSVHeaderPresent = "yes"
+	CSVHeaderAbsent  = "no"
+)
+
+// CSV columns holding the artist and the album title
+const (
+	columnArtist = "artist"
+	columnAlbum  = "album"
+)
+
+// artistColumns and albumColumns are header names recognized as the artist
+// and album title columns, in order of preference
+var (
+	artistColumns = []string{columnArtist, "album artist", "albumartist", "artist name", "band"}
+	albumColumns  = []string{columnAlbum, "album title", "album name", "title", "release"}
+)
+
+// albumCSVColumns are the columns written by WriteAlbumsCSV. Besides the
+// artist and album columns, ImportCSV reads the metadata columns among them
+// by header name.
+var albumCSVColumns = []string{
+	columnArtist, columnAlbum, fieldYear, fieldPriority, fieldTags, fieldNotes,
+	fieldAdded, fieldSource, fieldSkips, fieldSnoozed, fieldPinned,
+}
+
+// historyCSVColumns are the columns written by WriteHistoryCSV
+var historyCSVColumns = append(slices.Clone(albumCSVColumns), fieldPicked, fieldMethod, fieldSeed, fieldEvent, fieldFrom)
+
+// CSVOptions configures how ImportCSV reads a CSV file
+type CSVOptions struct {
+	Delimiter rune   // field delimiter; zero means a comma
+	Header    string // CSVHeaderAuto, CSVHeaderPresent or CSVHeaderAbsent; empty means auto
+
+	// ArtistColumn and AlbumColumn select a column by header name or by
+	// 1-based number. When empty, a column with a known name is used, such as
+	// "artist" or "album artist", or without a header the first and second.
+	ArtistColumn string
+	AlbumColumn  string
+}
+
+// ImportCSV imports albums from a CSV file, one per row, with the same
+// duplicate handling as ImportAlbums. With a header, the metadata columns
+// written by WriteAlbumsCSV (year, priority, tags, notes, added, source,
+// skips, snoozed and pinned) are read too. Rejected rows are reported with
+// their line number and as CSV text; the report's Header holds the header
+// row.
+func (qs *QueueService) ImportCSV(filename string, options CSVOptions) (ImportReport, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return ImportReport{}, fmt.Errorf("file not found: %s", filename)
+	}
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to read import file: %w", err)
+	}
+	defer file.Close()
+
+	entries, header, err := readCSVEntries(file, options)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report, err := qs.ImportItems(entries)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report.Header = header
+	return report, nil
+}
+
+// readCSVEntries reads the albums of a CSV source and returns them with the
+// header row as CSV text, or "" when there is none
+func readCSVEntries(r io.Reader, options CSVOptions) ([]ImportItem, string, error) {
+	delimiter := options.Delimiter
+	if delimiter == 0 {
+		delimiter = ','
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+
+	first, err := reader.Read()
+	if err == io.EOF {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read CSV: %w", err)
+	}
+	// Spreadsheet applications may start the file with a byte order mark
+	first[0] = strings.TrimPrefix(first[0], "\ufeff")
+
+	var header []string
+	switch options.Header {
+	case CSVHeaderPresent:
+		header = first
+	case CSVHeaderAbsent:
+	case CSVHeaderAuto, "":
+		if isCSVHeader(first, options) {
+			header = first
+		}
+	default:
+		return nil, "", fmt.Errorf("invalid CSV header mode %q: must be %s, %s or %s", options.Header, CSVHeaderAuto, CSVHeaderPresent, CSVHeaderAbsent)
+	}
+
+	artist, err := csvColumn(columnArtist, options.ArtistColumn, header, artistColumns, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	album, err := csvColumn(columnAlbum, options.AlbumColumn, header, albumColumns, 1)
+	if err != nil {
+		return nil, "", err
+	}
+	if artist == album {
+		return nil, "", fmt.Errorf("the artist and album columns must differ")
+	}
+
+	// Metadata columns by field key, only known from a header
+	metadata := map[string]int{}
+	for i, name := range header {
+		name = normalizeColumn(name)
+		if i != artist && i != album && slices.Contains(albumCSVColumns[2:], name) {
+			metadata[name] = i
+		}
+	}
+
+	var entries []ImportItem
+	row, headerText := first, ""
+	if header != nil {
+		headerText = formatCSVRow(header, delimiter)
+		row, err = reader.Read()
+	}
+	for ; err != io.EOF; row, err = reader.Read() {
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		text := formatCSVRow(row, delimiter)
+		entry := ImportItem{Line: line, Text: text}
+		entry.Album, entry.Err = parseCSVRow(row, text, artist, album, metadata)
+		entries = append(entries, entry)
+	}
+
+	return entries, headerText, nil
+}
+
+// isCSVHeader reports whether row names both an artist and an album column,
+// by a known name or as selected in options, so that a first album whose
+// artist or title happens to be a column name is not taken for a header
+func isCSVHeader(row []string, options CSVOptions) bool {
+	names := func(spec string, known []string) []string {
+		if spec == "" {
+			return known
+		}
+		return append(slices.Clone(known), normalizeColumn(spec))
+	}
+	artistNames, albumNames := names(options.ArtistColumn, artistColumns), names(options.AlbumColumn, albumColumns)
+
+	hasArtist, hasAlbum := false, false
+	for _, cell := range row {
+		name := normalizeColumn(cell)
+		hasArtist = hasArtist || slices.Contains(artistNames, name)
+		hasAlbum = hasAlbum || slices.Contains(albumNames, name)
+	}
+	return hasArtist && hasAlbum
+}
+
+// csvColumn returns the index of the column selected by spec, a header name
+// or 1-based number. Without spec, the first header column named one of
+// names is used, or without a header the column at fallback.
+func csvColumn(column, spec string, header []string, names []string, fallback int) (int, error) {
+	if number, err := strconv.Atoi(spec); err == nil {
+		if number < 1 {
+			return 0, fmt.Errorf("invalid %s column %d: columns are numbered from 1", column, number)
+		}
+		return number - 1, nil
+	}
+
+	if spec != "" {
+		if header == nil {
+			return 0, fmt.Errorf("the %s column %q is selected by name, but the CSV has no header", column, spec)
+		}
+		names = []string{normalizeColumn(spec)}
+	} else if header == nil {
+		return fallback, nil
+	}
+
+	for _, name := range names {
+		for i, cell := range header {
+			if normalizeColumn(cell) == name {
+				return i, nil
+			}
+		}
+	}
+
+	if spec != "" {
+		return 0, fmt.Errorf("no %s column %q in the CSV header", column, spec)
+	}
+	return 0, fmt.Errorf("no %s column in the CSV header (looked for %s)", column, strings.Join(names, ", "))
+}
+
+// normalizeColumn folds a header name for comparison
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// parseCSVRow reads an album from a CSV row. text is the row as CSV, used in
+// errors.
+func parseCSVRow(row []string, text string, artistColumn, albumColumn int, metadata map[string]int) (Album, error) {
+	cell := func(i int) string {
+		if i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	album := Album{Artist: cell(artistColumn), Title: cell(albumColumn)}
+	if album.Artist == "" {
+		return Album{}, &AlbumFormatError{Text: text, Problem: "missing artist"}
+	}
+	if album.Title == "" {
+		return Album{}, &AlbumFormatError{Text: text, Problem: "missing album title"}
+	}
+
+	for _, key := range albumCSVColumns[2:] {
+		index, found := metadata[key]
+		if !found || cell(index) == "" {
+			continue
+		}
+		if err := album.setField(key, cell(index)); err != nil {
+			return Album{}, err
+		}
+	}
+
+	return album, nil
+}
+
+// formatCSVRow encodes row as a line of CSV, without the line break
+func formatCSVRow(row []string, delimiter rune) string {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Comma = delimiter
+	writer.Write(row)
+	writer.Flush()
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// WriteAlbumsCSV writes albums to w as CSV with a header row, one album per
+// row with all its metadata. Unset fields are left empty. delimiter zero
+// means a comma.
+func WriteAlbumsCSV(w io.Writer, albums []Album, delimiter rune) error {
+	return writeCSV(w, delimiter, albumCSVColumns, len(albums), func(i int) []string {
+		return albumCSVRow(albums[i])
+	})
+}
+
+// WriteHistoryCSV writes history entries to w as CSV like WriteAlbumsCSV,
+// followed by the pick columns: picked, method, seed, event and from
+func WriteHistoryCSV(w io.Writer, entries []HistoryEntry, delimiter rune) error {
+	return writeCSV(w, delimiter, historyCSVColumns, len(entries), func(i int) []string {
+		entry := entries[i]
+		seed := ""
+		if entry.Seed != nil {
+			seed = strconv.FormatInt(*entry.Seed, 10)
+		}
+		return append(albumCSVRow(entry.Album), formatCSVTime(entry.PickedAt), entry.Method, seed, entry.Event, entry.From)
+	})
+}
+
+// writeCSV writes the header and count rows produced by row
+func writeCSV(w io.Writer, delimiter rune, header []string, count int, row func(i int) []string) error {
+	writer := csv.NewWriter(w)
+	if delimiter != 0 {
+		writer.Comma = delimiter
+	}
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for i := 0; i < count; i++ {
+		if err := writer.Write(row(i)); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// albumCSVRow returns the cells of album in the order of albumCSVColumns
+func albumCSVRow(album Album) []string {
+	number := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	pinned := ""
+	if album.Pinned {
+		pinned = "true"
+	}
+
+	return []string{
+		album.Artist, album.Title, number(album.Year), number(album.Priority),
+		strings.Join(album.Tags, ","), album.Notes, formatCSVTime(album.AddedAt),
+		album.Source, number(album.Skips), formatCSVTime(album.SnoozedUntil), pinned,
+	}
+}
+
+// formatCSVTime formats t as in queue records, or "" when it is zero
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
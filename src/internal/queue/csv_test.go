@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_ImportCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		options  CSVOptions
+		expected []string
+	}{
+		{
+			name:     "detected header",
+			content:  "\ufeffAlbum,Album Artist,Rating\nAbbey Road,The Beatles,5\n\"Get to Heaven\",\"Everything - Everything\",4\n",
+			expected: []string{"The Beatles - Abbey Road", `"Everything - Everything" - Get to Heaven`},
+		},
+		{
+			name:     "no header",
+			content:  "The Beatles,Abbey Road\nJay-Z,\"The Blueprint\"\n",
+			expected: []string{"The Beatles - Abbey Road", "Jay-Z - The Blueprint"},
+		},
+		{
+			name:     "first album named like a column",
+			content:  "Meghan Trainor,Title\nThe Band,Music from Big Pink\n",
+			expected: []string{"Meghan Trainor - Title", "The Band - Music from Big Pink"},
+		},
+		{
+			name:     "columns by number and delimiter",
+			content:  "1969;Abbey Road;The Beatles\n1997;\"OK; Computer\";Radiohead\n",
+			options:  CSVOptions{Delimiter: ';', ArtistColumn: "3", AlbumColumn: "2"},
+			expected: []string{"The Beatles - Abbey Road", "Radiohead - OK; Computer"},
+		},
+		{
+			name:     "columns by name",
+			content:  "Band\tRecord\nThe Beatles\tAbbey Road\n",
+			options:  CSVOptions{Delimiter: '\t', ArtistColumn: "band", AlbumColumn: "Record"},
+			expected: []string{"The Beatles - Abbey Road"},
+		},
+		{
+			name:     "header forced",
+			content:  "Who,What\nThe Beatles,Abbey Road\n",
+			options:  CSVOptions{Header: CSVHeaderPresent, ArtistColumn: "1", AlbumColumn: "2"},
+			expected: []string{"The Beatles - Abbey Road"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importFile := filepath.Join(t.TempDir(), "albums.csv")
+			if err := os.WriteFile(importFile, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			memoryStorage := storage.NewMemoryStorage("queue")
+			report, err := NewQueue(memoryStorage).ImportCSV(importFile, tt.options)
+			if err != nil {
+				t.Fatalf("ImportCSV returned error: %v", err)
+			}
+			if report.Added != len(tt.expected) || len(report.Rejected) != 0 {
+				t.Errorf("Unexpected report %+v", report)
+			}
+
+			lines, err := readAlbumTexts(memoryStorage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lines, tt.expected) {
+				t.Errorf("Expected queue %v, got %v", tt.expected, lines)
+			}
+		})
+	}
+}
+
+func TestQueueService_ImportCSV_Report(t *testing.T) {
+	importFile := filepath.Join(t.TempDir(), "albums.csv")
+	content := "artist,album,year,tags\n" +
+		"The Beatles,Abbey Road,1969,\"rock, classic\"\n" +
+		"\"Pink\nFloyd\",The Wall,,\n" +
+		",Untitled,,\n" +
+		"Radiohead,OK Computer,ninety-seven,\n" +
+		"the beatles,abbey road,,\n"
+	if err := os.WriteFile(importFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	memoryStorage := storage.NewMemoryStorage("queue")
+	report, err := NewQueue(memoryStorage).ImportCSV(importFile, CSVOptions{})
+	if err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+
+	if report.Added != 2 || report.Duplicates != 1 || report.FormatErrors != 2 || report.Header != "artist,album,year,tags" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	// Line numbers count the lines of a quoted field spanning two lines
+	expected := []RejectedLine{
+		{Line: 5, Text: ",Untitled,,", Reason: RejectFormat, Message: "missing artist"},
+		{Line: 6, Text: "Radiohead,OK Computer,ninety-seven,", Reason: RejectFormat, Message: `invalid year value "ninety-seven" for Radiohead - OK Computer: strconv.Atoi: parsing "ninety-seven": invalid syntax`},
+		{Line: 7, Text: "the beatles,abbey road,,", Reason: RejectDuplicate, Message: "duplicate of 'The Beatles - Abbey Road' on line 2"},
+	}
+	if !reflect.DeepEqual(report.Rejected, expected) {
+		t.Errorf("Unexpected rejected lines:\n got  %+v\n want %+v", report.Rejected, expected)
+	}
+
+	albums, err := NewQueue(memoryStorage).ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if albums[0].Year != 1969 || !reflect.DeepEqual(albums[0].Tags, []string{"rock", "classic"}) || albums[1].Artist != "Pink\nFloyd" {
+		t.Errorf("Unexpected albums %+v", albums)
+	}
+}
+
+func TestQueueService_ImportCSV_ColumnErrors(t *testing.T) {
+	importFile := filepath.Join(t.TempDir(), "albums.csv")
+	if err := os.WriteFile(importFile, []byte("Artist,Album\nThe Beatles,Abbey Road\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		options  CSVOptions
+		expected string
+	}{
+		{CSVOptions{ArtistColumn: "Band"}, `no artist column "Band" in the CSV header`},
+		{CSVOptions{Header: CSVHeaderAbsent, AlbumColumn: "Album"}, "selected by name, but the CSV has no header"},
+		{CSVOptions{ArtistColumn: "0"}, "columns are numbered from 1"},
+		{CSVOptions{ArtistColumn: "2"}, "the artist and album columns must differ"},
+		{CSVOptions{Header: "maybe"}, `invalid CSV header mode "maybe"`},
+	}
+
+	for _, tt := range tests {
+		_, err := NewQueue(storage.NewMemoryStorage("queue")).ImportCSV(importFile, tt.options)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("ImportCSV with %+v: expected error containing %q, got %v", tt.options, tt.expected, err)
+		}
+	}
+}
+
+func TestWriteAlbumsCSV_RoundTrip(t *testing.T) {
+	albums := []Album{
+		{
+			Artist:       "Everything - Everything",
+			Title:        "Get to Heaven",
+			AddedAt:      time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+			Source:       SourceAdd,
+			Notes:        "from \"the\" shop, second floor",
+			Year:         2015,
+			Priority:     3,
+			Skips:        1,
+			SnoozedUntil: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			Pinned:       true,
+			Tags:         []string{"indie", "uk"},
+		},
+		{Artist: "Miles Davis", Title: "Kind of Blue"},
+	}
+
+	var builder strings.Builder
+	if err := WriteAlbumsCSV(&builder, albums, ';'); err != nil {
+		t.Fatalf("WriteAlbumsCSV returned error: %v", err)
+	}
+	if !strings.HasPrefix(builder.String(), "artist;album;year;priority;tags;notes;added;source;skips;snoozed;pinned\n") {
+		t.Errorf("Unexpected CSV header in %q", builder.String())
+	}
+
+	importFile := filepath.Join(t.TempDir(), "albums.csv")
+	if err := os.WriteFile(importFile, []byte(builder.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	memoryStorage := storage.NewMemoryStorage("queue")
+	if _, err := NewQueue(memoryStorage).ImportCSV(importFile, CSVOptions{Delimiter: ';'}); err != nil {
+		t.Fatalf("ImportCSV returned error: %v", err)
+	}
+
+	imported, err := NewQueue(memoryStorage).ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported[0], albums[0]) {
+		t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", imported[0], albums[0])
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	entries := []HistoryEntry{
+		{Album: Album{Artist: "Miles Davis", Title: "Kind of Blue"}, PickedAt: time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC), Method: MethodRandom, Seed: seedOf(42)},
+		{Album: Album{Artist: "Miles Davis", Title: "Kind of Blue"}, PickedAt: time.Date(2024, 3, 2, 20, 0, 0, 0, time.UTC), Event: EventSkipped},
+		{Album: Album{Artist: "Blur", Title: "Parklife"}, PickedAt: time.Date(2024, 3, 3, 20, 0, 0, 0, time.UTC), Method: MethodRandom, Seed: seedOf(0)},
+		{Album: Album{Artist: "Blur", Title: "13"}, PickedAt: time.Date(2024, 3, 4, 20, 0, 0, 0, time.UTC), Method: MethodPinned},
+	}
+
+	var builder strings.Builder
+	if err := WriteHistoryCSV(&builder, entries, 0); err != nil {
+		t.Fatalf("WriteHistoryCSV returned error: %v", err)
+	}
+
+	expected := "artist,album,year,priority,tags,notes,added,source,skips,snoozed,pinned,picked,method,seed,event,from\n" +
+		"Miles Davis,Kind of Blue,,,,,,,,,,2024-03-01T20:00:00Z,random,42,,\n" +
+		"Miles Davis,Kind of Blue,,,,,,,,,,2024-03-02T20:00:00Z,,,skipped,\n" +
+		"Blur,Parklife,,,,,,,,,,2024-03-03T20:00:00Z,random,0,,\n" +
+		"Blur,13,,,,,,,,,,2024-03-04T20:00:00Z,pinned,,,\n"
+	if builder.String() != expected {
+		t.Errorf("Unexpected history CSV:\n got  %q\n want %q", builder.String(), expected)
+	}
+}
@@ -0,0 +1,249 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DuplicateGroup is a set of albums in the queue that are duplicates or near
+// duplicates of each other
+type DuplicateGroup struct {
+	Albums []Album // in queue order
+
+	// Similarity is 1 when all albums share a match key, otherwise the lowest
+	// similarity that linked two of them
+	Similarity float64
+}
+
+// ListenedAlbum is an album in the queue that has already been played
+type ListenedAlbum struct {
+	Album  Album        // the album in the queue
+	Played HistoryEntry // its most recent play
+}
+
+// Duplicates groups the albums in the queue that share a match key (see
+// Album.MatchKey) and, with a similarity threshold (see WithSimilarity), those
+// that resemble each other. Groups are ordered by their first album's
+// position in the queue.
+func (qs *QueueService) Duplicates() ([]DuplicateGroup, error) {
+	albums, err := qs.ListAlbums()
+	if err != nil {
+		return nil, err
+	}
+
+	return groupDuplicates(albums, qs.similarity), nil
+}
+
+// groupDuplicates groups albums by match key and then joins groups whose keys
+// are at least threshold similar, transitively. A zero threshold only groups
+// albums with equal keys.
+func groupDuplicates(albums []Album, threshold float64) []DuplicateGroup {
+	// Indices of the albums with each key, keys in order of first appearance
+	var keys []string
+	members := map[string][]int{}
+	for i, album := range albums {
+		key := album.MatchKey()
+		if _, seen := members[key]; !seen {
+			keys = append(keys, key)
+		}
+		members[key] = append(members[key], i)
+	}
+
+	// Join similar keys into sets identified by their first key
+	parent := make([]int, len(keys))
+	similarity := make([]float64, len(keys))
+	for i := range keys {
+		parent[i] = i
+		similarity[i] = 1
+	}
+	root := func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+
+	if threshold > 0 {
+		for i := 1; i < len(keys); i++ {
+			for j := 0; j < i; j++ {
+				a, b := root(i), root(j)
+				if a == b {
+					continue
+				}
+				score := keySimilarity([]rune(keys[i]), []rune(keys[j]))
+				if score < threshold {
+					continue
+				}
+				top := min(a, b)
+				parent[max(a, b)] = top
+				similarity[top] = min(similarity[a], similarity[b], score)
+			}
+		}
+	}
+
+	sets := map[int][]int{}
+	for i, key := range keys {
+		sets[root(i)] = append(sets[root(i)], members[key]...)
+	}
+
+	var groups []DuplicateGroup
+	for i := range keys {
+		indices := sets[i]
+		if root(i) != i || len(indices) < 2 {
+			continue
+		}
+
+		slices.Sort(indices)
+		group := DuplicateGroup{Similarity: similarity[i]}
+		for _, index := range indices {
+			group.Albums = append(group.Albums, albums[index])
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// Listened returns the albums in the queue that have already been picked and
+// not skipped, matched by match key (see Album.MatchKey), in queue order
+func (qs *QueueService) Listened() ([]ListenedAlbum, error) {
+	albums, err := qs.ListAlbums()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := qs.History(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	played := map[string]HistoryEntry{}
+	for _, entry := range plays(history) {
+		played[entry.Album.MatchKey()] = entry
+	}
+
+	var listened []ListenedAlbum
+	for _, album := range albums {
+		if entry, found := played[album.MatchKey()]; found {
+			listened = append(listened, ListenedAlbum{Album: album, Played: entry})
+		}
+	}
+	return listened, nil
+}
+
+// MergeAlbums combines duplicates into one album. The album added earliest
+// (an album without an added time counts as oldest) is kept with its name and
+// metadata; fields it lacks are filled in from the others in order of age.
+// Tags are combined, the highest priority is kept and the album stays pinned
+// if any of them was.
+func MergeAlbums(albums []Album) Album {
+	byAge := slices.Clone(albums)
+	slices.SortStableFunc(byAge, func(a, b Album) int { return a.AddedAt.Compare(b.AddedAt) })
+
+	merged := byAge[0]
+	merged.Tags = slices.Clone(merged.Tags)
+	for _, album := range byAge[1:] {
+		if merged.Source == "" {
+			merged.Source = album.Source
+		}
+		if merged.Notes == "" {
+			merged.Notes = album.Notes
+		}
+		if merged.Year == 0 {
+			merged.Year = album.Year
+		}
+		merged.Priority = max(merged.Priority, album.Priority)
+		merged.Pinned = merged.Pinned || album.Pinned
+		merged.Tags = NormalizeTags(append(merged.Tags, album.Tags...)...)
+	}
+
+	return merged
+}
+
+// Merge replaces each group of duplicates with the album MergeAlbums makes of
+// it, at the position of the group's first album, and records the other
+// albums in the history as merged. All groups are merged in one operation,
+// undone together. Nothing is merged if any album is no longer in the queue.
+// The merged albums are returned in the order of groups.
+func (qs *QueueService) Merge(groups ...[]Album) ([]Album, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no duplicates given")
+	}
+
+	var merged []Album
+	err := qs.mutate(OpDedupe, func(existingAlbums []Album) (mutation, error) {
+		albums := existingAlbums
+		now := qs.now()
+		var history []HistoryEntry
+		merged = nil
+		count := 0
+
+		for _, group := range groups {
+			if len(group) < 2 {
+				return mutation{}, fmt.Errorf("a group of duplicates needs at least two albums")
+			}
+
+			// Merge the stored albums, which may have changed since the
+			// duplicates were found
+			var indices []int
+			current := make([]Album, len(group))
+			for i, album := range group {
+				index := -1
+				for j, candidate := range albums {
+					if candidate.Key() == album.Key() && !slices.Contains(indices, j) {
+						index = j
+						break
+					}
+				}
+				if index == -1 {
+					return mutation{}, fmt.Errorf("album '%s' is no longer in the queue", album)
+				}
+				indices = append(indices, index)
+				current[i] = albums[index]
+			}
+
+			kept := MergeAlbums(current)
+			first := slices.Min(indices)
+
+			var updated []Album
+			for i, album := range albums {
+				switch {
+				case i == first:
+					updated = append(updated, kept)
+				case slices.Contains(indices, i):
+				default:
+					updated = append(updated, album)
+				}
+			}
+			keptFound := false
+			for _, album := range current {
+				if !keptFound && album.Key() == kept.Key() {
+					keptFound = true
+					continue
+				}
+				history = append(history, HistoryEntry{Album: album, PickedAt: now, Event: EventMerged})
+			}
+
+			albums = updated
+			merged = append(merged, kept)
+			count += len(current)
+		}
+
+		description := fmt.Sprintf("%d albums into %s", count, merged[0])
+		if len(merged) > 1 {
+			description = fmt.Sprintf("%d albums into %d", count, len(merged))
+		}
+
+		return mutation{
+			albums:      albums,
+			history:     history,
+			description: description,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_Duplicates(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{
+		"The Beatles - Abbey Road",
+		"Miles Davis - Kind of Blue",
+		"Beatles - Abbey Road (Remastered)",
+		"Miles Davis - Kind of Blu",
+		"Pink Floyd - The Wall",
+		"the beatles - abbey road",
+	})
+
+	groups, err := NewQueue(memoryStorage).Duplicates()
+	if err != nil {
+		t.Fatalf("Duplicates returned error: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Albums) != 3 || groups[0].Similarity != 1 {
+		t.Fatalf("Expected one group of three exact duplicates, got %+v", groups)
+	}
+
+	groups, err = NewQueue(memoryStorage, WithSimilarity(0.9)).Duplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected two groups with a similarity threshold, got %+v", groups)
+	}
+	if groups[1].Albums[0].Title != "Kind of Blue" || groups[1].Albums[1].Title != "Kind of Blu" || groups[1].Similarity >= 1 {
+		t.Errorf("Unexpected near-duplicate group %+v", groups[1])
+	}
+}
+
+func TestMergeAlbums(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	merged := MergeAlbums([]Album{
+		{Artist: "Beatles", Title: "Abbey Road", AddedAt: day(5), Year: 1969, Priority: 3, Tags: []string{"rock"}},
+		{Artist: "The Beatles", Title: "Abbey Road", AddedAt: day(1), Source: SourceImport, Tags: []string{"vinyl"}},
+		{Artist: "The Beatles", Title: "Abbey Road (Remastered)", AddedAt: day(3), Notes: "remaster", Pinned: true},
+	})
+
+	expected := Album{
+		Artist: "The Beatles", Title: "Abbey Road", AddedAt: day(1), Source: SourceImport,
+		Notes: "remaster", Year: 1969, Priority: 3, Pinned: true, Tags: []string{"vinyl", "rock"},
+	}
+	if merged.String() != expected.String() || merged.AddedAt != expected.AddedAt || merged.Source != expected.Source ||
+		merged.Notes != expected.Notes || merged.Year != expected.Year || merged.Priority != expected.Priority ||
+		merged.Pinned != expected.Pinned || !slices.Equal(merged.Tags, expected.Tags) {
+		t.Errorf("Expected %+v, got %+v", expected, merged)
+	}
+}
+
+func TestQueueService_Merge(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{
+		"Pink Floyd - The Wall",
+		"Beatles - Abbey Road\tadded=2024-03-05T00:00:00Z",
+		"Miles Davis - Kind of Blue",
+		"The Beatles - Abbey Road\tadded=2024-03-01T00:00:00Z\ttags=rock",
+	})
+	queue := NewQueue(memoryStorage)
+
+	groups, err := queue.Duplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := queue.Merge(groups[0].Albums)
+	if err != nil {
+		t.Fatalf("Merge returned error: %v", err)
+	}
+	if len(merged) != 1 || merged[0].Artist != "The Beatles" {
+		t.Errorf("Expected the oldest album to be kept, got %v", merged)
+	}
+
+	albums, err := readAlbumTexts(memoryStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(albums, ", "); got != "Pink Floyd - The Wall, The Beatles - Abbey Road, Miles Davis - Kind of Blue" {
+		t.Errorf("Expected the merged album at the first duplicate's position, got %s", got)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Event != EventMerged || history[0].Album.Artist != "Beatles" {
+		t.Errorf("Expected the dropped album recorded as merged, got %+v", history)
+	}
+
+	// The merge is undone as one operation
+	if _, err := queue.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := queue.CountAlbums(); count != 4 {
+		t.Errorf("Expected undo to restore 4 albums, got %d", count)
+	}
+
+	if _, err := queue.Merge([]Album{{Artist: "Nobody", Title: "Nothing"}, groups[0].Albums[0]}); err == nil || !strings.Contains(err.Error(), "no longer in the queue") {
+		t.Errorf("Expected error for an album not in the queue, got %v", err)
+	}
+}
+
+func TestQueueService_Listened(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"The Beatles - Abbey Road", "Pink Floyd - The Wall", "Miles Davis - Kind of Blue"})
+	memoryStorage.Sibling("archive").WriteLines([]string{
+		"Beatles - Abbey Road (Remastered)\tpicked=2024-03-01T00:00:00Z",
+		"Pink Floyd - The Wall\tpicked=2024-03-02T00:00:00Z",
+		"Pink Floyd - The Wall\tpicked=2024-03-03T00:00:00Z\tevent=skipped",
+	})
+
+	listened, err := NewQueue(memoryStorage).Listened()
+	if err != nil {
+		t.Fatalf("Listened returned error: %v", err)
+	}
+	if len(listened) != 1 || listened[0].Album.Title != "Abbey Road" || listened[0].Played.Album.Artist != "Beatles" {
+		t.Errorf("Expected only Abbey Road to be listened to, got %+v", listened)
+	}
+}
@@ -0,0 +1,292 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DocumentFormat identifies a JSON document written by WriteDocument
+const DocumentFormat = "music-queue"
+
+// DocumentVersion is the version of the JSON document schema written by
+// WriteDocument. It changes whenever a field changes meaning or is removed;
+// fields added later are ignored by older readers.
+const DocumentVersion = 1
+
+// Document is the JSON interchange format for the full state of a queue:
+// the albums in the queue in order, and the listening history oldest first
+type Document struct {
+	Format     string                 `json:"format"`  // always DocumentFormat
+	Version    int                    `json:"version"` // DocumentVersion
+	ExportedAt time.Time              `json:"exported_at"`
+	Queue      []DocumentAlbum        `json:"queue"`
+	History    []DocumentHistoryEntry `json:"history"`
+}
+
+// DocumentAlbum is an album in a Document. Unset details are omitted.
+type DocumentAlbum struct {
+	Artist       string     `json:"artist"`
+	Album        string     `json:"album"`
+	Year         int        `json:"year,omitempty"`
+	Priority     int        `json:"priority,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	AddedAt      *time.Time `json:"added,omitempty"`
+	Source       string     `json:"source,omitempty"`
+	Skips        int        `json:"skips,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed,omitempty"`
+	Pinned       bool       `json:"pinned,omitempty"`
+}
+
+// DocumentHistoryEntry is a history entry in a Document: the album with the
+// details of its pick or event
+type DocumentHistoryEntry struct {
+	DocumentAlbum
+	PickedAt *time.Time `json:"picked,omitempty"`
+	Method   string     `json:"method,omitempty"`
+	Seed     *int64     `json:"seed,omitempty"`
+	Event    string     `json:"event,omitempty"`
+	From     string     `json:"from,omitempty"`
+}
+
+// NewDocument builds the Document of a queue and its history
+func NewDocument(albums []Album, history []HistoryEntry, exportedAt time.Time) Document {
+	document := Document{
+		Format:     DocumentFormat,
+		Version:    DocumentVersion,
+		ExportedAt: exportedAt.UTC(),
+		Queue:      make([]DocumentAlbum, len(albums)),
+		History:    make([]DocumentHistoryEntry, len(history)),
+	}
+
+	for i, album := range albums {
+		document.Queue[i] = newDocumentAlbum(album)
+	}
+	for i, entry := range history {
+		document.History[i] = DocumentHistoryEntry{
+			DocumentAlbum: newDocumentAlbum(entry.Album),
+			PickedAt:      documentTime(entry.PickedAt),
+			Method:        entry.Method,
+			Seed:          entry.Seed,
+			Event:         entry.Event,
+			From:          entry.From,
+		}
+	}
+
+	return document
+}
+
+// WriteDocument writes the Document of a queue and its history to w as
+// indented JSON
+func WriteDocument(w io.Writer, albums []Album, history []HistoryEntry, exportedAt time.Time) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewDocument(albums, history, exportedAt)); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// ReadDocument reads a Document written by WriteDocument. Documents of
+// another format or of a version this reader does not support are rejected
+// with an error naming the version.
+func ReadDocument(r io.Reader) (Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	// Check the format and version before relying on any other field
+	var header struct {
+		Format  string `json:"format"`
+		Version int    `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return Document{}, fmt.Errorf("invalid JSON document: %w", err)
+	}
+	if header.Format != DocumentFormat {
+		return Document{}, fmt.Errorf("not a music queue document: \"format\" must be %q, got %q", DocumentFormat, header.Format)
+	}
+	switch {
+	case header.Version == 0:
+		return Document{}, fmt.Errorf("invalid music queue document: missing \"version\"")
+	case header.Version > DocumentVersion:
+		return Document{}, fmt.Errorf("music queue document version %d is newer than this program supports (version %d); update it to import the document", header.Version, DocumentVersion)
+	case header.Version < DocumentVersion:
+		// Older versions are migrated to the current schema here; version 1
+		// is the first, so there are none yet
+		return Document{}, fmt.Errorf("music queue document version %d is not supported (version %d is)", header.Version, DocumentVersion)
+	}
+
+	var document Document
+	if err := json.Unmarshal(data, &document); err != nil {
+		return Document{}, fmt.Errorf("invalid music queue document: %w", err)
+	}
+	return document, nil
+}
+
+// HistoryEntries returns the document's history entries
+func (d Document) HistoryEntries() ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, len(d.History))
+	for i, entry := range d.History {
+		album, err := entry.album()
+		if err != nil {
+			return nil, fmt.Errorf("history entry %d: %w", i+1, err)
+		}
+		entries[i] = HistoryEntry{
+			Album:    album,
+			PickedAt: timeOf(entry.PickedAt),
+			Method:   entry.Method,
+			Seed:     entry.Seed,
+			Event:    entry.Event,
+			From:     entry.From,
+		}
+	}
+	return entries, nil
+}
+
+// ImportJSON imports a Document written by WriteDocument: the albums of its
+// queue are added with the same duplicate handling as ImportAlbums, and its
+// history entries that are not already in the history are appended, in one
+// operation. Rejected albums are reported by their position in the queue
+// array and as queue records.
+func (qs *QueueService) ImportJSON(filename string) (ImportReport, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return ImportReport{}, fmt.Errorf("file not found: %s", filename)
+	}
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to read import file: %w", err)
+	}
+	defer file.Close()
+
+	document, err := ReadDocument(file)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	history, err := document.HistoryEntries()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	entries := make([]ImportItem, len(document.Queue))
+	for i, item := range document.Queue {
+		album, err := item.album()
+		entries[i] = ImportItem{Line: i + 1, Text: FormatRecord(item.record()), Album: album, Err: err}
+	}
+
+	if len(entries) == 0 && len(history) == 0 {
+		return ImportReport{}, nil
+	}
+
+	var report ImportReport
+	err = qs.mutate(OpImport, func(existingAlbums []Album) (mutation, error) {
+		archived, err := qs.archive.ReadLines()
+		if err != nil {
+			return mutation{}, fmt.Errorf("failed to read history: %w", err)
+		}
+		existingHistory := make(map[string]bool, len(archived))
+		for _, line := range archived {
+			existingHistory[line] = true
+		}
+
+		var updatedAlbums []Album
+		updatedAlbums, report = qs.importAlbums(existingAlbums, entries)
+
+		var added []HistoryEntry
+		for _, entry := range history {
+			record := FormatHistoryRecord(entry)
+			if !existingHistory[record] {
+				added = append(added, entry)
+				existingHistory[record] = true
+			}
+		}
+		report.History = len(added)
+
+		return mutation{
+			albums:      updatedAlbums,
+			history:     added,
+			description: fmt.Sprintf("%d albums and %d history entries", report.Added, report.History),
+		}, nil
+	})
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	return report, nil
+}
+
+// newDocumentAlbum converts an album to its document form
+func newDocumentAlbum(album Album) DocumentAlbum {
+	return DocumentAlbum{
+		Artist:       album.Artist,
+		Album:        album.Title,
+		Year:         album.Year,
+		Priority:     album.Priority,
+		Tags:         album.Tags,
+		Notes:        album.Notes,
+		AddedAt:      documentTime(album.AddedAt),
+		Source:       album.Source,
+		Skips:        album.Skips,
+		SnoozedUntil: documentTime(album.SnoozedUntil),
+		Pinned:       album.Pinned,
+	}
+}
+
+// record converts the entry to an album without checking it
+func (d DocumentAlbum) record() Album {
+	return Album{
+		Artist:       strings.TrimSpace(d.Artist),
+		Title:        strings.TrimSpace(d.Album),
+		AddedAt:      timeOf(d.AddedAt),
+		Source:       d.Source,
+		Notes:        d.Notes,
+		Year:         d.Year,
+		Priority:     d.Priority,
+		Skips:        d.Skips,
+		SnoozedUntil: timeOf(d.SnoozedUntil),
+		Pinned:       d.Pinned,
+		Tags:         NormalizeTags(d.Tags...),
+	}
+}
+
+// album converts the entry to an album, checking the values a queue record
+// could not hold
+func (d DocumentAlbum) album() (Album, error) {
+	album := d.record()
+	text := album.String()
+	switch {
+	case album.Artist == "":
+		return Album{}, &AlbumFormatError{Text: text, Problem: "missing artist"}
+	case album.Title == "":
+		return Album{}, &AlbumFormatError{Text: text, Problem: "missing album title"}
+	case album.Priority < 0:
+		return Album{}, fmt.Errorf("invalid %s value %d for %s", fieldPriority, album.Priority, album)
+	case album.Skips < 0:
+		return Album{}, fmt.Errorf("invalid %s value %d for %s", fieldSkips, album.Skips, album)
+	}
+	return album, nil
+}
+
+// documentTime returns t in UTC, or nil when it is zero
+func documentTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// timeOf returns *t, or the zero time when t is nil
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
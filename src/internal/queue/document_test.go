@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestDocument_RoundTrip(t *testing.T) {
+	albums := []Album{
+		{
+			Artist:       "Everything - Everything",
+			Title:        "Get to Heaven",
+			AddedAt:      time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+			Source:       SourceImport,
+			Notes:        "second floor",
+			Year:         2015,
+			Priority:     3,
+			Skips:        1,
+			SnoozedUntil: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+			Pinned:       true,
+			Tags:         []string{"indie", "uk"},
+		},
+		{Artist: "Pink Floyd", Title: "The Wall", AddedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Source: SourceAdd},
+	}
+	history := []HistoryEntry{
+		{Album: Album{Artist: "Miles Davis", Title: "Kind of Blue"}, PickedAt: time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC), Method: MethodRandom, Seed: seedOf(42)},
+		{Album: Album{Artist: "Miles Davis", Title: "Kind of Blue"}, PickedAt: time.Date(2024, 3, 2, 20, 0, 0, 0, time.UTC), Event: EventSkipped},
+		{Album: Album{Artist: "Blur", Title: "Parklife"}, PickedAt: time.Date(2024, 3, 3, 20, 0, 0, 0, time.UTC), Event: EventEdited, From: "Blurr - Parklife"},
+	}
+
+	var builder strings.Builder
+	if err := WriteDocument(&builder, albums, history, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteDocument returned error: %v", err)
+	}
+	for _, expected := range []string{`"format": "music-queue"`, `"version": 1`, `"album": "Get to Heaven"`, `"tags": [`} {
+		if !strings.Contains(builder.String(), expected) {
+			t.Errorf("Expected document to contain %s, got:\n%s", expected, builder.String())
+		}
+	}
+
+	documentFile := filepath.Join(t.TempDir(), "queue.json")
+	if err := os.WriteFile(documentFile, []byte(builder.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+	report, err := queue.ImportJSON(documentFile)
+	if err != nil {
+		t.Fatalf("ImportJSON returned error: %v", err)
+	}
+	if report.Added != 2 || report.History != 3 || len(report.Rejected) != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	imported, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported, albums) {
+		t.Errorf("Queue round trip mismatch:\n got  %+v\n want %+v", imported, albums)
+	}
+
+	importedHistory, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(importedHistory, history) {
+		t.Errorf("History round trip mismatch:\n got  %+v\n want %+v", importedHistory, history)
+	}
+
+	// Importing again adds nothing
+	report, err = queue.ImportJSON(documentFile)
+	if err != nil {
+		t.Fatalf("ImportJSON returned error: %v", err)
+	}
+	if report.Added != 0 || report.Duplicates != 2 || report.History != 0 {
+		t.Errorf("Expected everything to be skipped on a second import, got %+v", report)
+	}
+
+	// The first import is undone as one operation; the second changed nothing
+	if _, err := queue.Undo(); err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+	if count, _ := queue.CountAlbums(); count != 0 {
+		t.Errorf("Expected an empty queue after undoing the import, got %d albums", count)
+	}
+	if entries, _ := queue.History(time.Time{}, time.Time{}); len(entries) != 0 {
+		t.Errorf("Expected no history after undoing the import, got %+v", entries)
+	}
+}
+
+func TestQueueService_ImportJSON_Rejects(t *testing.T) {
+	documentFile := filepath.Join(t.TempDir(), "queue.json")
+	document := `{"format": "music-queue", "version": 1, "future": true, "queue": [
+		{"artist": "The Beatles", "album": "Abbey Road", "unknown": "ignored"},
+		{"artist": "", "album": "Untitled"},
+		{"artist": "Radiohead", "album": "OK Computer", "priority": -1},
+		{"artist": "the beatles", "album": "abbey road"}
+	]}`
+	if err := os.WriteFile(documentFile, []byte(document), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := NewQueue(storage.NewMemoryStorage("queue")).ImportJSON(documentFile)
+	if err != nil {
+		t.Fatalf("ImportJSON returned error: %v", err)
+	}
+
+	expected := []RejectedLine{
+		{Line: 2, Text: `"" - Untitled`, Reason: RejectFormat, Message: "missing artist"},
+		{Line: 3, Text: "Radiohead - OK Computer\tpriority=-1", Reason: RejectFormat, Message: "invalid priority value -1 for Radiohead - OK Computer"},
+		{Line: 4, Text: "the beatles - abbey road", Reason: RejectDuplicate, Message: "duplicate of 'The Beatles - Abbey Road' on line 1"},
+	}
+	if report.Added != 1 || !reflect.DeepEqual(report.Rejected, expected) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestReadDocument_Versions(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		expected string
+	}{
+		{"not JSON", "Artist - Album", "invalid JSON document"},
+		{"other format", `{"version": 1, "queue": []}`, `not a music queue document: "format" must be "music-queue", got ""`},
+		{"missing version", `{"format": "music-queue", "queue": []}`, `missing "version"`},
+		{"newer version", `{"format": "music-queue", "version": 2, "queue": []}`, "version 2 is newer than this program supports (version 1)"},
+		{"invalid version", `{"format": "music-queue", "version": -1}`, "version -1 is not supported"},
+		{"wrong field type", `{"format": "music-queue", "version": 1, "queue": {}}`, "invalid music queue document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadDocument(strings.NewReader(tt.document))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("ReadDocument: expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Match returns the albums in the queue matched by pattern (see MatchAlbums),
+// in queue order, without changing anything. Use it to confirm what Remove
+// will delete.
+func (qs *QueueService) Match(pattern string) ([]Album, error) {
+	albums, err := qs.ListAlbums()
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := MatchAlbums(albums, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Album, len(matches))
+	for i, index := range matches {
+		result[i] = albums[index]
+	}
+	return result, nil
+}
+
+// Remove deletes the given albums from the queue, matched case-insensitively
+// by "Artist - Album" text, and records each removal in the history. Nothing
+// is removed if any of them is no longer in the queue.
+func (qs *QueueService) Remove(albums ...Album) ([]Album, error) {
+	if len(albums) == 0 {
+		return nil, fmt.Errorf("no album given")
+	}
+
+	var removed []Album
+	err := qs.mutate(OpRemove, func(existingAlbums []Album) (mutation, error) {
+		removeKeys := albumKeys(albums)
+		existingKeys := albumKeys(existingAlbums)
+		for _, album := range albums {
+			if !existingKeys[album.Key()] {
+				return mutation{}, fmt.Errorf("album '%s' is no longer in the queue", album)
+			}
+		}
+
+		now := qs.now()
+		var kept []Album
+		var history []HistoryEntry
+		removed = nil
+		for _, album := range existingAlbums {
+			if removeKeys[album.Key()] {
+				removed = append(removed, album)
+				history = append(history, HistoryEntry{Album: album, PickedAt: now, Event: EventRemoved})
+				continue
+			}
+			kept = append(kept, album)
+		}
+
+		description := removed[0].String()
+		if len(removed) > 1 {
+			description = fmt.Sprintf("%d albums", len(removed))
+		}
+
+		return mutation{
+			albums:      kept,
+			history:     history,
+			description: description,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
+
+// Edit replaces the artist and title of the album identified by ref (see
+// FindAlbum) with those parsed from text, keeping its position and metadata,
+// and records the change in the history. It fails if the new name belongs to
+// another album in the queue; changing only the capitalization is allowed.
+func (qs *QueueService) Edit(ref string, text string) (Album, error) {
+	replacement, err := ParseAlbum(text)
+	if err != nil {
+		return Album{}, err
+	}
+
+	return qs.edit(ref, replacement)
+}
+
+// edit gives the album identified by ref the artist and title of replacement
+func (qs *QueueService) edit(ref string, replacement Album) (Album, error) {
+	var updated Album
+	err := qs.mutate(OpEdit, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+
+		original := existingAlbums[index]
+		others := append(append([]Album(nil), existingAlbums[:index]...), existingAlbums[index+1:]...)
+		if err := addAlbumCheck(replacement, others); err != nil {
+			return mutation{}, err
+		}
+
+		albums := append([]Album(nil), existingAlbums...)
+		albums[index].Artist = replacement.Artist
+		albums[index].Title = replacement.Title
+		updated = albums[index]
+
+		if updated.String() == original.String() {
+			return mutation{albums: existingAlbums}, nil
+		}
+
+		return mutation{
+			albums:      albums,
+			history:     []HistoryEntry{{Album: updated, PickedAt: qs.now(), Event: EventEdited, From: original.String()}},
+			description: fmt.Sprintf("%s -> %s", original, updated),
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return updated, nil
+}
+
+// Rename changes only the artist or only the title of the album identified
+// by ref; an empty artist or title keeps the current one. See Edit.
+func (qs *QueueService) Rename(ref string, artist, title string) (Album, error) {
+	albums, err := qs.ListAlbums()
+	if err != nil {
+		return Album{}, err
+	}
+
+	index, err := FindAlbum(albums, ref)
+	if err != nil {
+		return Album{}, err
+	}
+
+	current := albums[index]
+	replacement := Album{Artist: strings.TrimSpace(artist), Title: strings.TrimSpace(title)}
+	if replacement.Artist == "" {
+		replacement.Artist = current.Artist
+	}
+	if replacement.Title == "" {
+		replacement.Title = current.Title
+	}
+
+	// Refer to the album by its full text, so that a concurrent change to the
+	// queue order cannot redirect the edit to another album
+	return qs.edit(current.String(), replacement)
+}
@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_Remove(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohead - In Rainbows", "Pink Floyd - The Wall", "Radiohead - OK Computer"})
+	queue := NewQueue(memoryStorage)
+
+	matches, err := queue.Match("radiohead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %v", matches)
+	}
+
+	removed, err := queue.Remove(matches...)
+	if err != nil {
+		t.Fatalf("Remove returned error: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 albums removed, got %v", removed)
+	}
+
+	remaining, err := readAlbumTexts(memoryStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(remaining, ",") != "Pink Floyd - The Wall" {
+		t.Errorf("Unexpected queue after remove: %v", remaining)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Event != EventRemoved || history[1].Album.Title != "OK Computer" {
+		t.Errorf("Expected removals in history, got %+v", history)
+	}
+	if len(plays(history)) != 0 {
+		t.Error("Expected removals not to count as plays")
+	}
+
+	// Removing an album that is gone fails without removing anything
+	_, err = queue.Remove(Album{Artist: "Pink Floyd", Title: "The Wall"}, removed[0])
+	if err == nil || !strings.Contains(err.Error(), "no longer in the queue") {
+		t.Errorf("Expected error for missing album, got %v", err)
+	}
+	if count, _ := queue.CountAlbums(); count != 1 {
+		t.Errorf("Expected nothing removed on error, got %d albums", count)
+	}
+
+	// Undo brings both albums back in place
+	if _, err := queue.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	remaining, err = readAlbumTexts(memoryStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 3 || remaining[0] != "Radiohead - In Rainbows" {
+		t.Errorf("Expected undo to restore the queue, got %v", remaining)
+	}
+}
+
+func TestQueueService_Edit(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohed - In Rainbows\tpriority=3", "Pink Floyd - The Wall"})
+	queue := NewQueue(memoryStorage)
+
+	updated, err := queue.Edit("1", "Radiohead - In Rainbows")
+	if err != nil {
+		t.Fatalf("Edit returned error: %v", err)
+	}
+	if updated.Artist != "Radiohead" || updated.Priority != 3 {
+		t.Errorf("Expected corrected artist with metadata kept, got %+v", updated)
+	}
+
+	albums, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if albums[0].String() != "Radiohead - In Rainbows" {
+		t.Errorf("Expected the album to keep its position, got %v", albums)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Event != EventEdited || history[0].From != "Radiohed - In Rainbows" {
+		t.Errorf("Expected the edit in history, got %+v", history)
+	}
+
+	// Duplicates are rejected, but changing capitalization is not a duplicate
+	if _, err := queue.Edit("1", "pink floyd - the wall"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected duplicate error, got %v", err)
+	}
+	if _, err := queue.Edit("wall", "Pink Floyd - THE WALL"); err != nil {
+		t.Errorf("Expected capitalization change to be allowed, got %v", err)
+	}
+
+	if _, err := queue.Edit("1", "no separator"); err == nil || !strings.Contains(err.Error(), "invalid album format") {
+		t.Errorf("Expected format error, got %v", err)
+	}
+}
+
+func TestQueueService_Rename(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohead - Kid B"})
+	queue := NewQueue(memoryStorage)
+
+	updated, err := queue.Rename("kid", "", "Kid A")
+	if err != nil {
+		t.Fatalf("Rename returned error: %v", err)
+	}
+	if updated.String() != "Radiohead - Kid A" {
+		t.Errorf("Expected only the title to change, got %s", updated)
+	}
+
+	// Renaming to the same name changes nothing
+	if _, err := queue.Rename("1", "Radiohead", ""); err != nil {
+		t.Fatal(err)
+	}
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Errorf("Expected only the real change in history, got %+v", history)
+	}
+}
@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Fields that filter conditions can test
+const (
+	FilterArtist = "artist"
+	FilterTitle  = "title"
+	FilterAlbum  = "album" // the full "Artist - Album" text
+	FilterNotes  = "notes"
+	FilterSource = "source"
+	FilterYear   = "year"
+	FilterTag    = "tag" // holds when any of the album's tags matches
+)
+
+// FilterFields lists the fields accepted in conditions
+func FilterFields() []string {
+	return []string{FilterArtist, FilterTitle, FilterAlbum, FilterNotes, FilterSource, FilterYear, FilterTag}
+}
+
+// Orders accepted by AlbumFilter.Sort
+const (
+	SortQueue  = "queue"
+	SortArtist = "artist"
+	SortAdded  = "added"
+)
+
+// SortOrders lists the supported sort orders
+func SortOrders() []string {
+	return []string{SortQueue, SortArtist, SortAdded}
+}
+
+// Condition tests one field of an album against a pattern (see
+// CompilePattern). With Exact set the whole value must equal the pattern,
+// ignoring case; with Negate set the condition holds when the test fails.
+// An empty Field tests the album text and the notes.
+type Condition struct {
+	Field   string
+	Pattern string
+	Exact   bool
+	Negate  bool
+}
+
+// ParseCondition parses a condition written as "field~pattern" (matches),
+// "field!~pattern" (does not match), "field=value" (equals) or
+// "field!=value" (does not equal), e.g. "title~live"
+func ParseCondition(text string) (Condition, error) {
+	index := strings.IndexAny(text, "~=")
+	if index <= 0 {
+		return Condition{}, fmt.Errorf("invalid filter %q: expected field~pattern or field=value", text)
+	}
+
+	condition := Condition{
+		Field:   strings.ToLower(strings.TrimSpace(text[:index])),
+		Pattern: text[index+1:],
+		Exact:   text[index] == '=',
+	}
+	if strings.HasSuffix(condition.Field, "!") {
+		condition.Field = strings.TrimSpace(strings.TrimSuffix(condition.Field, "!"))
+		condition.Negate = true
+	}
+
+	if !slices.Contains(FilterFields(), condition.Field) {
+		return Condition{}, fmt.Errorf("invalid filter %q: unknown field %q (available: %s)", text, condition.Field, strings.Join(FilterFields(), ", "))
+	}
+
+	return condition, nil
+}
+
+// AlbumFilter selects, orders and limits albums for display
+type AlbumFilter struct {
+	Conditions []Condition // all must hold
+	Sort       string      // one of SortOrders; empty keeps queue order
+	Limit      int         // maximum number of albums; zero means no limit
+}
+
+// FilterAlbums returns the indices of the albums that satisfy every
+// condition of filter, in the filter's order and cut to its limit. Indices
+// rather than albums are returned so that callers can show queue positions.
+func FilterAlbums(albums []Album, filter AlbumFilter) ([]int, error) {
+	if filter.Limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d: must be zero or greater", filter.Limit)
+	}
+
+	matchers := make([]func(Album) bool, len(filter.Conditions))
+	for i, condition := range filter.Conditions {
+		matcher, err := condition.matcher()
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = matcher
+	}
+
+	var indices []int
+	for i, album := range albums {
+		if !slices.ContainsFunc(matchers, func(matches func(Album) bool) bool { return !matches(album) }) {
+			indices = append(indices, i)
+		}
+	}
+
+	switch filter.Sort {
+	case "", SortQueue:
+	case SortArtist:
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return strings.Compare(strings.ToLower(albums[a].Artist+"\x00"+albums[a].Title), strings.ToLower(albums[b].Artist+"\x00"+albums[b].Title))
+		})
+	case SortAdded:
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return albums[a].AddedAt.Compare(albums[b].AddedAt)
+		})
+	default:
+		return nil, fmt.Errorf("unknown sort order %q (available: %s)", filter.Sort, strings.Join(SortOrders(), ", "))
+	}
+
+	if filter.Limit > 0 && len(indices) > filter.Limit {
+		indices = indices[:filter.Limit]
+	}
+	return indices, nil
+}
+
+// matcher compiles the condition into a test on an album
+func (c Condition) matcher() (func(Album) bool, error) {
+	matches := func(value string) bool { return strings.EqualFold(value, c.Pattern) }
+	if !c.Exact {
+		var err error
+		matches, err = CompilePattern(c.Pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(album Album) bool {
+		var found bool
+		switch c.Field {
+		case "":
+			found = matches(album.String()) || matches(album.Notes)
+		case FilterTag:
+			found = slices.ContainsFunc(album.Tags, matches)
+		default:
+			found = matches(filterValue(album, c.Field))
+		}
+		return found != c.Negate
+	}, nil
+}
+
+// filterValue returns the value of field for album as text
+func filterValue(album Album, field string) string {
+	switch field {
+	case FilterArtist:
+		return album.Artist
+	case FilterTitle:
+		return album.Title
+	case FilterNotes:
+		return album.Notes
+	case FilterSource:
+		return album.Source
+	case FilterYear:
+		if album.Year == 0 {
+			return ""
+		}
+		return strconv.Itoa(album.Year)
+	default:
+		return album.String()
+	}
+}
+
+// CompilePattern returns a case-insensitive test for pattern: "/expr/" is a
+// regular expression, a pattern containing *, ? or [ is a glob that must
+// match the whole value, and anything else matches as a substring
+func CompilePattern(pattern string) (func(string) bool, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		expr, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %w", pattern, err)
+		}
+		return expr.MatchString, nil
+	}
+
+	lower := strings.ToLower(pattern)
+	if strings.ContainsAny(pattern, "*?[") {
+		return func(value string) bool { return globMatch(lower, strings.ToLower(value)) }, nil
+	}
+	return func(value string) bool { return strings.Contains(strings.ToLower(value), lower) }, nil
+}
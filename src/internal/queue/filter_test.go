@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		text          string
+		expected      Condition
+		expectedError string
+	}{
+		{"title~live", Condition{Field: FilterTitle, Pattern: "live"}, ""},
+		{"Artist=Radiohead", Condition{Field: FilterArtist, Pattern: "Radiohead", Exact: true}, ""},
+		{"notes!~vinyl", Condition{Field: FilterNotes, Pattern: "vinyl", Negate: true}, ""},
+		{"year!=1997", Condition{Field: FilterYear, Pattern: "1997", Exact: true, Negate: true}, ""},
+		{"title~a=b", Condition{Field: FilterTitle, Pattern: "a=b"}, ""},
+		{"live", Condition{}, "expected field~pattern"},
+		{"~live", Condition{}, "expected field~pattern"},
+		{"label~xl", Condition{}, "unknown field \"label\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			condition, err := ParseCondition(tt.text)
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if condition != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, condition)
+			}
+		})
+	}
+}
+
+func TestFilterAlbums(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	albums := []Album{
+		{Artist: "Radiohead", Title: "In Rainbows", AddedAt: day(3), Year: 2007},
+		{Artist: "Nirvana", Title: "MTV Unplugged in New York (Live)", AddedAt: day(1), Notes: "acoustic", Tags: []string{"grunge", "unplugged"}},
+		{Artist: "AC/DC", Title: "Live at River Plate", AddedAt: day(2)},
+		{Artist: "radiohead", Title: "OK Computer", Year: 1997},
+	}
+
+	tests := []struct {
+		name          string
+		filter        AlbumFilter
+		expected      []int
+		expectedError string
+	}{
+		{"no filter", AlbumFilter{}, []int{0, 1, 2, 3}, ""},
+		{"substring", AlbumFilter{Conditions: []Condition{{Field: FilterTitle, Pattern: "LIVE"}}}, []int{1, 2}, ""},
+		{"glob", AlbumFilter{Conditions: []Condition{{Field: FilterTitle, Pattern: "live*"}}}, []int{2}, ""},
+		{"regex", AlbumFilter{Conditions: []Condition{{Field: FilterTitle, Pattern: `/\(live\)$/`}}}, []int{1}, ""},
+		{"exact", AlbumFilter{Conditions: []Condition{{Field: FilterArtist, Pattern: "RADIOHEAD", Exact: true}}}, []int{0, 3}, ""},
+		{"negated", AlbumFilter{Conditions: []Condition{{Field: FilterTitle, Pattern: "live", Negate: true}}}, []int{0, 3}, ""},
+		{"year", AlbumFilter{Conditions: []Condition{{Field: FilterYear, Pattern: "199?"}}}, []int{3}, ""},
+		{"tag", AlbumFilter{Conditions: []Condition{{Field: FilterTag, Pattern: "GRUNGE", Exact: true}}}, []int{1}, ""},
+		{"any field", AlbumFilter{Conditions: []Condition{{Pattern: "acoustic"}}}, []int{1}, ""},
+		{"all conditions", AlbumFilter{Conditions: []Condition{{Field: FilterArtist, Pattern: "radiohead"}, {Field: FilterYear, Pattern: "2007", Exact: true}}}, []int{0}, ""},
+		{"sort by artist", AlbumFilter{Sort: SortArtist}, []int{2, 1, 0, 3}, ""},
+		{"sort by added", AlbumFilter{Sort: SortAdded}, []int{3, 1, 2, 0}, ""},
+		{"limit", AlbumFilter{Sort: SortArtist, Limit: 2}, []int{2, 1}, ""},
+		{"bad regex", AlbumFilter{Conditions: []Condition{{Field: FilterTitle, Pattern: "/(/"}}}, nil, "invalid regular expression"},
+		{"bad sort", AlbumFilter{Sort: "year"}, nil, "unknown sort order"},
+		{"bad limit", AlbumFilter{Limit: -1}, nil, "invalid limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indices, err := FilterAlbums(albums, tt.filter)
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(indices, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, indices)
+			}
+		})
+	}
+}
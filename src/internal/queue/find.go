@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FindAlbum returns the index of the album identified by ref: a 1-based
+// position as shown by the list command, the full "Artist - Album" text
+// (case-insensitive), or a case-insensitive substring matching exactly one
+// album
+func FindAlbum(albums []Album, ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return 0, fmt.Errorf("no album given")
+	}
+
+	if position, err := strconv.Atoi(ref); err == nil {
+		if position < 1 || position > len(albums) {
+			return 0, fmt.Errorf("no album at position %d (queue has %d albums)", position, len(albums))
+		}
+		return position - 1, nil
+	}
+
+	key := strings.ToLower(ref)
+	if album, err := ParseAlbum(ref); err == nil {
+		for i, candidate := range albums {
+			if candidate.Key() == album.Key() {
+				return i, nil
+			}
+		}
+	}
+
+	var matches []int
+	for i, candidate := range albums {
+		if strings.Contains(candidate.Key(), key) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("album '%s' not found in the queue", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, match := range matches {
+			names[i] = albums[match].String()
+		}
+		return 0, fmt.Errorf("'%s' matches %d albums: %s", ref, len(matches), strings.Join(names, "; "))
+	}
+}
+
+// MatchAlbums returns the indices of every album matched by pattern: a
+// 1-based position, the full "Artist - Album" text (case-insensitive), a
+// case-insensitive glob such as "radiohead - *", or a case-insensitive
+// substring. Unlike FindAlbum, several matches are not an error; no match is.
+func MatchAlbums(albums []Album, pattern string) ([]int, error) {
+	pattern = strings.TrimSpace(pattern)
+	if _, err := strconv.Atoi(pattern); err == nil || pattern == "" {
+		index, err := FindAlbum(albums, pattern)
+		if err != nil {
+			return nil, err
+		}
+		return []int{index}, nil
+	}
+
+	key := strings.ToLower(pattern)
+	for i, album := range albums {
+		if album.Key() == key {
+			return []int{i}, nil
+		}
+	}
+
+	glob := strings.ContainsAny(pattern, "*?[")
+	var matches []int
+	for i, album := range albums {
+		if (glob && globMatch(key, album.Key())) || (!glob && strings.Contains(album.Key(), key)) {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no album in the queue matches '%s'", pattern)
+	}
+	return matches, nil
+}
+
+// globMatch reports whether key matches the glob pattern. Unlike in file
+// paths, "*" also matches "/" (as in "AC/DC"). Malformed patterns match nothing.
+func globMatch(pattern, key string) bool {
+	const slash = "\x00"
+	matched, err := path.Match(strings.ReplaceAll(pattern, "/", slash), strings.ReplaceAll(key, "/", slash))
+	return err == nil && matched
+}
@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindAlbum(t *testing.T) {
+	albums := []Album{
+		{Artist: "Radiohead", Title: "In Rainbows"},
+		{Artist: "Radiohead", Title: "OK Computer"},
+		{Artist: "Pink Floyd", Title: "The Wall"},
+	}
+
+	tests := []struct {
+		name          string
+		ref           string
+		expectedIndex int
+		expectedError string
+	}{
+		{"position", "2", 1, ""},
+		{"full text", "pink floyd - the wall", 2, ""},
+		{"unique substring", "rainbows", 0, ""},
+		{"position out of range", "4", 0, "no album at position 4"},
+		{"ambiguous substring", "radiohead", 0, "matches 2 albums"},
+		{"not found", "Beatles", 0, "not found"},
+		{"empty", " ", 0, "no album given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := FindAlbum(albums, tt.ref)
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if index != tt.expectedIndex {
+				t.Errorf("Expected index %d, got %d", tt.expectedIndex, index)
+			}
+		})
+	}
+}
+
+func TestMatchAlbums(t *testing.T) {
+	albums := []Album{
+		{Artist: "Radiohead", Title: "In Rainbows"},
+		{Artist: "Radiohead", Title: "OK Computer"},
+		{Artist: "AC/DC", Title: "Back in Black"},
+		{Artist: "Radio Birdman", Title: "Radios Appear"},
+	}
+
+	tests := []struct {
+		name          string
+		pattern       string
+		expected      []int
+		expectedError string
+	}{
+		{"position", "3", []int{2}, ""},
+		{"exact text", "radiohead - ok computer", []int{1}, ""},
+		{"substring", "radiohead", []int{0, 1}, ""},
+		{"glob", "radio* - *", []int{0, 1, 3}, ""},
+		{"glob across slash", "ac*", []int{2}, ""},
+		{"position out of range", "9", nil, "no album at position 9"},
+		{"no match", "beatles", nil, "no album in the queue matches"},
+		{"empty", "", nil, "no album given"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := MatchAlbums(albums, tt.pattern)
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !slices.Equal(matches, tt.expected) {
+				t.Errorf("Expected matches %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Selection methods recorded in listening history
+const (
+	MethodRandom = "random"
+	MethodManual = "manual"
+	MethodPinned = "pinned"
+)
+
+// History events other than picks
+const (
+	EventSkipped = "skipped"
+	EventRemoved = "removed"
+	EventEdited  = "edited"
+	EventMerged  = "merged" // the album was merged into a duplicate of it
+)
+
+// Record field keys used for history metadata, in addition to the album fields
+const (
+	fieldPicked = "picked"
+	fieldMethod = "method"
+	fieldSeed   = "seed"
+	fieldEvent  = "event"
+	fieldFrom   = "from"
+)
+
+// HistoryEntry is one album taken from the queue, with when and how it was
+// picked. Entries with an Event record something else that happened to an
+// album, such as EventSkipped; PickedAt is then the time of the event.
+type HistoryEntry struct {
+	Album    Album
+	PickedAt time.Time // zero for entries archived before history was recorded
+	Method   string    // how the album was picked, e.g. MethodRandom
+	Seed     *int64    // seed of the pick's random source; nil when the pick was not seeded
+	Event    string    // empty for picks
+	From     string    // for EventEdited, the album's previous "Artist - Album" text
+}
+
+// FormatHistoryRecord encodes a history entry as a single storage line: the
+// album record followed by the pick metadata fields
+func FormatHistoryRecord(entry HistoryEntry) string {
+	fields := []string{FormatRecord(entry.Album)}
+
+	if !entry.PickedAt.IsZero() {
+		fields = append(fields, fieldPicked+"="+entry.PickedAt.UTC().Format(time.RFC3339))
+	}
+	if entry.Method != "" {
+		fields = append(fields, fieldMethod+"="+escapeField(entry.Method))
+	}
+	if entry.Seed != nil {
+		fields = append(fields, fieldSeed+"="+strconv.FormatInt(*entry.Seed, 10))
+	}
+	if entry.Event != "" {
+		fields = append(fields, fieldEvent+"="+escapeField(entry.Event))
+	}
+	if entry.From != "" {
+		fields = append(fields, fieldFrom+"="+escapeField(entry.From))
+	}
+
+	return strings.Join(fields, "\t")
+}
+
+// ParseHistoryRecord decodes a storage line written by FormatHistoryRecord.
+// Plain archive lines from older versions are accepted as entries without a
+// pick time or method, including "Artist-Album" lines with a bare dash.
+func ParseHistoryRecord(line string) (HistoryEntry, error) {
+	album, err := parseStoredRecord(line)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	entry := HistoryEntry{Album: album}
+	for _, field := range strings.Split(line, "\t")[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		value = unescapeField(value)
+
+		switch key {
+		case fieldPicked:
+			pickedAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return HistoryEntry{}, fmt.Errorf("invalid %s value %q for %s: %w", key, value, album, err)
+			}
+			entry.PickedAt = pickedAt
+		case fieldMethod:
+			entry.Method = value
+		case fieldSeed:
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return HistoryEntry{}, fmt.Errorf("invalid %s value %q for %s: %w", key, value, album, err)
+			}
+			entry.Seed = &seed
+		case fieldEvent:
+			entry.Event = value
+		case fieldFrom:
+			entry.From = value
+		}
+	}
+
+	return entry, nil
+}
+
+// History returns the albums taken from the queue, oldest first. Entries are
+// limited to those picked at or after since and before until; a zero bound is
+// open. Entries without a pick time are only returned when both bounds are zero.
+func (qs *QueueService) History(since, until time.Time) ([]HistoryEntry, error) {
+	lines, err := qs.archive.ReadLines()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	bounded := !since.IsZero() || !until.IsZero()
+	entries := make([]HistoryEntry, 0, len(lines))
+	for i, line := range lines {
+		entry, err := ParseHistoryRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: line %d: %w", i+1, err)
+		}
+
+		if bounded {
+			if entry.PickedAt.IsZero() {
+				continue
+			}
+			if !since.IsZero() && entry.PickedAt.Before(since) {
+				continue
+			}
+			if !until.IsZero() && !entry.PickedAt.Before(until) {
+				continue
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// plays returns the picks in history that were not skipped afterwards,
+// oldest first. Selection rules and selectors look at these rather than the
+// full history.
+func plays(history []HistoryEntry) []HistoryEntry {
+	var result []HistoryEntry
+	for _, entry := range history {
+		switch entry.Event {
+		case "":
+			result = append(result, entry)
+		case EventSkipped:
+			// A skip takes back the most recent pick of the album
+			for i := len(result) - 1; i >= 0; i-- {
+				if result[i].Album.Key() == entry.Album.Key() {
+					result = slices.Delete(result, i, i+1)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+// seedOf returns a pointer to seed, for HistoryEntry and Pick literals
+func seedOf(seed int64) *int64 {
+	return &seed
+}
+
+func TestFormatHistoryRecord_RoundTrip(t *testing.T) {
+	album := Album{Artist: "Miles Davis", Title: "Kind of Blue", Source: SourceAdd, Year: 1959, Tags: []string{"jazz"}}
+	pickedAt := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	// A zero seed is a seed like any other; only unseeded picks have none
+	for _, entry := range []HistoryEntry{
+		{Album: album, PickedAt: pickedAt, Method: MethodRandom, Seed: seedOf(-8123456789)},
+		{Album: album, PickedAt: pickedAt, Method: MethodRandom, Seed: seedOf(0)},
+		{Album: album, PickedAt: pickedAt, Method: MethodPinned},
+	} {
+		parsed, err := ParseHistoryRecord(FormatHistoryRecord(entry))
+		if err != nil {
+			t.Fatalf("ParseHistoryRecord returned error: %v", err)
+		}
+
+		if !reflect.DeepEqual(parsed, entry) {
+			t.Errorf("Round trip mismatch:\n got  %+v\n want %+v", parsed, entry)
+		}
+	}
+}
+
+func TestParseHistoryRecord_LegacyArchiveLine(t *testing.T) {
+	entry, err := ParseHistoryRecord("Led Zeppelin - IV")
+	if err != nil {
+		t.Fatalf("ParseHistoryRecord returned error: %v", err)
+	}
+
+	if entry.Album.String() != "Led Zeppelin - IV" || !entry.PickedAt.IsZero() || entry.Method != "" {
+		t.Errorf("Unexpected entry for legacy line: %+v", entry)
+	}
+
+	if _, err := ParseHistoryRecord("Led Zeppelin - IV\tpicked=last week"); err == nil {
+		t.Error("Expected error for invalid pick time")
+	}
+}
+
+func TestQueueService_GetNextAlbum_RecordsHistory(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	if err := memoryStorage.WriteLines([]string{"Artist 1 - Album 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	queue := NewQueue(memoryStorage)
+	pickedAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	queue.now = func() time.Time { return pickedAt }
+
+	if _, err := queue.GetNextAlbum(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("History returned error: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 history entry, got %d", len(entries))
+	}
+
+	if entries[0].Album.String() != "Artist 1 - Album 1" || !entries[0].PickedAt.Equal(pickedAt) || entries[0].Method != MethodRandom {
+		t.Errorf("Unexpected history entry: %+v", entries[0])
+	}
+}
+
+func TestQueueService_History_Filtering(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+
+	archive := memoryStorage.Sibling("archive")
+	err := archive.WriteLines([]string{
+		"Legacy - Album",
+		FormatHistoryRecord(HistoryEntry{Album: Album{Artist: "A", Title: "One"}, PickedAt: day(1), Method: MethodRandom}),
+		FormatHistoryRecord(HistoryEntry{Album: Album{Artist: "B", Title: "Two"}, PickedAt: day(10), Method: MethodRandom}),
+		FormatHistoryRecord(HistoryEntry{Album: Album{Artist: "C", Title: "Three"}, PickedAt: day(20), Method: MethodManual}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue := NewQueue(memoryStorage)
+
+	tests := []struct {
+		name     string
+		since    time.Time
+		until    time.Time
+		expected []string
+	}{
+		{"no bounds includes legacy entries", time.Time{}, time.Time{}, []string{"Legacy - Album", "A - One", "B - Two", "C - Three"}},
+		{"since is inclusive", day(10), time.Time{}, []string{"B - Two", "C - Three"}},
+		{"until is exclusive", time.Time{}, day(10), []string{"A - One"}},
+		{"both bounds", day(2), day(15), []string{"B - Two"}},
+		{"empty range", day(21), time.Time{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := queue.History(tt.since, tt.until)
+			if err != nil {
+				t.Fatalf("History returned error: %v", err)
+			}
+
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Expected %d entries, got %d: %+v", len(tt.expected), len(entries), entries)
+			}
+
+			for i, expected := range tt.expected {
+				if entries[i].Album.String() != expected {
+					t.Errorf("Entry %d: expected %q, got %q", i, expected, entries[i].Album)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"music-queue/src/internal/storage"
+)
+
+// Reasons an import line is rejected
+const (
+	RejectFormat        = "format"
+	RejectDuplicate     = "duplicate"
+	RejectNearDuplicate = "near-duplicate"
+)
+
+// RejectedLine is a line of an import source that was not added to the queue
+type RejectedLine struct {
+	Line    int    `json:"line"`    // 1-based line number in the source; zero when it has no lines
+	Text    string `json:"text"`    // the line as read
+	Reason  string `json:"reason"`  // RejectFormat, RejectDuplicate or RejectNearDuplicate
+	Message string `json:"message"` // what is wrong, e.g. "missing artist"
+
+	// Record is the rejected album as a line of a text import, set when
+	// the source has no lines and Text only says where the album was found
+	Record string `json:"record,omitempty"`
+}
+
+// ImportReport is the outcome of an import: how many albums were added and
+// skipped, and why each skipped line was rejected, in source order
+type ImportReport struct {
+	Added        int            `json:"added"`
+	Duplicates   int            `json:"duplicates"` // including near duplicates
+	FormatErrors int            `json:"format_errors"`
+	Rejected     []RejectedLine `json:"rejected"`
+
+	// History is the number of history entries added from a JSON document
+	History int `json:"history,omitempty"`
+
+	// Header is the header line of a CSV source, needed to import rejected
+	// lines again; empty for other sources
+	Header string `json:"header,omitempty"`
+}
+
+// ImportItem is one album read from an import source, or the error that
+// kept it from being read, with its position in the source for the report.
+// Importers outside this package pass them to ImportItems.
+type ImportItem struct {
+	Line  int    // 1-based position in the source, or zero when it has none
+	Text  string // the source text, or where the item was found, reported when it is rejected
+	Album Album
+	Err   error // reported as a format error
+}
+
+// NewImportItem makes the import item of an album read by an importer
+// outside this package. text is the source text reported if the album is
+// rejected; when empty, the album's record is reported instead. An album
+// without an artist or title is a format error.
+func NewImportItem(line int, text string, album Album) ImportItem {
+	if text == "" {
+		text = FormatRecord(album)
+	}
+
+	item := ImportItem{Line: line, Text: text, Album: album}
+	switch {
+	case album.Artist == "":
+		item.Album, item.Err = Album{}, &AlbumFormatError{Text: text, Problem: "missing artist"}
+	case album.Title == "":
+		item.Album, item.Err = Album{}, &AlbumFormatError{Text: text, Problem: "missing album title"}
+	}
+	return item
+}
+
+// ImportAlbums imports albums from a text file, skipping duplicates (case-insensitive)
+// Returns the number of albums added, number of duplicates skipped, number of format errors, and any error encountered
+func (qs *QueueService) ImportAlbums(filename string) (added int, duplicates int, formatErrors int, err error) {
+	report, err := qs.ImportFile(filename)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return report.Added, report.Duplicates, report.FormatErrors, nil
+}
+
+// ImportFile imports albums from a text file like ImportAlbums and reports
+// every rejected line with its line number in the file, counting blank lines
+func (qs *QueueService) ImportFile(filename string) (ImportReport, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return ImportReport{}, fmt.Errorf("file not found: %s", filename)
+	}
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to read import file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []ImportItem
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			entries = append(entries, parseImportLine(number, line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ImportReport{}, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	return qs.ImportItems(entries)
+}
+
+// ImportFrom imports albums from any store, one album per line, with the same
+// duplicate and format handling as ImportAlbums. Lines may carry metadata in
+// the queue record format; albums without an added time or source get the
+// import time and SourceImport. Near duplicates (see WithSimilarity) are
+// skipped and counted as duplicates.
+func (qs *QueueService) ImportFrom(source storage.Store) (added int, duplicates int, formatErrors int, err error) {
+	report, err := qs.ImportReportFrom(source)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return report.Added, report.Duplicates, report.FormatErrors, nil
+}
+
+// ImportReportFrom imports albums from any store like ImportFrom and reports
+// every rejected line. Stores do not keep blank lines, so line numbers count
+// the store's lines.
+func (qs *QueueService) ImportReportFrom(source storage.Store) (ImportReport, error) {
+	// Read import source
+	importLines, err := source.ReadLines()
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	entries := make([]ImportItem, 0, len(importLines))
+	for i, line := range importLines {
+		if strings.TrimSpace(line) != "" {
+			entries = append(entries, parseImportLine(i+1, strings.TrimSpace(line)))
+		}
+	}
+
+	return qs.ImportItems(entries)
+}
+
+// parseImportLine reads an album record from a line of an import file
+func parseImportLine(number int, line string) ImportItem {
+	album, err := ParseRecord(line)
+	return ImportItem{Line: number, Text: line, Album: album, Err: err}
+}
+
+// ImportItems adds the albums of items to the queue in one operation, with
+// the same duplicate handling as ImportAlbums, and reports the items that
+// were rejected. Albums without an added time or source get the import time
+// and SourceImport.
+func (qs *QueueService) ImportItems(items []ImportItem) (ImportReport, error) {
+	// Handle empty file gracefully
+	if len(items) == 0 {
+		return ImportReport{}, nil
+	}
+
+	var report ImportReport
+	err := qs.mutate(OpImport, func(existingAlbums []Album) (mutation, error) {
+		var updatedAlbums []Album
+		updatedAlbums, report = qs.importAlbums(existingAlbums, items)
+		return mutation{
+			albums:      updatedAlbums,
+			description: fmt.Sprintf("%d albums", report.Added),
+		}, nil
+	})
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	return report, nil
+}
+
+// importAlbums appends the albums of entries to existingAlbums, skipping
+// duplicates and entries that are not valid albums, and reports what was
+// skipped
+func (qs *QueueService) importAlbums(existingAlbums []Album, entries []ImportItem) ([]Album, ImportReport) {
+	var report ImportReport
+
+	// Index the match keys of the queue for normalized duplicate checking;
+	// keys[i] is the key of currentAlbums[i]
+	keys := matchKeys(existingAlbums)
+	indices := make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, found := indices[key]; !found {
+			indices[key] = i
+		}
+	}
+
+	// Items of the imported albums, by position in currentAlbums
+	imported := map[int]ImportItem{}
+
+	currentAlbums := existingAlbums
+	importedAt := qs.now()
+
+	for _, entry := range entries {
+		reject := func(reason, message string) {
+			rejected := RejectedLine{Line: entry.Line, Text: entry.Text, Reason: reason, Message: message}
+			if entry.Line == 0 && entry.Err == nil {
+				rejected.Record = FormatRecord(entry.Album)
+			}
+			report.Rejected = append(report.Rejected, rejected)
+		}
+
+		// Check format validity first
+		if entry.Err != nil {
+			report.FormatErrors++
+			reject(RejectFormat, formatProblem(entry.Err))
+			continue
+		}
+		album := entry.Album
+
+		// Check for duplicates and, with a threshold, near duplicates
+		key := album.MatchKey()
+		if index, found := indices[key]; found {
+			report.Duplicates++
+			reject(RejectDuplicate, "duplicate of "+describeMatch(currentAlbums[index], imported, index))
+			continue
+		}
+		if qs.similarity > 0 {
+			if index, similarity, found := nearestAlbum(key, keys, qs.similarity); found {
+				report.Duplicates++
+				reject(RejectNearDuplicate, fmt.Sprintf("looks like %s (%.0f%% similar)", describeMatch(currentAlbums[index], imported, index), similarity*100))
+				continue
+			}
+		}
+
+		if album.AddedAt.IsZero() {
+			album.AddedAt = importedAt
+		}
+		if album.Source == "" {
+			album.Source = SourceImport
+		}
+
+		// Add album
+		imported[len(currentAlbums)] = entry
+		indices[key] = len(currentAlbums)
+		currentAlbums = append(currentAlbums, album)
+		keys = append(keys, key)
+		report.Added++
+	}
+
+	return currentAlbums, report
+}
+
+// describeMatch names the album at index that a rejected entry duplicates:
+// one imported from an earlier item, by its line or else its text, or one
+// already in the queue
+func describeMatch(album Album, imported map[int]ImportItem, index int) string {
+	item, found := imported[index]
+	switch {
+	case found && item.Line != 0:
+		return fmt.Sprintf("'%s' on line %d", album, item.Line)
+	case found:
+		return fmt.Sprintf("'%s' from %s", album, item.Text)
+	}
+	return fmt.Sprintf("'%s' already in the queue", album)
+}
+
+// formatProblem returns what is wrong with an entry that could not be read,
+// without repeating the entry's text
+func formatProblem(err error) string {
+	var formatErr *AlbumFormatError
+	if errors.As(err, &formatErr) {
+		return formatErr.Problem
+	}
+	return err.Error()
+}
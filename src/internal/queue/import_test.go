@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_ImportFile_Report(t *testing.T) {
+	tempDir := t.TempDir()
+	importFile := filepath.Join(tempDir, "import.txt")
+	importContent := "Pink Floyd - The Wall\n" +
+		"\n" +
+		"Sleater-Kinney\n" +
+		"The Beatles - Abbey Road\n" +
+		"pink floyd - the wall (Remastered)\n" +
+		"Miles Davis - Kind of Blu\n" +
+		"- Untitled\n" +
+		"Radiohead - OK Computer\tyear=ninety-seven\n"
+	if err := os.WriteFile(importFile, []byte(importContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"The Beatles - Abbey Road", "Miles Davis - Kind of Blue"})
+
+	report, err := NewQueue(memoryStorage, WithSimilarity(0.9)).ImportFile(importFile)
+	if err != nil {
+		t.Fatalf("ImportFile returned error: %v", err)
+	}
+
+	if report.Added != 1 || report.Duplicates != 3 || report.FormatErrors != 3 {
+		t.Errorf("Expected 1 added, 3 duplicates and 3 format errors, got %+v", report)
+	}
+
+	expected := []RejectedLine{
+		{Line: 3, Text: "Sleater-Kinney", Reason: RejectFormat, Message: "missing ' - ' between artist and album (a dash only separates them with spaces around it)"},
+		{Line: 4, Text: "The Beatles - Abbey Road", Reason: RejectDuplicate, Message: "duplicate of 'The Beatles - Abbey Road' already in the queue"},
+		{Line: 5, Text: "pink floyd - the wall (Remastered)", Reason: RejectDuplicate, Message: "duplicate of 'Pink Floyd - The Wall' on line 1"},
+		{Line: 6, Text: "Miles Davis - Kind of Blu", Reason: RejectNearDuplicate, Message: "looks like 'Miles Davis - Kind of Blue' already in the queue (96% similar)"},
+		{Line: 7, Text: "- Untitled", Reason: RejectFormat, Message: "missing artist"},
+		{Line: 8, Text: "Radiohead - OK Computer\tyear=ninety-seven", Reason: RejectFormat, Message: `invalid year value "ninety-seven" for Radiohead - OK Computer: strconv.Atoi: parsing "ninety-seven": invalid syntax`},
+	}
+	if !reflect.DeepEqual(report.Rejected, expected) {
+		t.Errorf("Unexpected rejected lines:\n got  %+v\n want %+v", report.Rejected, expected)
+	}
+
+	lines, err := readAlbumTexts(memoryStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"The Beatles - Abbey Road", "Miles Davis - Kind of Blue", "Pink Floyd - The Wall"}) {
+		t.Errorf("Unexpected queue after import: %v", lines)
+	}
+}
+
+func TestQueueService_ImportReportFrom(t *testing.T) {
+	importSource := storage.NewMemoryStorage("import")
+	importSource.WriteLines([]string{"Artist - Album", "Not an album"})
+
+	report, err := NewQueue(storage.NewMemoryStorage("queue")).ImportReportFrom(importSource)
+	if err != nil {
+		t.Fatalf("ImportReportFrom returned error: %v", err)
+	}
+
+	if report.Added != 1 || len(report.Rejected) != 1 || report.Rejected[0].Line != 2 || report.Rejected[0].Reason != RejectFormat {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestQueueService_ImportItems(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queueService := NewQueue(memoryStorage)
+	if err := queueService.AddAlbum("Radiohead - OK Computer"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items without lines, as from a directory scan, are described by text
+	// and rejected with their album as a record
+	report, err := queueService.ImportItems([]ImportItem{
+		{Text: "/music/Radiohead/OK Computer", Album: Album{Artist: "Radiohead", Title: "OK Computer"}},
+		{Text: "/music/Slowdive/Souvlaki", Album: Album{Artist: "Slowdive", Title: "Souvlaki", Source: SourceLibrary}},
+		{Text: "/music/Slowdive/Souvlaki (Remastered)", Album: Album{Artist: "Slowdive", Title: "Souvlaki (Remastered)"}},
+	})
+	if err != nil {
+		t.Fatalf("ImportItems() error = %v", err)
+	}
+
+	expected := []RejectedLine{
+		{Text: "/music/Radiohead/OK Computer", Reason: RejectDuplicate, Message: "duplicate of 'Radiohead - OK Computer' already in the queue", Record: "Radiohead - OK Computer"},
+		{Text: "/music/Slowdive/Souvlaki (Remastered)", Reason: RejectDuplicate, Message: "duplicate of 'Slowdive - Souvlaki' from /music/Slowdive/Souvlaki", Record: "Slowdive - Souvlaki (Remastered)"},
+	}
+	if report.Added != 1 || !reflect.DeepEqual(report.Rejected, expected) {
+		t.Errorf("ImportItems() report = %+v, want 1 added and rejected %+v", report, expected)
+	}
+
+	albums, err := queueService.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := albums[len(albums)-1]; last.Source != SourceLibrary {
+		t.Errorf("imported album source = %q, want %q", last.Source, SourceLibrary)
+	}
+}
+
+func TestNewImportItem(t *testing.T) {
+	tests := []struct {
+		text     string
+		album    Album
+		expected ImportItem
+	}{
+		{"Radiohead,OK Computer", Album{Artist: "Radiohead", Title: "OK Computer"},
+			ImportItem{Line: 1, Text: "Radiohead,OK Computer", Album: Album{Artist: "Radiohead", Title: "OK Computer"}}},
+		{"Burial,", Album{Artist: "Burial"},
+			ImportItem{Line: 1, Text: "Burial,", Err: &AlbumFormatError{Text: "Burial,", Problem: "missing album title"}}},
+		// Without text, the album's record is reported
+		{"", Album{Title: "Untitled"},
+			ImportItem{Line: 1, Text: `"" - Untitled`, Err: &AlbumFormatError{Text: `"" - Untitled`, Problem: "missing artist"}}},
+	}
+
+	for _, tt := range tests {
+		if item := NewImportItem(1, tt.text, tt.album); !reflect.DeepEqual(item, tt.expected) {
+			t.Errorf("NewImportItem(%q) = %+v, want %+v", tt.text, item, tt.expected)
+		}
+	}
+}
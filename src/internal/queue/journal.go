@@ -0,0 +1,377 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+// MaxUndo is the number of operations kept in the undo journal
+const MaxUndo = 50
+
+// Operations recorded in the undo journal
+const (
+	OpAdd        = "add"
+	OpImport     = "import"
+	OpNext       = "next"
+	OpPrioritize = "prioritize"
+	OpSkip       = "skip"
+	OpSnooze     = "snooze"
+	OpRemove     = "remove"
+	OpEdit       = "edit"
+	OpMove       = "move"
+	OpPin        = "pin"
+	OpPlay       = "play"
+	OpTag        = "tag"
+	OpDedupe     = "dedupe"
+)
+
+var (
+	// ErrNothingToUndo is returned by Undo when the journal is empty
+	ErrNothingToUndo = errors.New("nothing to undo")
+
+	// ErrNothingToRedo is returned by Redo when no undone operation can be reapplied
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// JournalEntry records one mutating operation so that it can be undone and
+// redone. Only the changed region of the queue is stored: the Offset lines
+// before it and the Suffix lines after it were left untouched.
+type JournalEntry struct {
+	Op          string    `json:"op"`
+	Description string    `json:"description"`
+	At          time.Time `json:"at"`
+	Offset      int       `json:"offset"`
+	Suffix      int       `json:"suffix"`
+	Before      []string  `json:"before,omitempty"`  // queue records replaced by the operation
+	After       []string  `json:"after,omitempty"`   // queue records written by the operation
+	History     []string  `json:"history,omitempty"` // history records appended by the operation
+
+	// State names the sibling store of selector state the operation changed,
+	// such as the shuffle bag of a pick, with its lines before and after
+	State       string   `json:"state,omitempty"`
+	StateBefore []string `json:"state_before,omitempty"`
+	StateAfter  []string `json:"state_after,omitempty"`
+}
+
+// mutation is the result of a mutating operation: the complete new queue,
+// any history entries to append, a human-readable description, and the
+// selector state to save with a pick
+type mutation struct {
+	albums      []Album
+	history     []HistoryEntry
+	description string
+	state       *SelectorState
+}
+
+// mutate runs a read-modify-write cycle on the queue under the queue lock.
+// fn receives the current queue and returns the change to apply. History
+// entries are written before the queue so that a failure can never lose an
+// album, selector state after it so that a failed pick leaves it as it was,
+// and the change is recorded in the undo journal.
+func (qs *QueueService) mutate(op string, fn func(albums []Album) (mutation, error)) error {
+	return qs.withLock(func() error {
+		beforeLines, err := qs.storage.ReadLines()
+		if err != nil {
+			return fmt.Errorf("failed to read queue: %w", err)
+		}
+
+		albums, err := decodeRecords(beforeLines)
+		if err != nil {
+			return fmt.Errorf("failed to read queue: %w", err)
+		}
+
+		change, err := fn(albums)
+		if err != nil {
+			return err
+		}
+
+		afterLines := encodeRecords(change.albums)
+		historyLines := make([]string, len(change.history))
+		for i, entry := range change.history {
+			historyLines[i] = FormatHistoryRecord(entry)
+		}
+
+		// Nothing changed, so there is nothing to save or undo
+		if slices.Equal(beforeLines, afterLines) && len(historyLines) == 0 {
+			return nil
+		}
+
+		if len(historyLines) > 0 {
+			if err := qs.archive.AppendLines(historyLines...); err != nil {
+				return fmt.Errorf("failed to archive album: %w", err)
+			}
+		}
+
+		var stateBefore []string
+		if change.state != nil {
+			stateBefore, err = qs.storage.Sibling(change.state.Sibling).ReadLines()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", change.state.Sibling, err)
+			}
+		}
+
+		if err := qs.storage.WriteLines(afterLines); err != nil {
+			return fmt.Errorf("failed to save updated queue: %w", err)
+		}
+
+		entry := newJournalEntry(op, change.description, qs.now(), beforeLines, afterLines)
+		entry.History = historyLines
+
+		if change.state != nil {
+			if err := qs.storage.Sibling(change.state.Sibling).WriteLines(change.state.Lines); err != nil {
+				return fmt.Errorf("failed to save %s: %w", change.state.Sibling, err)
+			}
+			entry.State, entry.StateBefore, entry.StateAfter = change.state.Sibling, stateBefore, change.state.Lines
+		}
+
+		if err := qs.pushJournal(entry); err != nil {
+			return fmt.Errorf("failed to update undo journal: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// newJournalEntry builds a journal entry holding only the region of the
+// queue that differs between before and after
+func newJournalEntry(op, description string, at time.Time, before, after []string) JournalEntry {
+	offset := 0
+	for offset < len(before) && offset < len(after) && before[offset] == after[offset] {
+		offset++
+	}
+
+	suffix := 0
+	for suffix < len(before)-offset && suffix < len(after)-offset &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	return JournalEntry{
+		Op:          op,
+		Description: description,
+		At:          at.UTC(),
+		Offset:      offset,
+		Suffix:      suffix,
+		Before:      slices.Clone(before[offset : len(before)-suffix]),
+		After:       slices.Clone(after[offset : len(after)-suffix]),
+	}
+}
+
+// Undo reverses the most recent mutating operation, restoring both the queue
+// and the history, and returns the operation that was undone. It fails if
+// the queue or history were changed by something other than this tool since
+// the operation was recorded.
+func (qs *QueueService) Undo() (JournalEntry, error) {
+	var entry JournalEntry
+	err := qs.withLock(func() error {
+		var err error
+		entry, err = qs.replay(qs.undoStore(), qs.redoStore(), ErrNothingToUndo, true)
+		return err
+	})
+	return entry, err
+}
+
+// Redo reapplies the most recently undone operation and returns it. Any new
+// mutating operation clears the redo journal.
+func (qs *QueueService) Redo() (JournalEntry, error) {
+	var entry JournalEntry
+	err := qs.withLock(func() error {
+		var err error
+		entry, err = qs.replay(qs.redoStore(), qs.undoStore(), ErrNothingToRedo, false)
+		return err
+	})
+	return entry, err
+}
+
+// replay pops the last entry from one journal, applies it to the queue,
+// history and selector state (in reverse when undoing), and pushes it onto
+// the other journal.
+// The caller must hold the queue lock.
+func (qs *QueueService) replay(from, to storage.Store, emptyErr error, undo bool) (JournalEntry, error) {
+	entries, err := readJournal(from)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	if len(entries) == 0 {
+		return JournalEntry{}, emptyErr
+	}
+
+	entry := entries[len(entries)-1]
+
+	expected, replacement := entry.After, entry.Before
+	if !undo {
+		expected, replacement = entry.Before, entry.After
+	}
+
+	queueLines, err := qs.storage.ReadLines()
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	if !regionMatches(queueLines, entry.Offset, entry.Suffix, expected) {
+		return JournalEntry{}, fmt.Errorf("cannot %s %s: the queue was changed outside the journal", replayVerb(undo), entry.Op)
+	}
+
+	historyLines, err := qs.archive.ReadLines()
+	if err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if undo {
+		tail := len(historyLines) - len(entry.History)
+		if tail < 0 || !slices.Equal(historyLines[tail:], entry.History) {
+			return JournalEntry{}, fmt.Errorf("cannot undo %s: the history was changed outside the journal", entry.Op)
+		}
+		historyLines = historyLines[:tail]
+	} else {
+		historyLines = append(historyLines, entry.History...)
+	}
+
+	updated := slices.Concat(
+		queueLines[:entry.Offset],
+		replacement,
+		queueLines[len(queueLines)-entry.Suffix:],
+	)
+
+	// Restore the queue before trimming history on undo, and extend history
+	// before the queue on redo, so an album is never missing from both
+	if undo {
+		if err := qs.storage.WriteLines(updated); err != nil {
+			return JournalEntry{}, fmt.Errorf("failed to save updated queue: %w", err)
+		}
+		if len(entry.History) > 0 {
+			if err := qs.archive.WriteLines(historyLines); err != nil {
+				return JournalEntry{}, fmt.Errorf("failed to save history: %w", err)
+			}
+		}
+	} else {
+		if len(entry.History) > 0 {
+			if err := qs.archive.AppendLines(entry.History...); err != nil {
+				return JournalEntry{}, fmt.Errorf("failed to save history: %w", err)
+			}
+		}
+		if err := qs.storage.WriteLines(updated); err != nil {
+			return JournalEntry{}, fmt.Errorf("failed to save updated queue: %w", err)
+		}
+	}
+
+	if entry.State != "" {
+		state := entry.StateAfter
+		if undo {
+			state = entry.StateBefore
+		}
+		if err := qs.storage.Sibling(entry.State).WriteLines(state); err != nil {
+			return JournalEntry{}, fmt.Errorf("failed to save %s: %w", entry.State, err)
+		}
+	}
+
+	if err := writeJournal(from, entries[:len(entries)-1]); err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to update undo journal: %w", err)
+	}
+
+	if err := appendJournal(to, entry); err != nil {
+		return JournalEntry{}, fmt.Errorf("failed to update undo journal: %w", err)
+	}
+
+	return entry, nil
+}
+
+// replayVerb names the direction of a replay for error messages
+func replayVerb(undo bool) string {
+	if undo {
+		return "undo"
+	}
+	return "redo"
+}
+
+// regionMatches reports whether lines consist of offset lines, then expected,
+// then suffix lines
+func regionMatches(lines []string, offset, suffix int, expected []string) bool {
+	if offset+len(expected)+suffix != len(lines) {
+		return false
+	}
+	return slices.Equal(lines[offset:offset+len(expected)], expected)
+}
+
+// pushJournal records a new operation and clears the redo journal
+func (qs *QueueService) pushJournal(entry JournalEntry) error {
+	if err := appendJournal(qs.undoStore(), entry); err != nil {
+		return err
+	}
+
+	redo, err := qs.redoStore().ReadLines()
+	if err != nil {
+		return err
+	}
+
+	if len(redo) > 0 {
+		return qs.redoStore().WriteLines(nil)
+	}
+
+	return nil
+}
+
+// undoStore returns the store holding operations that can be undone
+func (qs *QueueService) undoStore() storage.Store {
+	return qs.storage.Sibling("undo")
+}
+
+// redoStore returns the store holding undone operations that can be redone
+func (qs *QueueService) redoStore() storage.Store {
+	return qs.storage.Sibling("redo")
+}
+
+// readJournal decodes the entries in a journal store, oldest first
+func readJournal(store storage.Store) ([]JournalEntry, error) {
+	lines, err := store.ReadLines()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read undo journal: %w", err)
+	}
+
+	entries := make([]JournalEntry, 0, len(lines))
+	for i, line := range lines {
+		var entry JournalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read undo journal: line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// writeJournal replaces the entries in a journal store
+func writeJournal(store storage.Store, entries []JournalEntry) error {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(line))
+	}
+
+	return store.WriteLines(lines)
+}
+
+// appendJournal adds an entry to a journal store, dropping the oldest entries
+// beyond MaxUndo
+func appendJournal(store storage.Store, entry JournalEntry) error {
+	entries, err := readJournal(store)
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, entry)
+	if len(entries) > MaxUndo {
+		entries = entries[len(entries)-MaxUndo:]
+	}
+
+	return writeJournal(store, entries)
+}
@@ -0,0 +1,301 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestNewJournalEntry_StoresChangedRegion(t *testing.T) {
+	tests := []struct {
+		name           string
+		before         []string
+		after          []string
+		expectedOffset int
+		expectedSuffix int
+		expectedBefore []string
+		expectedAfter  []string
+	}{
+		{"append", []string{"a", "b"}, []string{"a", "b", "c"}, 2, 0, nil, []string{"c"}},
+		{"remove middle", []string{"a", "b", "c"}, []string{"a", "c"}, 1, 1, []string{"b"}, nil},
+		{"replace first", []string{"a", "b"}, []string{"x", "b"}, 0, 1, []string{"a"}, []string{"x"}},
+		{"repeated lines", []string{"a", "a"}, []string{"a"}, 1, 0, []string{"a"}, nil},
+		{"empty to one", nil, []string{"a"}, 0, 0, nil, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := newJournalEntry(OpAdd, "", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), tt.before, tt.after)
+
+			if entry.Offset != tt.expectedOffset || entry.Suffix != tt.expectedSuffix {
+				t.Errorf("Expected offset %d suffix %d, got offset %d suffix %d", tt.expectedOffset, tt.expectedSuffix, entry.Offset, entry.Suffix)
+			}
+
+			if strings.Join(entry.Before, ",") != strings.Join(tt.expectedBefore, ",") {
+				t.Errorf("Expected before %v, got %v", tt.expectedBefore, entry.Before)
+			}
+
+			if strings.Join(entry.After, ",") != strings.Join(tt.expectedAfter, ",") {
+				t.Errorf("Expected after %v, got %v", tt.expectedAfter, entry.After)
+			}
+
+			// Applying the region to before must give after
+			if !regionMatches(tt.before, entry.Offset, entry.Suffix, entry.Before) {
+				t.Error("Before region does not match the original queue")
+			}
+			if !regionMatches(tt.after, entry.Offset, entry.Suffix, entry.After) {
+				t.Error("After region does not match the updated queue")
+			}
+		})
+	}
+}
+
+func TestQueueService_Undo_Next(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	for _, album := range []string{"Artist 1 - Album 1", "Artist 2 - Album 2", "Artist 3 - Album 3"} {
+		if err := queue.AddAlbum(album); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queueBefore, _ := memoryStorage.ReadLines()
+
+	selected, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := queue.Undo()
+	if err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+
+	if entry.Op != OpNext || entry.Description != selected.String() {
+		t.Errorf("Expected undone next of %q, got %+v", selected, entry)
+	}
+
+	// Queue is restored exactly, including position and metadata
+	queueAfter, _ := memoryStorage.ReadLines()
+	if strings.Join(queueAfter, "\n") != strings.Join(queueBefore, "\n") {
+		t.Errorf("Expected queue to be restored.\n got  %q\n want %q", queueAfter, queueBefore)
+	}
+
+	// History entry is removed
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected empty history after undo, got %+v", history)
+	}
+}
+
+func TestQueueService_Undo_MultiLevelAndRedo(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	if err := queue.AddAlbum("Artist 1 - Album 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	importSource := storage.NewMemoryStorage("import")
+	importSource.WriteLines([]string{"Artist 2 - Album 2", "Artist 3 - Album 3"})
+	if _, _, _, err := queue.ImportFrom(importSource); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := queue.GetNextAlbum(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Undo next, import and add in reverse order
+	for _, expectedOp := range []string{OpNext, OpImport, OpAdd} {
+		entry, err := queue.Undo()
+		if err != nil {
+			t.Fatalf("Undo returned error: %v", err)
+		}
+		if entry.Op != expectedOp {
+			t.Errorf("Expected to undo %s, got %s", expectedOp, entry.Op)
+		}
+	}
+
+	if count, _ := queue.CountAlbums(); count != 0 {
+		t.Errorf("Expected empty queue after undoing everything, got %d albums", count)
+	}
+
+	if _, err := queue.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+
+	// Redo add and import
+	for _, expectedOp := range []string{OpAdd, OpImport} {
+		entry, err := queue.Redo()
+		if err != nil {
+			t.Fatalf("Redo returned error: %v", err)
+		}
+		if entry.Op != expectedOp {
+			t.Errorf("Expected to redo %s, got %s", expectedOp, entry.Op)
+		}
+	}
+
+	if count, _ := queue.CountAlbums(); count != 3 {
+		t.Errorf("Expected 3 albums after redo, got %d", count)
+	}
+
+	// A new operation clears what is left to redo
+	if err := queue.AddAlbum("Artist 4 - Album 4"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := queue.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo after a new operation, got %v", err)
+	}
+}
+
+func TestQueueService_Redo_Next(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	if err := queue.AddAlbum("Artist 1 - Album 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := queue.GetNextAlbum(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := queue.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := queue.Redo(); err != nil {
+		t.Fatalf("Redo returned error: %v", err)
+	}
+
+	if count, _ := queue.CountAlbums(); count != 0 {
+		t.Errorf("Expected album to be taken again, got %d albums in queue", count)
+	}
+
+	history, _ := queue.History(time.Time{}, time.Time{})
+	if len(history) != 1 || history[0].Album.String() != "Artist 1 - Album 1" {
+		t.Errorf("Expected history entry to be restored, got %+v", history)
+	}
+}
+
+func TestQueueService_Undo_ShuffleNext(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"A - One", "B - Two", "C - Three", "D - Four"})
+	queue := NewQueue(memoryStorage, WithSelector(ShuffleBagSelector{Queue: memoryStorage}))
+
+	// Deal the bag with a first pick
+	if _, err := queue.GetNextAlbum(); err != nil {
+		t.Fatal(err)
+	}
+
+	picked, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, _ := memoryStorage.Sibling("bag").ReadLines()
+
+	if _, err := queue.Undo(); err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+
+	// The bag is put back with the album, so next picks it again
+	again, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Key() != picked.Key() {
+		t.Errorf("Expected %s again after undo, got %s", picked, again)
+	}
+
+	if _, err := queue.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queue.Redo(); err != nil {
+		t.Fatalf("Redo returned error: %v", err)
+	}
+	if redone, _ := memoryStorage.Sibling("bag").ReadLines(); !slices.Equal(redone, bag) {
+		t.Errorf("Expected redo to restore the bag %v, got %v", bag, redone)
+	}
+}
+
+func TestQueueService_Undo_ExternalChange(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	if err := queue.AddAlbum("Artist 1 - Album 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hand-edit the queue behind the service's back
+	memoryStorage.WriteLines([]string{"Someone Else - Entirely"})
+
+	_, err := queue.Undo()
+	if err == nil || !strings.Contains(err.Error(), "changed outside the journal") {
+		t.Fatalf("Expected external change error, got %v", err)
+	}
+
+	// Nothing was touched
+	lines, _ := memoryStorage.ReadLines()
+	if len(lines) != 1 || lines[0] != "Someone Else - Entirely" {
+		t.Errorf("Expected hand-edited queue to be left alone, got %v", lines)
+	}
+}
+
+func TestQueueService_NoChangeIsNotJournaled(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	if err := queue.AddAlbum("Artist 1 - Album 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed add and import of only duplicates change nothing
+	queue.AddAlbum("Artist 1 - Album 1")
+	importSource := storage.NewMemoryStorage("import")
+	importSource.WriteLines([]string{"artist 1 - album 1"})
+	queue.ImportFrom(importSource)
+
+	entries, err := readJournal(memoryStorage.Sibling("undo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("Expected only the successful add to be journaled, got %d entries", len(entries))
+	}
+}
+
+func TestQueueService_JournalIsBounded(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	for i := 0; i < MaxUndo+5; i++ {
+		if err := queue.AddAlbum(fmt.Sprintf("Artist %d - Album %d", i, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := readJournal(memoryStorage.Sibling("undo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != MaxUndo {
+		t.Errorf("Expected journal to keep %d entries, got %d", MaxUndo, len(entries))
+	}
+
+	if entries[0].Description != "Artist 5 - Album 5" {
+		t.Errorf("Expected oldest entries to be dropped first, got %q", entries[0].Description)
+	}
+}
@@ -0,0 +1,230 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NearDuplicateError is returned when an album is not an exact duplicate but
+// resembles one already in the queue at least as closely as the similarity
+// threshold (see WithSimilarity). Callers may ask the user to confirm and add
+// the album without the threshold.
+type NearDuplicateError struct {
+	Album      Album
+	Match      Album   // the album already in the queue
+	Similarity float64 // between 0 and 1
+}
+
+// Error implements error
+func (e *NearDuplicateError) Error() string {
+	return fmt.Sprintf("album '%s' looks like '%s' already in the queue (%.0f%% similar)", e.Album, e.Match, e.Similarity*100)
+}
+
+// editionWords mark a parenthesized or dash-separated title suffix as naming
+// an edition of the album rather than a different album. Words that also
+// name re-recordings or other albums, such as "version", are left out.
+var editionWords = []string{
+	"remaster", "remastered", "deluxe", "expanded", "edition", "anniversary",
+	"bonus", "reissue", "mono", "stereo",
+}
+
+// leadingArticles are dropped from the start of artist names
+var leadingArticles = []string{"the", "a", "an"}
+
+// MatchKey returns the value used for duplicate detection. Unlike Key, it
+// ignores differences that do not make a different album: Unicode NFKC
+// normalization (composed accents, full-width letters, ligatures), dash and quote
+// variants, a leading article in the artist, punctuation and spacing, and
+// edition suffixes such as "(Remastered)" or "- Deluxe Edition".
+func (a Album) MatchKey() string {
+	return normalizeArtist(a.Artist) + " - " + normalizeTitle(a.Title)
+}
+
+// normalizeArtist folds an artist name and drops a leading article, also
+// when written after a comma ("Beatles, The")
+func normalizeArtist(artist string) string {
+	artist = strings.TrimSpace(strings.ToLower(foldCompatibility(artist)))
+	for _, article := range leadingArticles {
+		artist = strings.TrimSuffix(artist, ", "+article)
+	}
+
+	words := strings.Fields(normalizeText(artist))
+	if len(words) > 1 && slices.Contains(leadingArticles, words[0]) {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeTitle folds an album title and drops edition suffixes
+func normalizeTitle(title string) string {
+	title = strings.ToLower(foldCompatibility(title))
+
+	for {
+		trimmed := strings.TrimSpace(title)
+		stripped := stripEditionSuffix(trimmed)
+		if stripped == trimmed || strings.TrimSpace(stripped) == "" {
+			break
+		}
+		title = stripped
+	}
+
+	return normalizeText(title)
+}
+
+// stripEditionSuffix removes one trailing "(...)" or "[...]" group, or
+// " - ..." part, that names an edition. Other titles are returned unchanged.
+func stripEditionSuffix(title string) string {
+	var suffix string
+	var rest string
+	switch {
+	case strings.HasSuffix(title, ")") || strings.HasSuffix(title, "]"):
+		open := strings.LastIndexAny(title, "([")
+		if open == -1 {
+			return title
+		}
+		rest, suffix = title[:open], title[open:]
+	default:
+		separator := strings.LastIndex(title, " - ")
+		if separator == -1 {
+			return title
+		}
+		rest, suffix = title[:separator], title[separator:]
+	}
+
+	for _, word := range strings.Fields(normalizeText(suffix)) {
+		if slices.Contains(editionWords, word) {
+			return rest
+		}
+	}
+	return title
+}
+
+// normalizeText folds text for comparison: compatibility characters are
+// replaced, letters lowercased, "&" spelled "and", apostrophes dropped and
+// any other run of punctuation or spaces collapsed to a single space.
+// Combining marks stay with the letter before them.
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(strings.ToLower(foldCompatibility(text)), "&", " and ")
+
+	var builder strings.Builder
+	space := false
+	for _, r := range text {
+		switch {
+		case unicode.IsMark(r) && builder.Len() > 0 && !space:
+			builder.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if space && builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			space = false
+			builder.WriteRune(r)
+		case r == '\'':
+			// "Don't" and "Dont" are the same title
+		default:
+			space = true
+		}
+	}
+	return builder.String()
+}
+
+// punctuationRunes maps the dash and quote variants that NFKC normalization
+// keeps to their ASCII forms
+var punctuationRunes = map[rune]rune{
+	'\u2010': '-', '\u2011': '-', '\u2012': '-', '\u2013': '-', '\u2014': '-',
+	'\u2015': '-', '\u2212': '-', '\ufe58': '-',
+	'\u2018': '\'', '\u2019': '\'', '\u02bc': '\'', '\u00b4': '\'',
+	'\u201c': '"', '\u201d': '"',
+}
+
+// foldCompatibility applies Unicode NFKC normalization, which composes
+// accented letters and replaces compatibility characters (full-width forms,
+// ligatures, special spaces, "㎏"), and punctuationRunes. The punctuation is
+// mapped first too, as NFKC splits "´" into a space and a combining accent.
+func foldCompatibility(text string) string {
+	punctuation := func(r rune) rune {
+		if replacement, found := punctuationRunes[r]; found {
+			return replacement
+		}
+		return r
+	}
+	return strings.Map(punctuation, norm.NFKC.String(strings.Map(punctuation, text)))
+}
+
+// Similarity returns how alike the match keys of two albums are, from 0
+// (nothing in common) to 1 (duplicates), based on their edit distance
+func Similarity(a, b Album) float64 {
+	return keySimilarity([]rune(a.MatchKey()), []rune(b.MatchKey()))
+}
+
+// keySimilarity is Similarity for match keys
+func keySimilarity(a, b []rune) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// nearestAlbum returns the index of the album whose match key, among keys,
+// is most similar to key, and that similarity, if it reaches threshold.
+// Keys too different in length to reach the threshold are not compared.
+func nearestAlbum(key string, keys []string, threshold float64) (int, float64, bool) {
+	target := []rune(key)
+	nearest, best := -1, 0.0
+	for i, candidate := range keys {
+		runes := []rune(candidate)
+		longest := max(len(target), len(runes))
+		if longest > 0 && 1-float64(abs(len(target)-len(runes)))/float64(longest) < max(threshold, best) {
+			continue
+		}
+
+		if score := keySimilarity(target, runes); nearest == -1 || score > best {
+			nearest, best = i, score
+		}
+	}
+	return nearest, best, nearest != -1 && best >= threshold
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// matchKeys returns the match key of each album
+func matchKeys(albums []Album) []string {
+	keys := make([]string, len(albums))
+	for i, album := range albums {
+		keys[i] = album.MatchKey()
+	}
+	return keys
+}
@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestAlbum_MatchKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		equal bool
+	}{
+		{"case", "The Beatles - Abbey Road", "THE BEATLES - ABBEY ROAD", true},
+		{"leading article", "The Beatles - Abbey Road", "Beatles - Abbey Road", true},
+		{"trailing article", "Beatles, The - Abbey Road", "The Beatles - Abbey Road", true},
+		{"en dash separator", "The Beatles – Abbey Road", "The Beatles - Abbey Road", true},
+		{"dash inside title", "Artist - Side—B", "Artist - Side-B", true},
+		{"edition in parentheses", "The Beatles - Abbey Road (Remastered)", "The Beatles - Abbey Road", true},
+		{"edition in brackets", "The Beatles - Abbey Road [2019 Super Deluxe Edition]", "The Beatles - Abbey Road", true},
+		{"edition after dash", "Pink Floyd - Wish You Were Here - 2011 Remaster", "Pink Floyd - Wish You Were Here", true},
+		{"several editions", "Artist - Album (Deluxe) [Remastered]", "Artist - Album", true},
+		{"punctuation and spacing", "Guns N' Roses - Appetite  for Destruction", "Guns N Roses - Appetite for Destruction!", true},
+		{"ampersand", "Simon & Garfunkel - Bookends", "Simon and Garfunkel - Bookends", true},
+		{"full-width letters", "Ｒａｄｉｏｈｅａｄ - Kid A", "Radiohead - Kid A", true},
+		{"ligature", "Artist - ﬁrst", "Artist - first", true},
+		{"curly apostrophe", "Artist - Don’t Stop", "Artist - Dont Stop", true},
+		{"acute accent apostrophe", "Artist - Don´t Stop", "Artist - Dont Stop", true},
+		{"combining accent", "Sigur Ro\u0301s - Takk", "Sigur Rós - Takk", true},
+		{"accents are kept", "Sigur Rós - Takk", "Sigur Ros - Takk", false},
+		{"compatibility unit", "Artist - 5㎏", "Artist - 5kg", true},
+		{"fraction kept", "Artist - 8½", "Artist - 8", false},
+		{"non-breaking space", "Artist - Kid\u00a0A", "Artist - Kid A", true},
+		{"re-recording is different", "Taylor Swift - Fearless (Taylor's Version)", "Taylor Swift - Fearless", false},
+		{"bonus track version", "Artist - Album (Bonus Track Version)", "Artist - Album", true},
+		{"live album is different", "Nirvana - Nevermind (Live)", "Nirvana - Nevermind", false},
+		{"article-only artist kept", "The The - Soul Mining", "The - Soul Mining", true},
+		{"different title", "The Beatles - Revolver", "The Beatles - Abbey Road", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAlbum(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseAlbum(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if equal := a.MatchKey() == b.MatchKey(); equal != tt.equal {
+				t.Errorf("Expected match keys equal=%v, got %q and %q", tt.equal, a.MatchKey(), b.MatchKey())
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	abbeyRoad := Album{Artist: "The Beatles", Title: "Abbey Road"}
+
+	if got := Similarity(abbeyRoad, Album{Artist: "Beatles", Title: "Abbey Road (Remastered)"}); got != 1 {
+		t.Errorf("Expected normalized duplicates to be fully similar, got %g", got)
+	}
+
+	typo := Similarity(abbeyRoad, Album{Artist: "Beatles", Title: "Abey Road"})
+	if math.Abs(typo-(1-1.0/float64(len("beatles - abbey road")))) > 1e-9 {
+		t.Errorf("Expected one edit of difference, got %g", typo)
+	}
+
+	if got := Similarity(abbeyRoad, Album{Artist: "Miles Davis", Title: "Kind of Blue"}); got > 0.5 {
+		t.Errorf("Expected unrelated albums to be dissimilar, got %g", got)
+	}
+}
+
+func TestQueueService_Add_NormalizedDuplicates(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"The Beatles - Abbey Road"})
+	queue := NewQueue(memoryStorage)
+
+	err := queue.AddAlbum("Beatles – Abbey Road (Remastered)")
+	if err == nil || !strings.Contains(err.Error(), "already exists as 'The Beatles - Abbey Road'") {
+		t.Errorf("Expected normalized duplicate error, got %v", err)
+	}
+
+	// Without a threshold, near duplicates are added
+	if err := queue.AddAlbum("Beatles - Abey Road"); err != nil {
+		t.Errorf("Expected near duplicate to be added without a threshold, got %v", err)
+	}
+}
+
+func TestQueueService_Add_NearDuplicate(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"The Beatles - Abbey Road", "Miles Davis - Kind of Blue"})
+	queue := NewQueue(memoryStorage, WithSimilarity(0.9))
+
+	err := queue.AddAlbum("Beatles - Abey Road")
+	var nearDuplicate *NearDuplicateError
+	if !errors.As(err, &nearDuplicate) {
+		t.Fatalf("Expected NearDuplicateError, got %v", err)
+	}
+	if nearDuplicate.Match.Title != "Abbey Road" || nearDuplicate.Similarity < 0.9 {
+		t.Errorf("Unexpected near duplicate %+v", nearDuplicate)
+	}
+
+	if err := queue.AddAlbum("Miles Davis - Sketches of Spain"); err != nil {
+		t.Errorf("Expected dissimilar album to be added, got %v", err)
+	}
+}
+
+func TestQueueService_ImportFrom_NormalizedDuplicates(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"The Beatles - Abbey Road"})
+	queue := NewQueue(memoryStorage, WithSimilarity(0.9))
+
+	source := storage.NewMemoryStorage("import")
+	source.WriteLines([]string{
+		"Beatles - Abbey Road",
+		"The Beatles – Abbey Road (Remastered)",
+		"Beatles - Abey Road",
+		"Pink Floyd - The Wall",
+		"Pink Floyd - The Wall (Deluxe Edition)",
+	})
+
+	added, duplicates, formatErrors, err := queue.ImportFrom(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 || duplicates != 4 || formatErrors != 0 {
+		t.Errorf("Expected 1 added, 4 duplicates, 0 format errors; got %d, %d, %d", added, duplicates, formatErrors)
+	}
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DefaultLockTimeout is how long mutating operations wait for another process
+// to release the queue lock before giving up
+const DefaultLockTimeout = 10 * time.Second
+
+// Option configures optional QueueService behavior
+type Option func(*QueueService)
+
+// WithLockTimeout sets how long mutating operations wait for the queue lock
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(qs *QueueService) {
+		qs.lockTimeout = timeout
+	}
+}
+
+// WithRules sets the selection rules GetNextAlbum applies before picking,
+// e.g. an ArtistDiversityRule
+func WithRules(rules ...SelectionRule) Option {
+	return func(qs *QueueService) {
+		qs.rules = append(qs.rules, rules...)
+	}
+}
+
+// WithTagFilter limits the albums GetNextAlbum may pick to those allowed by
+// filter. Unlike selection rules, the filter is never relaxed: when no album
+// passes it, nothing is picked. Tags are normalized by NormalizeTags.
+func WithTagFilter(filter TagFilter) Option {
+	return func(qs *QueueService) {
+		qs.tagFilter = TagFilter{Include: NormalizeTags(filter.Include...), Exclude: NormalizeTags(filter.Exclude...)}
+	}
+}
+
+// WithSimilarity makes Add and ImportFrom treat an album as a near duplicate
+// when its similarity (see Similarity) to an album already in the queue is at
+// least threshold, between 0 and 1. Zero, the default, only rejects albums
+// with the same match key.
+func WithSimilarity(threshold float64) Option {
+	return func(qs *QueueService) {
+		qs.similarity = threshold
+	}
+}
+
+// WithSelector sets the strategy GetNextAlbum uses to choose among the
+// albums allowed by the selection rules
+func WithSelector(selector Selector) Option {
+	return func(qs *QueueService) {
+		qs.selector = selector
+	}
+}
+
+// WithRandSource sets the source the seed of each pick is drawn from. By
+// default it is seeded from the current time.
+func WithRandSource(source rand.Source) Option {
+	return func(qs *QueueService) {
+		qs.nextSeed = source.Int63
+	}
+}
+
+// WithSeed seeds every pick with seed, making selection reproducible: a pick
+// made with the seed recorded in its history entry, from the same queue and
+// history, chooses the same album
+func WithSeed(seed int64) Option {
+	return func(qs *QueueService) {
+		qs.nextSeed = func() int64 { return seed }
+	}
+}
@@ -0,0 +1,104 @@
+package queue
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestNewQueue_DefaultOptions(t *testing.T) {
+	queue := NewQueue(storage.NewMemoryStorage("queue"))
+
+	if queue.lockTimeout != DefaultLockTimeout {
+		t.Errorf("Expected default lock timeout %s, got %s", DefaultLockTimeout, queue.lockTimeout)
+	}
+}
+
+func TestWithLockTimeout(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage, WithLockTimeout(20*time.Millisecond))
+
+	if queue.lockTimeout != 20*time.Millisecond {
+		t.Errorf("Expected lock timeout 20ms, got %s", queue.lockTimeout)
+	}
+
+	// Hold the lock as another process would
+	unlock, err := memoryStorage.Lock(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	err = queue.AddAlbum("Artist - Album")
+	var lockedErr *storage.LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected *storage.LockedError, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "queue is locked by another process (waited 20ms)") {
+		t.Errorf("Expected 'queue is locked by another process' message, got: %v", err)
+	}
+}
+
+// seededQueue returns a queue service over a fresh in-memory queue of albums
+func seededQueue(albums []string, opts ...Option) *QueueService {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines(albums)
+	return NewQueue(memoryStorage, opts...)
+}
+
+func TestWithRandSource_Reproducible(t *testing.T) {
+	albums := []string{"A - One", "B - Two", "C - Three", "D - Four", "E - Five"}
+
+	pickAll := func(queue *QueueService) []string {
+		var picks []string
+		for range albums {
+			album, err := queue.GetNextAlbum()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			picks = append(picks, album.String())
+		}
+		return picks
+	}
+
+	first := pickAll(seededQueue(albums, WithRandSource(rand.NewSource(7))))
+	second := pickAll(seededQueue(albums, WithRandSource(rand.NewSource(7))))
+
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		t.Errorf("Expected the same source to give the same picks, got %v and %v", first, second)
+	}
+}
+
+func TestWithSeed_ReplaysRecordedPick(t *testing.T) {
+	albums := []string{"A - One", "B - Two", "C - Three", "D - Four", "E - Five"}
+
+	original := seededQueue(albums)
+	picked, err := original.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := original.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history[0].Seed == nil {
+		t.Fatal("Expected the pick's seed to be recorded in history")
+	}
+
+	// The same seed on the same queue picks the same album, every time
+	for i := 0; i < 3; i++ {
+		replayed, err := seededQueue(albums, WithSeed(*history[0].Seed)).GetNextAlbum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replayed.Key() != picked.Key() {
+			t.Errorf("Replay with seed %d picked %s, original pick was %s", *history[0].Seed, replayed, picked)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Move puts the album identified by ref (see FindAlbum) at the given
+// 1-based position in the queue, shifting the albums in between, and returns
+// it
+func (qs *QueueService) Move(ref string, position int) (Album, error) {
+	var moved Album
+	err := qs.mutate(OpMove, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+		if position < 1 || position > len(existingAlbums) {
+			return mutation{}, fmt.Errorf("invalid position %d (queue has %d albums)", position, len(existingAlbums))
+		}
+
+		moved = existingAlbums[index]
+		albums := moveAlbum(existingAlbums, index, position-1)
+
+		return mutation{
+			albums:      albums,
+			description: fmt.Sprintf("%s to position %d", moved, position),
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return moved, nil
+}
+
+// Pin marks the album identified by ref (see FindAlbum) to be picked next
+// regardless of the selection mode and moves it to the top of the queue, so
+// the most recently pinned album comes first. Pinning also ends a snooze.
+// With pinned false the mark is removed and the album stays where it is.
+func (qs *QueueService) Pin(ref string, pinned bool) (Album, error) {
+	var updated Album
+	err := qs.mutate(OpPin, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+
+		albums := append([]Album(nil), existingAlbums...)
+		albums[index].Pinned = pinned
+		description := fmt.Sprintf("%s (unpinned)", albums[index])
+		if pinned {
+			albums[index].SnoozedUntil = time.Time{}
+			albums = moveAlbum(albums, index, 0)
+			index = 0
+			description = fmt.Sprintf("%s (pinned)", albums[index])
+		}
+		updated = albums[index]
+
+		return mutation{
+			albums:      albums,
+			description: description,
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return updated, nil
+}
+
+// Play takes the album identified by ref (see FindAlbum) out of the queue
+// and records it in the history as a manual pick, for when you already know
+// what you want to hear. Snoozed albums can be played too.
+func (qs *QueueService) Play(ref string) (Album, error) {
+	var played Album
+	err := qs.mutate(OpPlay, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+
+		var change mutation
+		played, change, err = qs.take(existingAlbums, Pick{Album: existingAlbums[index], Method: MethodManual})
+		return change, err
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return played, nil
+}
+
+// moveAlbum returns a copy of albums with the album at from moved to index to
+func moveAlbum(albums []Album, from, to int) []Album {
+	album := albums[from]
+	moved := slices.Delete(slices.Clone(albums), from, from+1)
+	return slices.Insert(moved, to, album)
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_Move(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"A - One", "B - Two", "C - Three", "D - Four"})
+	queue := NewQueue(memoryStorage)
+
+	if _, err := queue.Move("four", 2); err != nil {
+		t.Fatalf("Move returned error: %v", err)
+	}
+	if _, err := queue.Move("1", 3); err != nil {
+		t.Fatalf("Move returned error: %v", err)
+	}
+
+	albums, err := readAlbumTexts(memoryStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(albums, ", "); got != "D - Four, B - Two, A - One, C - Three" {
+		t.Errorf("Unexpected order after moves: %s", got)
+	}
+
+	if _, err := queue.Move("1", 5); err == nil || !strings.Contains(err.Error(), "invalid position 5") {
+		t.Errorf("Expected position error, got %v", err)
+	}
+}
+
+func TestQueueService_Pin(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"A - One", "B - Two", "C - Three"})
+	queue := NewQueue(memoryStorage, WithSelector(LIFOSelector{}))
+
+	if _, err := queue.Snooze("two", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := queue.Pin("two", true)
+	if err != nil {
+		t.Fatalf("Pin returned error: %v", err)
+	}
+	if !pinned.Pinned || !pinned.SnoozedUntil.IsZero() {
+		t.Errorf("Expected pinned, awake album, got %+v", pinned)
+	}
+
+	albums, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if albums[0].Title != "Two" {
+		t.Errorf("Expected pinned album at the top, got %v", albums)
+	}
+
+	// The pinned album wins over the selector, then selection resumes
+	album, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Two" {
+		t.Errorf("Expected pinned album to be picked, got %s", album)
+	}
+	album, err = queue.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Three" {
+		t.Errorf("Expected the selector to pick after the pin, got %s", album)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history[0].Method != MethodPinned || history[1].Method != ModeLIFO {
+		t.Errorf("Unexpected pick methods: %+v", history)
+	}
+	if history[0].Seed != nil || history[1].Seed == nil {
+		t.Errorf("Expected a seed for the selector's pick only: %+v", history)
+	}
+
+	// Unpinning keeps the position
+	if _, err := queue.Pin("one", true); err != nil {
+		t.Fatal(err)
+	}
+	unpinned, err := queue.Pin("one", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unpinned.Pinned {
+		t.Error("Expected album to be unpinned")
+	}
+	pick, err := queue.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pick.Method == MethodPinned {
+		t.Errorf("Expected no pinned pick after unpinning, got %+v", pick)
+	}
+}
+
+func TestQueueService_Play(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"A - One", "B - Two", "C - Three"})
+	queue := NewQueue(memoryStorage)
+
+	played, err := queue.Play("2")
+	if err != nil {
+		t.Fatalf("Play returned error: %v", err)
+	}
+	if played.String() != "B - Two" {
+		t.Errorf("Expected B - Two, got %s", played)
+	}
+
+	if count, _ := queue.CountAlbums(); count != 2 {
+		t.Errorf("Expected 2 albums left, got %d", count)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Method != MethodManual || history[0].Seed != nil {
+		t.Errorf("Expected a manual pick in history, got %+v", history)
+	}
+
+	if _, err := queue.Play("nothing"); err == nil {
+		t.Error("Expected error for unknown album")
+	}
+
+	entry, err := queue.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Op != OpPlay {
+		t.Errorf("Expected to undo play, got %s", entry.Op)
+	}
+}
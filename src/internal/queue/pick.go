@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrNoMoreSuggestions is returned by Suggest when every album in the queue
+// has been excluded
+var ErrNoMoreSuggestions = errors.New("no more albums to suggest")
+
+// Pick is an album chosen by the selector but not yet taken from the queue
+type Pick struct {
+	Album  Album
+	Method string // name of the selector that chose the album
+	Seed   *int64 // seed of the random source the choice was made with; nil for a pinned album
+
+	state *SelectorState // state of a StatefulSelector to save when the album is taken
+}
+
+// Suggest chooses the album GetNextAlbum would pick, without changing the
+// queue, the history or any selector state. Albums in exclude are never
+// suggested, which lets a caller reject a suggestion and ask for another
+// ("reroll"); once every album is excluded the error is ErrNoMoreSuggestions.
+// Pass the result to Accept to take the album.
+func (qs *QueueService) Suggest(exclude ...Album) (Pick, error) {
+	picks, err := qs.suggestions(1, exclude)
+	if err != nil {
+		return Pick{}, err
+	}
+	return picks[0], nil
+}
+
+// Peek returns up to n distinct suggestions: the album GetNextAlbum would
+// pick, then the one a reroll would give, and so on. Nothing is changed.
+func (qs *QueueService) Peek(n int) ([]Pick, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of suggestions %d: must be at least 1", n)
+	}
+
+	picks, err := qs.suggestions(n, nil)
+	if errors.Is(err, ErrNoMoreSuggestions) && len(picks) > 0 {
+		return picks, nil
+	}
+	return picks, err
+}
+
+// suggestions chooses up to n albums one after another, excluding each
+// suggestion from the following choices. On error the suggestions made so
+// far are returned with it.
+func (qs *QueueService) suggestions(n int, exclude []Album) ([]Pick, error) {
+	albums, err := qs.readQueue()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	history, err := qs.History(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := albumKeys(exclude)
+	picks := make([]Pick, 0, n)
+	for len(picks) < n {
+		pick, err := qs.choose(albums, history, excluded, qs.selector)
+		if err != nil {
+			return picks, err
+		}
+
+		picks = append(picks, pick)
+		excluded[pick.Album.Key()] = true
+	}
+
+	return picks, nil
+}
+
+// Accept takes a suggested album from the queue and records it in the
+// history with the pick's method and seed, like GetNextAlbum. It fails if
+// the album has left the queue since it was suggested.
+func (qs *QueueService) Accept(pick Pick) (Album, error) {
+	var selectedAlbum Album
+	err := qs.mutate(OpNext, func(existingAlbums []Album) (mutation, error) {
+		var change mutation
+		var err error
+		selectedAlbum, change, err = qs.take(existingAlbums, pick)
+		return change, err
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return selectedAlbum, nil
+}
+
+// choose applies the selection rules and selector to the albums that pass
+// the tag filter and are neither excluded nor snoozed, using a freshly seeded
+// random source. Rules and selectors see the picks in history that were not
+// skipped. A pinned album bypasses both: the first one in the queue is chosen
+// outright.
+func (qs *QueueService) choose(albums []Album, history []HistoryEntry, excluded map[string]bool, selector Selector) (Pick, error) {
+	// Check if queue is empty
+	if len(albums) == 0 {
+		return Pick{}, fmt.Errorf("the queue is empty")
+	}
+
+	now := qs.now()
+	var available, snoozed []Album
+	tagged := 0
+	for _, album := range albums {
+		if !qs.tagFilter.Allows(album) {
+			continue
+		}
+		tagged++
+		if excluded[album.Key()] {
+			continue
+		}
+		if album.Snoozed(now) {
+			snoozed = append(snoozed, album)
+			continue
+		}
+		available = append(available, album)
+	}
+	if tagged == 0 {
+		return Pick{}, fmt.Errorf("no album in the queue matches the tags %s", qs.tagFilter)
+	}
+	if len(available) == 0 {
+		if len(snoozed) > 0 {
+			return Pick{}, fmt.Errorf("every album left in the queue is snoozed (the first wakes up %s)", firstWake(snoozed).Local().Format("2006-01-02 15:04"))
+		}
+		return Pick{}, ErrNoMoreSuggestions
+	}
+
+	for _, album := range available {
+		if album.Pinned {
+			return Pick{Album: album, Method: MethodPinned}, nil
+		}
+	}
+
+	history = plays(history)
+	seed := qs.nextSeed()
+	ctx := SelectionContext{
+		Queue:   albums,
+		History: history,
+		Now:     now,
+		Rand:    rand.New(rand.NewSource(seed)),
+	}
+
+	eligible := applyRules(qs.rules, available, history, ctx.Now)
+	candidates := make([]Album, len(eligible))
+	for i, index := range eligible {
+		candidates[i] = available[index]
+	}
+
+	var choice int
+	var state *SelectorState
+	var err error
+	if stateful, ok := selector.(StatefulSelector); ok {
+		var selectorState SelectorState
+		choice, selectorState, err = stateful.SelectState(candidates, ctx)
+		state = &selectorState
+	} else {
+		choice, err = selector.Select(candidates, ctx)
+	}
+	if err != nil {
+		return Pick{}, err
+	}
+	if choice < 0 || choice >= len(candidates) {
+		return Pick{}, fmt.Errorf("%s selector chose album %d of %d", selector.Name(), choice+1, len(candidates))
+	}
+
+	return Pick{Album: candidates[choice], Method: selector.Name(), Seed: &seed, state: state}, nil
+}
+
+// take removes the picked album from albums and returns it with the change
+// that records the pick in the history. A pin or snooze ends with the pick.
+func (qs *QueueService) take(albums []Album, pick Pick) (Album, mutation, error) {
+	selectedIndex := -1
+	for i, album := range albums {
+		if album.Key() == pick.Album.Key() {
+			selectedIndex = i
+			break
+		}
+	}
+	if selectedIndex == -1 {
+		return Album{}, mutation{}, fmt.Errorf("album '%s' is no longer in the queue", pick.Album)
+	}
+
+	selectedAlbum := albums[selectedIndex]
+	selectedAlbum.Pinned, selectedAlbum.SnoozedUntil = false, time.Time{}
+
+	// Create new slice excluding the selected album
+	updatedAlbums := make([]Album, 0, len(albums)-1)
+	updatedAlbums = append(updatedAlbums, albums[:selectedIndex]...)
+	updatedAlbums = append(updatedAlbums, albums[selectedIndex+1:]...)
+
+	return selectedAlbum, mutation{
+		albums:      updatedAlbums,
+		history:     []HistoryEntry{{Album: selectedAlbum, PickedAt: qs.now(), Method: pick.Method, Seed: pick.Seed}},
+		description: selectedAlbum.String(),
+		state:       pick.state,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestQueueService_Suggest_ChangesNothing(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"A - One", "B - Two", "C - Three"})
+	bagStore := memoryStorage.Sibling("bag")
+
+	queue := NewQueue(memoryStorage, WithSelector(ShuffleBagSelector{Queue: memoryStorage}))
+
+	if _, err := queue.Suggest(); err != nil {
+		t.Fatalf("Suggest returned error: %v", err)
+	}
+	if _, err := queue.Peek(3); err != nil {
+		t.Fatalf("Peek returned error: %v", err)
+	}
+
+	for name, store := range map[string]storage.Store{
+		"queue":   memoryStorage,
+		"archive": memoryStorage.Sibling("archive"),
+		"bag":     bagStore,
+		"undo":    memoryStorage.Sibling("undo"),
+	} {
+		lines, err := store.ReadLines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "queue" {
+			if len(lines) != 3 {
+				t.Errorf("Expected queue to be unchanged, got %v", lines)
+			}
+		} else if len(lines) != 0 {
+			t.Errorf("Expected %s to be untouched, got %v", name, lines)
+		}
+	}
+}
+
+func TestQueueService_Suggest_MatchesNext(t *testing.T) {
+	albums := []string{"A - One", "B - Two", "C - Three", "D - Four", "E - Five"}
+
+	suggestion, err := seededQueue(albums, WithSeed(99)).Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	picked, err := seededQueue(albums, WithSeed(99)).GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if suggestion.Album.Key() != picked.Key() || suggestion.Seed == nil || *suggestion.Seed != 99 || suggestion.Method != ModeRandom {
+		t.Errorf("Expected suggestion %+v to match next pick %s", suggestion, picked)
+	}
+}
+
+func TestQueueService_Suggest_Reroll(t *testing.T) {
+	queue := seededQueue([]string{"A - One", "B - Two"})
+
+	first, err := queue.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := queue.Suggest(first.Album)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Album.Key() == first.Album.Key() {
+		t.Errorf("Expected a different album after rejecting %s", first.Album)
+	}
+
+	if _, err := queue.Suggest(first.Album, second.Album); !errors.Is(err, ErrNoMoreSuggestions) {
+		t.Errorf("Expected ErrNoMoreSuggestions, got %v", err)
+	}
+}
+
+func TestQueueService_Peek(t *testing.T) {
+	queue := seededQueue([]string{"A - One", "B - Two", "C - Three"}, WithSelector(FIFOSelector{}))
+
+	picks, err := queue.Peek(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	for _, pick := range picks {
+		titles = append(titles, pick.Album.String())
+	}
+	if !slices.Equal(titles, []string{"A - One", "B - Two"}) {
+		t.Errorf("Unexpected suggestions: %v", titles)
+	}
+
+	// Asking for more suggestions than albums returns them all
+	picks, err = queue.Peek(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(picks) != 3 {
+		t.Errorf("Expected 3 suggestions, got %d", len(picks))
+	}
+
+	if _, err := queue.Peek(0); err == nil {
+		t.Error("Expected error for zero suggestions")
+	}
+
+	if _, err := seededQueue(nil).Peek(1); err == nil || !strings.Contains(err.Error(), "the queue is empty") {
+		t.Errorf("Expected empty queue error, got %v", err)
+	}
+}
+
+func TestQueueService_Accept(t *testing.T) {
+	queue := seededQueue([]string{"A - One\tyear=2001", "B - Two"}, WithSeed(5))
+
+	pick, err := queue.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album, err := queue.Accept(pick)
+	if err != nil {
+		t.Fatalf("Accept returned error: %v", err)
+	}
+	if album.Key() != pick.Album.Key() {
+		t.Errorf("Expected %s to be taken, got %s", pick.Album, album)
+	}
+
+	albums, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(albums) != 1 || albums[0].Key() == pick.Album.Key() {
+		t.Errorf("Expected the accepted album to leave the queue, got %v", albums)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Method != ModeRandom || history[0].Seed == nil || *history[0].Seed != 5 {
+		t.Errorf("Expected the pick's method and seed in history, got %+v", history)
+	}
+
+	// A suggestion can only be accepted while the album is in the queue
+	if _, err := queue.Accept(pick); err == nil || !strings.Contains(err.Error(), "no longer in the queue") {
+		t.Errorf("Expected error accepting a taken album, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"fmt"
+)
+
+// SetPriority changes the selection weight of the album identified by ref
+// (see FindAlbum) and returns the updated album. A priority of zero resets
+// the album to DefaultPriority. Any demotion from skipping is cleared, so the
+// album's weight is exactly the new priority.
+func (qs *QueueService) SetPriority(ref string, priority int) (Album, error) {
+	if priority < 0 {
+		return Album{}, fmt.Errorf("invalid priority %d: must be zero or greater", priority)
+	}
+
+	var updated Album
+	err := qs.mutate(OpPrioritize, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+
+		albums := append([]Album(nil), existingAlbums...)
+		albums[index].Priority = priority
+		albums[index].Skips = 0
+		updated = albums[index]
+
+		return mutation{
+			albums:      albums,
+			description: fmt.Sprintf("%s (priority %d)", updated, updated.EffectivePriority()),
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return updated, nil
+}
+
+// totalWeight returns the sum of the selection weights of albums
+func totalWeight(albums []Album) float64 {
+	total := 0.0
+	for _, album := range albums {
+		total += album.Weight()
+	}
+	return total
+}
+
+// weightedIndex maps n, a number in [0, totalWeight(albums)), to the index
+// of the album whose share of the total weight contains it, so that a
+// uniformly random n selects each album with probability proportional to
+// its weight
+func weightedIndex(albums []Album, n float64) int {
+	for i, album := range albums {
+		n -= album.Weight()
+		if n < 0 {
+			return i
+		}
+	}
+	return len(albums) - 1
+}
@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestWeightedIndex(t *testing.T) {
+	albums := []Album{
+		{Artist: "A", Title: "Default"},
+		{Artist: "B", Title: "Heavy", Priority: 3},
+		{Artist: "C", Title: "Explicit One", Priority: 1},
+	}
+
+	if total := totalWeight(albums); total != 5 {
+		t.Fatalf("Expected total weight 5, got %g", total)
+	}
+
+	// Each album owns a range of n values as long as its weight
+	expected := []int{0, 1, 1, 1, 2}
+	for i, want := range expected {
+		n := float64(i) + 0.5
+		if got := weightedIndex(albums, n); got != want {
+			t.Errorf("weightedIndex(%g) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestAlbum_Weight(t *testing.T) {
+	tests := []struct {
+		album    Album
+		expected float64
+	}{
+		{Album{}, 1},
+		{Album{Priority: 4}, 4},
+		{Album{Priority: 4, Skips: 1}, 2},
+		{Album{Skips: 3}, 0.125},
+	}
+
+	for _, tt := range tests {
+		if got := tt.album.Weight(); got != tt.expected {
+			t.Errorf("Weight of %+v = %g, want %g", tt.album, got, tt.expected)
+		}
+	}
+}
+
+// stubSource is a rand.Source whose Int63 always returns the same value, so
+// that rand.Rand.Float64 returns value / 2^63
+type stubSource int64
+
+// drawFraction returns the stubSource that makes Float64 return fraction
+func drawFraction(fraction float64) stubSource {
+	return stubSource(fraction * (1 << 62) * 2)
+}
+
+func (s stubSource) Int63() int64 { return int64(s) }
+func (s stubSource) Seed(int64)   {}
+
+func TestRandomSelector_Weighted(t *testing.T) {
+	candidates := []Album{
+		{Artist: "Long Tail", Title: "Backlog"},
+		{Artist: "Urgent", Title: "Release", Priority: 3},
+	}
+
+	// Out of a total weight of 4, only the first quarter of draws belongs to
+	// the default-priority album
+	expected := []string{"Long Tail", "Urgent", "Urgent", "Urgent"}
+	for i, artist := range expected {
+		draw := (float64(i) + 0.5) / 4
+		ctx := SelectionContext{Queue: candidates, Rand: rand.New(drawFraction(draw))}
+
+		choice, err := RandomSelector{}.Select(candidates, ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if candidates[choice].Artist != artist {
+			t.Errorf("Draw %g: expected %s, got %s", draw, artist, candidates[choice])
+		}
+	}
+}
+
+func TestQueueService_SetPriority(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	queue := NewQueue(memoryStorage)
+
+	for _, title := range []string{"Radiohead - In Rainbows", "Pink Floyd - The Wall"} {
+		if err := queue.AddAlbum(title); err != nil {
+			t.Fatalf("Failed to add album: %v", err)
+		}
+	}
+
+	album, err := queue.SetPriority("wall", 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if album.String() != "Pink Floyd - The Wall" || album.Priority != 5 {
+		t.Errorf("Unexpected updated album: %+v", album)
+	}
+
+	albums, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if albums[0].Priority != 0 || albums[1].Priority != 5 {
+		t.Errorf("Expected only the second album to be prioritized, got %+v", albums)
+	}
+
+	// Prioritizing is journaled like any other change
+	entry, err := queue.Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if entry.Op != OpPrioritize {
+		t.Errorf("Expected %s entry, got %s", OpPrioritize, entry.Op)
+	}
+
+	albums, err = queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if albums[1].Priority != 0 {
+		t.Errorf("Expected priority restored by undo, got %d", albums[1].Priority)
+	}
+}
+
+func TestQueueService_SetPriority_Errors(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohead - In Rainbows"})
+	queue := NewQueue(memoryStorage)
+
+	if _, err := queue.SetPriority("1", -1); err == nil {
+		t.Error("Expected error for negative priority")
+	}
+
+	if _, err := queue.SetPriority("Beatles", 3); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
@@ -1,275 +1,223 @@
 package queue
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"music-queue/src/internal/storage"
 )
 
-// Package-level RNG for consistent random behavior
-var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+// Album sources recorded by the queue service
+const (
+	SourceAdd      = "add"
+	SourceImport   = "import"
+	SourceLibrary  = "library"  // found by scanning a music library directory
+	SourcePlaylist = "playlist" // derived from the tracks of a playlist
+)
 
 // QueueService handles business logic for the music queue
 type QueueService struct {
-	storage *storage.FileStorage
-}
-
-// NewQueue creates a new QueueService instance with the provided storage service
-func NewQueue(storageService *storage.FileStorage) *QueueService {
-	return &QueueService{
-		storage: storageService,
-	}
+	storage     storage.Store
+	archive     storage.Store
+	now         func() time.Time
+	lockTimeout time.Duration
+	rules       []SelectionRule
+	tagFilter   TagFilter
+	similarity  float64 // near-duplicate threshold; zero disables it
+	selector    Selector
+	nextSeed    func() int64 // seeds the random source of each pick
 }
 
-// validateAlbumFormat checks if an album entry follows the "Artist Name - Album Title" format
-// Returns true if valid, false otherwise
-func validateAlbumFormat(album string) bool {
-	album = strings.TrimSpace(album)
-
-	// Must contain at least one dash
-	dashIndex := strings.Index(album, "-")
-	if dashIndex == -1 {
-		return false
+// NewQueue creates a new QueueService instance with the provided storage service.
+// The archive (listening history) is kept in the "archive" sibling of the queue store.
+func NewQueue(storageService storage.Store, opts ...Option) *QueueService {
+	qs := &QueueService{
+		storage:     storageService,
+		archive:     storageService.Sibling("archive"),
+		now:         time.Now,
+		lockTimeout: DefaultLockTimeout,
+		selector:    RandomSelector{},
+		nextSeed:    rand.NewSource(time.Now().UnixNano()).Int63,
 	}
 
-	// Must have at least one character before the dash
-	if dashIndex == 0 {
-		return false
+	for _, opt := range opts {
+		opt(qs)
 	}
 
-	// Must have at least one character after the dash
-	if dashIndex == len(album)-1 {
-		return false
-	}
-
-	// Check that there's content before and after the dash (not just whitespace)
-	beforeDash := strings.TrimSpace(album[:dashIndex])
-	afterDash := strings.TrimSpace(album[dashIndex+1:])
-
-	return len(beforeDash) > 0 && len(afterDash) > 0
+	return qs
 }
 
-// addAlbumCheck validates a single album, checks for duplicates against the given map,
-// and returns an error if it fails. This is a helper for AddAlbum and ImportAlbums.
-func addAlbumCheck(albumTitle string, existingAlbumsMap map[string]bool) error {
-	trimmedTitle := strings.TrimSpace(albumTitle)
-
-	if !validateAlbumFormat(trimmedTitle) {
-		return fmt.Errorf("invalid format") // Generic error, handled by callers
-	}
-
-	albumLower := strings.ToLower(trimmedTitle)
-	if existingAlbumsMap[albumLower] {
-		return fmt.Errorf("album '%s' already exists", trimmedTitle)
+// withLock runs fn while holding the queue lock, so that the whole
+// read-modify-write cycle of a mutating operation is atomic with respect
+// to other processes using the same queue
+func (qs *QueueService) withLock(fn func() error) error {
+	unlock, err := qs.storage.Lock(qs.lockTimeout)
+	if err != nil {
+		var lockedErr *storage.LockedError
+		if errors.As(err, &lockedErr) {
+			return err
+		}
+		return fmt.Errorf("failed to lock queue: %w", err)
 	}
 
-	return nil
-}
+	err = fn()
 
-// AddAlbum adds a single album to the queue with duplicate checking
-// Returns an error if the album format is invalid or if there's a storage error
-func (qs *QueueService) AddAlbum(albumTitle string) error {
-	// Read existing queue
-	existingAlbums, err := qs.storage.ReadLines()
-	if err != nil {
-		return fmt.Errorf("failed to read existing queue: %w", err)
+	if unlockErr := unlock(); unlockErr != nil && err == nil {
+		err = fmt.Errorf("failed to unlock queue: %w", unlockErr)
 	}
 
-	// Create a map for case-insensitive duplicate checking
-	existingAlbumsMap := make(map[string]bool)
-	for _, album := range existingAlbums {
-		existingAlbumsMap[strings.ToLower(strings.TrimSpace(album))] = true
-	}
+	return err
+}
 
-	// Validate and check for duplicates using the helper
-	err = addAlbumCheck(albumTitle, existingAlbumsMap)
-	if err != nil {
-		if strings.Contains(err.Error(), "invalid format") {
-			return fmt.Errorf("invalid album format: must be 'Artist - Album' format")
+// addAlbumCheck checks a parsed album for duplicates among existingAlbums by
+// match key (see Album.MatchKey) and returns an error if it is already
+// present. This is a helper for Add and Edit.
+func addAlbumCheck(album Album, existingAlbums []Album) error {
+	key := album.MatchKey()
+	for _, existing := range existingAlbums {
+		if existing.MatchKey() != key {
+			continue
 		}
-		// Return the "already exists" error directly
-		return err
+		if existing.Key() == album.Key() {
+			return fmt.Errorf("album '%s' already exists", album)
+		}
+		return fmt.Errorf("album '%s' already exists as '%s'", album, existing)
 	}
 
-	// Add album to queue
-	updatedAlbums := append(existingAlbums, strings.TrimSpace(albumTitle))
+	return nil
+}
 
-	// Save updated queue
-	err = qs.storage.WriteLines(updatedAlbums)
+// readQueue reads and decodes every album record in the queue store
+func (qs *QueueService) readQueue() ([]Album, error) {
+	lines, err := qs.storage.ReadLines()
 	if err != nil {
-		return fmt.Errorf("failed to save updated queue: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return decodeRecords(lines)
 }
 
-// ImportAlbums imports albums from a text file, skipping duplicates (case-insensitive)
-// Returns the number of albums added, number of duplicates skipped, number of format errors, and any error encountered
-func (qs *QueueService) ImportAlbums(filename string) (added int, duplicates int, formatErrors int, err error) {
-	// Check if import file exists
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return 0, 0, 0, fmt.Errorf("file not found: %s", filename)
-	}
+// writeQueue encodes and saves the given albums as the full queue
+func (qs *QueueService) writeQueue(albums []Album) error {
+	return qs.storage.WriteLines(encodeRecords(albums))
+}
 
-	// Read import file
-	importStorage := storage.NewFileStorage(filename)
-	importAlbums, err := importStorage.ReadLines()
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to read import file: %w", err)
+// decodeRecords parses storage lines into albums
+func decodeRecords(lines []string) ([]Album, error) {
+	albums := make([]Album, 0, len(lines))
+	for i, line := range lines {
+		album, err := parseStoredRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		albums = append(albums, album)
 	}
+	return albums, nil
+}
 
-	// Handle empty file gracefully
-	if len(importAlbums) == 0 {
-		return 0, 0, 0, nil
+// encodeRecords formats albums as storage lines
+func encodeRecords(albums []Album) []string {
+	lines := make([]string, len(albums))
+	for i, album := range albums {
+		lines[i] = FormatRecord(album)
 	}
+	return lines
+}
 
-	// Read existing queue
-	existingAlbums, err := qs.storage.ReadLines()
-	if err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to read existing queue: %w", err)
+// albumKeys builds the case-insensitive lookup map of the albums' identities
+func albumKeys(albums []Album) map[string]bool {
+	keys := make(map[string]bool, len(albums))
+	for _, album := range albums {
+		keys[album.Key()] = true
 	}
+	return keys
+}
 
-	// Create a map for case-insensitive duplicate checking
-	existingAlbumsMap := make(map[string]bool)
-	for _, album := range existingAlbums {
-		existingAlbumsMap[strings.ToLower(strings.TrimSpace(album))] = true
+// AddAlbum adds a single album to the queue with duplicate checking
+// Returns an error if the album format is invalid or if there's a storage error
+func (qs *QueueService) AddAlbum(albumTitle string) error {
+	album, err := ParseAlbum(albumTitle)
+	if err != nil {
+		return err
 	}
 
-	// Process import albums, distinguishing between duplicates and format errors
-	addedCount := 0
-	duplicatesCount := 0
-	formatErrorsCount := 0
-	currentAlbums := existingAlbums
+	return qs.Add(album)
+}
 
-	for _, album := range importAlbums {
-		// Skip empty lines
-		if strings.TrimSpace(album) == "" {
-			continue
+// Add adds an album with its metadata to the queue with duplicate checking.
+// With a similarity threshold (see WithSimilarity), an album resembling one
+// in the queue is rejected with a *NearDuplicateError.
+// AddedAt is set to the current time and Source to "add" when they are unset.
+func (qs *QueueService) Add(album Album) error {
+	return qs.mutate(OpAdd, func(existingAlbums []Album) (mutation, error) {
+		// Check for duplicates using the helper
+		err := addAlbumCheck(album, existingAlbums)
+		if err != nil {
+			return mutation{}, err
 		}
 
-		trimmedAlbum := strings.TrimSpace(album)
-
-		// Check format validity first
-		if !validateAlbumFormat(trimmedAlbum) {
-			formatErrorsCount++
-			continue
+		if qs.similarity > 0 {
+			index, similarity, found := nearestAlbum(album.MatchKey(), matchKeys(existingAlbums), qs.similarity)
+			if found {
+				return mutation{}, &NearDuplicateError{Album: album, Match: existingAlbums[index], Similarity: similarity}
+			}
 		}
 
-		// Check for duplicates
-		albumLower := strings.ToLower(trimmedAlbum)
-		if existingAlbumsMap[albumLower] {
-			duplicatesCount++
-			continue
+		if album.AddedAt.IsZero() {
+			album.AddedAt = qs.now()
 		}
-
-		// Add album
-		currentAlbums = append(currentAlbums, trimmedAlbum)
-		existingAlbumsMap[albumLower] = true
-		addedCount++
-	}
-
-	// If we have new albums, save the updated queue
-	if addedCount > 0 {
-		err = qs.storage.WriteLines(currentAlbums)
-		if err != nil {
-			return 0, 0, 0, fmt.Errorf("failed to save updated queue: %w", err)
+		if album.Source == "" {
+			album.Source = SourceAdd
 		}
-	}
 
-	return addedCount, duplicatesCount, formatErrorsCount, nil
+		// Add album to queue
+		return mutation{
+			albums:      append(existingAlbums, album),
+			description: album.String(),
+		}, nil
+	})
 }
 
-// GetNextAlbum retrieves a random album from the queue, removes it, and archives it
+// GetNextAlbum retrieves an album from the queue, removes it, and records it in the history.
+// Albums are chosen among those allowed by the selection rules (see WithRules)
+// by the selector (see WithSelector), which defaults to weighted random selection.
+// Each pick uses a random source with its own seed, recorded in the history
+// entry so that the pick can be replayed with WithSeed. It is equivalent to
+// accepting the first suggestion, in one step.
 // Returns the selected album and any error encountered
-func (qs *QueueService) GetNextAlbum() (string, error) {
-	// Read existing queue
-	existingAlbums, err := qs.storage.ReadLines()
-	if err != nil {
-		return "", fmt.Errorf("failed to read queue: %w", err)
-	}
-
-	// Check if queue is empty
-	if len(existingAlbums) == 0 {
-		return "", fmt.Errorf("the queue is empty")
-	}
-
-	// Select random index using package-level RNG
-	randomIndex := rng.Intn(len(existingAlbums))
-	selectedAlbum := existingAlbums[randomIndex]
-
-	// Create new slice excluding the selected album
-	updatedAlbums := make([]string, 0, len(existingAlbums)-1)
-	for i, album := range existingAlbums {
-		if i != randomIndex {
-			updatedAlbums = append(updatedAlbums, album)
+func (qs *QueueService) GetNextAlbum() (Album, error) {
+	var selectedAlbum Album
+	err := qs.mutate(OpNext, func(existingAlbums []Album) (mutation, error) {
+		history, err := qs.History(time.Time{}, time.Time{})
+		if err != nil {
+			return mutation{}, err
 		}
-	}
 
-	// Save updated queue
-	err = qs.storage.WriteLines(updatedAlbums)
-	if err != nil {
-		return "", fmt.Errorf("failed to save updated queue: %w", err)
-	}
+		pick, err := qs.choose(existingAlbums, history, nil, qs.selector)
+		if err != nil {
+			return mutation{}, err
+		}
 
-	// Archive the selected album
-	err = qs.archiveAlbum(selectedAlbum)
+		var change mutation
+		selectedAlbum, change, err = qs.take(existingAlbums, pick)
+		return change, err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to archive album: %w", err)
+		return Album{}, err
 	}
 
 	return selectedAlbum, nil
 }
 
-// archiveAlbum adds an album to the archive file
-func (qs *QueueService) archiveAlbum(album string) error {
-	archivePath := qs.getArchivePath()
-	archiveStorage := storage.NewFileStorage(archivePath)
-
-	// Read existing archive
-	existingArchived, err := archiveStorage.ReadLines()
-	if err != nil {
-		return fmt.Errorf("failed to read archive: %w", err)
-	}
-
-	// Add album to archive (append to end)
-	updatedArchive := append(existingArchived, album)
-
-	// Save updated archive
-	err = archiveStorage.WriteLines(updatedArchive)
-	if err != nil {
-		return fmt.Errorf("failed to save archive: %w", err)
-	}
-
-	return nil
-}
-
-// getArchivePath returns the path to the archive file based on the queue file path
-func (qs *QueueService) getArchivePath() string {
-	queuePath := qs.storage.GetFilePath()
-	queueDir := filepath.Dir(queuePath)
-	queueBase := filepath.Base(queuePath)
-
-	// Replace "queue.txt" with "archive.txt" or add "_archive.txt" if different name
-	if queueBase == "queue.txt" {
-		return filepath.Join(queueDir, "archive.txt")
-	}
-
-	// For custom queue file names, append "_archive" before the extension
-	ext := filepath.Ext(queueBase)
-	nameWithoutExt := strings.TrimSuffix(queueBase, ext)
-	return filepath.Join(queueDir, nameWithoutExt+"_archive"+ext)
-}
-
 // ListAlbums retrieves all albums currently in the queue
-// Returns a slice of album strings and any error encountered
-func (qs *QueueService) ListAlbums() ([]string, error) {
+// Returns a slice of albums in queue order and any error encountered
+func (qs *QueueService) ListAlbums() ([]Album, error) {
 	// Read existing queue
-	existingAlbums, err := qs.storage.ReadLines()
+	existingAlbums, err := qs.readQueue()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read queue: %w", err)
 	}
@@ -280,8 +228,8 @@ func (qs *QueueService) ListAlbums() ([]string, error) {
 // CountAlbums returns the number of albums currently in the queue
 // Returns the count as an integer and any error encountered
 func (qs *QueueService) CountAlbums() (int, error) {
-	// Read existing queue
-	existingAlbums, err := qs.storage.ReadLines()
+	// Read existing queue; a corrupt line is reported rather than counted
+	existingAlbums, err := qs.readQueue()
 	if err != nil {
 		return 0, fmt.Errorf("failed to read queue: %w", err)
 	}
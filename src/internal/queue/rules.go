@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"strings"
+	"time"
+)
+
+// SelectionRule narrows the albums that GetNextAlbum may pick. Eligible
+// receives the candidate albums, the listening history (oldest first) and the
+// current time, and returns the indices of the candidates that may be picked.
+// A rule should fall back to a non-empty subset rather than rule out every
+// candidate; if it returns no indices the rule is ignored for that pick.
+type SelectionRule interface {
+	Eligible(candidates []Album, history []HistoryEntry, now time.Time) []int
+}
+
+// ArtistDiversityRule avoids artists that were picked recently: within the
+// last RecentPicks history entries, or within the last Within of the current
+// time. Zero disables the respective limit. When every candidate's artist was
+// picked recently, the artists picked longest ago remain eligible.
+type ArtistDiversityRule struct {
+	RecentPicks int
+	Within      time.Duration
+}
+
+// Eligible implements SelectionRule
+func (r ArtistDiversityRule) Eligible(candidates []Album, history []HistoryEntry, now time.Time) []int {
+	// lastPicked maps each recently picked artist to how many picks ago it
+	// was last played (0 for the most recent pick)
+	lastPicked := map[string]int{}
+	for age := 0; age < len(history); age++ {
+		entry := history[len(history)-1-age]
+
+		recentByCount := r.RecentPicks > 0 && age < r.RecentPicks
+		recentByTime := r.Within > 0 && !entry.PickedAt.IsZero() && now.Sub(entry.PickedAt) < r.Within
+		if !recentByCount && !recentByTime {
+			continue
+		}
+
+		artist := artistKey(entry.Album)
+		if _, seen := lastPicked[artist]; !seen {
+			lastPicked[artist] = age
+		}
+	}
+
+	var eligible []int
+	for i, album := range candidates {
+		if _, recent := lastPicked[artistKey(album)]; !recent {
+			eligible = append(eligible, i)
+		}
+	}
+	if len(eligible) > 0 {
+		return eligible
+	}
+
+	// Every remaining artist was played recently; prefer the ones played
+	// longest ago
+	oldest := -1
+	for _, album := range candidates {
+		oldest = max(oldest, lastPicked[artistKey(album)])
+	}
+	for i, album := range candidates {
+		if lastPicked[artistKey(album)] == oldest {
+			eligible = append(eligible, i)
+		}
+	}
+
+	return eligible
+}
+
+// artistKey returns the value used to compare artists case-insensitively
+func artistKey(album Album) string {
+	return strings.ToLower(album.Artist)
+}
+
+// applyRules returns the indices of the albums that satisfy every rule, in
+// order. Rules are applied one after another to the remaining candidates, and
+// a rule that would leave nothing to pick is skipped.
+func applyRules(rules []SelectionRule, albums []Album, history []HistoryEntry, now time.Time) []int {
+	indices := make([]int, len(albums))
+	for i := range albums {
+		indices[i] = i
+	}
+
+	for _, rule := range rules {
+		candidates := make([]Album, len(indices))
+		for i, index := range indices {
+			candidates[i] = albums[index]
+		}
+
+		eligible := rule.Eligible(candidates, history, now)
+		if len(eligible) == 0 {
+			continue
+		}
+
+		narrowed := make([]int, 0, len(eligible))
+		for _, i := range eligible {
+			if i >= 0 && i < len(indices) {
+				narrowed = append(narrowed, indices[i])
+			}
+		}
+		if len(narrowed) > 0 {
+			indices = narrowed
+		}
+	}
+
+	return indices
+}
@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestArtistDiversityRule_Eligible(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	history := []HistoryEntry{
+		{Album: Album{Artist: "Radiohead", Title: "Kid A"}, PickedAt: now.Add(-10 * 24 * time.Hour)},
+		{Album: Album{Artist: "Pink Floyd", Title: "Animals"}, PickedAt: now.Add(-2 * 24 * time.Hour)},
+		{Album: Album{Artist: "boards of canada", Title: "Geogaddi"}, PickedAt: now.Add(-time.Hour)},
+	}
+	candidates := []Album{
+		{Artist: "Radiohead", Title: "In Rainbows"},
+		{Artist: "Pink Floyd", Title: "The Wall"},
+		{Artist: "Boards of Canada", Title: "Tomorrow's Harvest"},
+		{Artist: "Björk", Title: "Homogenic"},
+	}
+
+	tests := []struct {
+		name       string
+		rule       ArtistDiversityRule
+		candidates []Album
+		expected   []int
+	}{
+		{"last pick, case-insensitive", ArtistDiversityRule{RecentPicks: 1}, candidates, []int{0, 1, 3}},
+		{"last two picks", ArtistDiversityRule{RecentPicks: 2}, candidates, []int{0, 3}},
+		{"within a week", ArtistDiversityRule{Within: 7 * 24 * time.Hour}, candidates, []int{0, 3}},
+		{"either limit", ArtistDiversityRule{RecentPicks: 3, Within: time.Hour}, candidates, []int{3}},
+		{"no limits", ArtistDiversityRule{}, candidates, []int{0, 1, 2, 3}},
+		{"falls back to artist played longest ago", ArtistDiversityRule{RecentPicks: 3}, candidates[:3], []int{0}},
+		{"single artist left", ArtistDiversityRule{RecentPicks: 1}, candidates[2:3], []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eligible := tt.rule.Eligible(tt.candidates, history, now)
+			if !slices.Equal(eligible, tt.expected) {
+				t.Errorf("Expected eligible %v, got %v", tt.expected, eligible)
+			}
+		})
+	}
+}
+
+// ruleFunc adapts a function to SelectionRule for tests
+type ruleFunc func(candidates []Album) []int
+
+func (f ruleFunc) Eligible(candidates []Album, history []HistoryEntry, now time.Time) []int {
+	return f(candidates)
+}
+
+func TestApplyRules(t *testing.T) {
+	albums := []Album{
+		{Artist: "A", Title: "One"},
+		{Artist: "B", Title: "Two"},
+		{Artist: "C", Title: "Three"},
+	}
+
+	dropFirst := ruleFunc(func(candidates []Album) []int {
+		var indices []int
+		for i := 1; i < len(candidates); i++ {
+			indices = append(indices, i)
+		}
+		return indices
+	})
+	rejectAll := ruleFunc(func(candidates []Album) []int { return nil })
+
+	// Rules compose, and indices refer to the original albums
+	if got := applyRules([]SelectionRule{dropFirst, dropFirst}, albums, nil, time.Time{}); !slices.Equal(got, []int{2}) {
+		t.Errorf("Expected [2], got %v", got)
+	}
+
+	// A rule that rejects everything is ignored
+	if got := applyRules([]SelectionRule{rejectAll, dropFirst}, albums, nil, time.Time{}); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("Expected [1 2], got %v", got)
+	}
+}
+
+func TestQueueService_GetNextAlbum_ArtistDiversity(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{
+		"Radiohead - In Rainbows",
+		"Radiohead - OK Computer",
+		"Radiohead - Kid A",
+		"Pink Floyd - The Wall",
+		"Pink Floyd - Animals",
+	})
+
+	queue := NewQueue(memoryStorage, WithRules(ArtistDiversityRule{RecentPicks: 1}))
+
+	// With two artists and the last one always avoided, picks alternate
+	// until only Radiohead is left
+	var artists []string
+	for i := 0; i < 5; i++ {
+		album, err := queue.GetNextAlbum()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		artists = append(artists, album.Artist)
+	}
+
+	for i := 1; i < 4; i++ {
+		if artists[i] == artists[i-1] {
+			t.Errorf("Same artist picked twice in a row before the queue ran out of variety: %v", artists)
+		}
+	}
+	if artists[4] != "Radiohead" {
+		t.Errorf("Expected the last pick to fall back to the remaining artist, got %v", artists)
+	}
+}
@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+// Selection modes accepted by NewSelector. The mode is also recorded as the
+// pick method in listening history.
+const (
+	ModeRandom     = MethodRandom
+	ModeFIFO       = "fifo"
+	ModeLIFO       = "lifo"
+	ModeOldest     = "oldest"
+	ModeShuffle    = "shuffle"
+	ModeRoundRobin = "round-robin"
+)
+
+// Modes lists the built-in selection modes
+func Modes() []string {
+	return []string{ModeRandom, ModeFIFO, ModeLIFO, ModeOldest, ModeShuffle, ModeRoundRobin}
+}
+
+// SelectionContext is what a Selector may base its choice on
+type SelectionContext struct {
+	Queue   []Album        // the whole queue, in order
+	History []HistoryEntry // listening history, oldest first
+	Now     time.Time
+	Rand    *rand.Rand // seeded per pick; use it for all randomness so picks can be replayed
+}
+
+// Selector chooses the album GetNextAlbum takes from the queue. Select
+// receives the candidates left after the selection rules were applied (never
+// empty, in queue order) and returns the index of the chosen candidate.
+type Selector interface {
+	// Name identifies the strategy and is recorded as the pick method in history
+	Name() string
+	Select(candidates []Album, ctx SelectionContext) (int, error)
+}
+
+// StatefulSelector is implemented by selectors that keep state between picks,
+// such as ShuffleBagSelector. Their Select saves nothing: SelectState chooses
+// the same way and also returns the state after the pick, which the queue
+// service saves with the queue when the pick is taken and records in the undo
+// journal.
+type StatefulSelector interface {
+	Selector
+	SelectState(candidates []Album, ctx SelectionContext) (int, SelectorState, error)
+}
+
+// SelectorState is the state of a StatefulSelector after a pick: the lines
+// of the sibling of the queue store named Sibling
+type SelectorState struct {
+	Sibling string
+	Lines   []string
+}
+
+// bagSibling names the sibling of the queue store holding the shuffle bag
+const bagSibling = "bag"
+
+// NewSelector returns the built-in selector for mode. The shuffle-bag
+// selector keeps its state in the "bag" sibling of queueStore.
+func NewSelector(mode string, queueStore storage.Store) (Selector, error) {
+	switch mode {
+	case ModeRandom:
+		return RandomSelector{}, nil
+	case ModeFIFO:
+		return FIFOSelector{}, nil
+	case ModeLIFO:
+		return LIFOSelector{}, nil
+	case ModeOldest:
+		return OldestSelector{}, nil
+	case ModeShuffle:
+		return ShuffleBagSelector{Queue: queueStore}, nil
+	case ModeRoundRobin:
+		return RoundRobinSelector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown selection mode %q (available: %s)", mode, strings.Join(Modes(), ", "))
+	}
+}
+
+// RandomSelector picks at random with probability proportional to each
+// album's Weight
+type RandomSelector struct{}
+
+// Name implements Selector
+func (RandomSelector) Name() string { return ModeRandom }
+
+// Select implements Selector
+func (RandomSelector) Select(candidates []Album, ctx SelectionContext) (int, error) {
+	return weightedIndex(candidates, ctx.Rand.Float64()*totalWeight(candidates)), nil
+}
+
+// FIFOSelector picks the album that has been in the queue longest by position
+type FIFOSelector struct{}
+
+// Name implements Selector
+func (FIFOSelector) Name() string { return ModeFIFO }
+
+// Select implements Selector
+func (FIFOSelector) Select(candidates []Album, ctx SelectionContext) (int, error) {
+	return 0, nil
+}
+
+// LIFOSelector picks the album added to the queue most recently by position
+type LIFOSelector struct{}
+
+// Name implements Selector
+func (LIFOSelector) Name() string { return ModeLIFO }
+
+// Select implements Selector
+func (LIFOSelector) Select(candidates []Album, ctx SelectionContext) (int, error) {
+	return len(candidates) - 1, nil
+}
+
+// OldestSelector picks the album with the earliest added time. Albums without
+// an added time predate metadata recording and count as oldest; ties go to
+// the album earliest in the queue.
+type OldestSelector struct{}
+
+// Name implements Selector
+func (OldestSelector) Name() string { return ModeOldest }
+
+// Select implements Selector
+func (OldestSelector) Select(candidates []Album, ctx SelectionContext) (int, error) {
+	oldest := 0
+	for i, album := range candidates {
+		if album.AddedAt.Before(candidates[oldest].AddedAt) {
+			oldest = i
+		}
+	}
+	return oldest, nil
+}
+
+// RoundRobinSelector cycles through artists in alphabetical order: it picks
+// the first album in the queue by the artist that follows the most recently
+// picked artist, wrapping around after the last one
+type RoundRobinSelector struct{}
+
+// Name implements Selector
+func (RoundRobinSelector) Name() string { return ModeRoundRobin }
+
+// Select implements Selector
+func (RoundRobinSelector) Select(candidates []Album, ctx SelectionContext) (int, error) {
+	lastArtist := ""
+	if len(ctx.History) > 0 {
+		lastArtist = artistKey(ctx.History[len(ctx.History)-1].Album)
+	}
+
+	// First album of the alphabetically next artist, or of the first artist
+	// overall when wrapping around
+	next, first := -1, 0
+	for i, album := range candidates {
+		artist := artistKey(album)
+		if artist < artistKey(candidates[first]) {
+			first = i
+		}
+		if artist > lastArtist && (next == -1 || artist < artistKey(candidates[next])) {
+			next = i
+		}
+	}
+
+	if next == -1 {
+		return first, nil
+	}
+	return next, nil
+}
+
+// ShuffleBagSelector deals the queue like a shuffled deck: each pass visits
+// every album in a random order fixed when the pass starts, and albums added
+// during a pass wait for the next one. The remaining order is kept in the
+// "bag" sibling of Queue, the queue store, as one album key per line.
+type ShuffleBagSelector struct {
+	Queue storage.Store
+}
+
+// Name implements Selector
+func (ShuffleBagSelector) Name() string { return ModeShuffle }
+
+// Select implements Selector without saving the bag (see SelectState)
+func (s ShuffleBagSelector) Select(candidates []Album, ctx SelectionContext) (int, error) {
+	choice, _, err := s.SelectState(candidates, ctx)
+	return choice, err
+}
+
+// SelectState implements StatefulSelector, returning the bag left after the
+// pick. Selection rules take precedence over the bag: when they rule out
+// every album left in the current pass, a random candidate is picked instead
+// and the pass continues afterwards.
+func (s ShuffleBagSelector) SelectState(candidates []Album, ctx SelectionContext) (int, SelectorState, error) {
+	bag, err := s.Queue.Sibling(bagSibling).ReadLines()
+	if err != nil {
+		return 0, SelectorState{}, fmt.Errorf("failed to read shuffle bag: %w", err)
+	}
+
+	// Forget albums that have left the queue since the bag was dealt
+	queued := albumKeys(ctx.Queue)
+	bag = slices.DeleteFunc(bag, func(key string) bool { return !queued[key] })
+
+	if len(bag) == 0 {
+		bag = make([]string, len(ctx.Queue))
+		for i, album := range ctx.Queue {
+			bag[i] = album.Key()
+		}
+		ctx.Rand.Shuffle(len(bag), func(i, j int) { bag[i], bag[j] = bag[j], bag[i] })
+	}
+
+	selected := -1
+	for _, key := range bag {
+		selected = slices.IndexFunc(candidates, func(album Album) bool { return album.Key() == key })
+		if selected != -1 {
+			break
+		}
+	}
+	if selected == -1 {
+		selected = ctx.Rand.Intn(len(candidates))
+	}
+
+	picked := candidates[selected].Key()
+	bag = slices.DeleteFunc(bag, func(key string) bool { return key == picked })
+
+	return selected, SelectorState{Sibling: bagSibling, Lines: bag}, nil
+}
@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestSelectors(t *testing.T) {
+	added := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	candidates := []Album{
+		{Artist: "Radiohead", Title: "In Rainbows", AddedAt: added.Add(2 * time.Hour)},
+		{Artist: "Björk", Title: "Homogenic", AddedAt: added},
+		{Artist: "Pink Floyd", Title: "The Wall", AddedAt: added.Add(time.Hour)},
+		{Artist: "Pink Floyd", Title: "Animals", AddedAt: added.Add(3 * time.Hour)},
+	}
+	picked := func(artist string) []HistoryEntry {
+		return []HistoryEntry{{Album: Album{Artist: artist, Title: "Earlier"}}}
+	}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		history  []HistoryEntry
+		expected int
+	}{
+		{"fifo", FIFOSelector{}, nil, 0},
+		{"lifo", LIFOSelector{}, nil, 3},
+		{"oldest", OldestSelector{}, nil, 1},
+		{"round-robin without history", RoundRobinSelector{}, nil, 1},
+		{"round-robin next artist", RoundRobinSelector{}, picked("björk"), 2},
+		{"round-robin first album of artist", RoundRobinSelector{}, picked("Led Zeppelin"), 2},
+		{"round-robin wraps around", RoundRobinSelector{}, picked("Radiohead"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			choice, err := tt.selector.Select(candidates, SelectionContext{Queue: candidates, History: tt.history})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if choice != tt.expected {
+				t.Errorf("Expected %s, got %s", candidates[tt.expected], candidates[choice])
+			}
+		})
+	}
+}
+
+func TestOldestSelector_LegacyAlbumsFirst(t *testing.T) {
+	candidates := []Album{
+		{Artist: "New", Title: "Album", AddedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Artist: "Legacy", Title: "Album"},
+	}
+
+	choice, err := OldestSelector{}.Select(candidates, SelectionContext{Queue: candidates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if choice != 1 {
+		t.Errorf("Expected album without added time to count as oldest, got %s", candidates[choice])
+	}
+}
+
+func TestNewSelector(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+
+	for _, mode := range Modes() {
+		selector, err := NewSelector(mode, memoryStorage)
+		if err != nil {
+			t.Fatalf("NewSelector(%q) returned error: %v", mode, err)
+		}
+		if selector.Name() != mode {
+			t.Errorf("Expected selector named %q, got %q", mode, selector.Name())
+		}
+	}
+
+	if _, err := NewSelector("alphabetical", memoryStorage); err == nil || !strings.Contains(err.Error(), "unknown selection mode") {
+		t.Errorf("Expected unknown mode error, got %v", err)
+	}
+}
+
+func TestShuffleBagSelector_FullPassBeforeRepeat(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	selector, err := NewSelector(ModeShuffle, memoryStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	albums := []Album{
+		{Artist: "A", Title: "One"},
+		{Artist: "B", Title: "Two"},
+		{Artist: "C", Title: "Three"},
+	}
+
+	// Without removing picks from the queue, every album must come up once
+	// per pass
+	for pass := 0; pass < 3; pass++ {
+		var seen []string
+		for range albums {
+			choice, state, err := selector.(StatefulSelector).SelectState(albums, SelectionContext{Queue: albums, Rand: rand.New(rand.NewSource(1))})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			memoryStorage.Sibling(state.Sibling).WriteLines(state.Lines)
+			seen = append(seen, albums[choice].Key())
+		}
+
+		slices.Sort(seen)
+		if !slices.Equal(seen, []string{"a - one", "b - two", "c - three"}) {
+			t.Errorf("Pass %d did not visit every album once: %v", pass, seen)
+		}
+	}
+}
+
+func TestShuffleBagSelector_NewAlbumsWaitForNextPass(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	selector := ShuffleBagSelector{Queue: memoryStorage}
+
+	albums := []Album{{Artist: "A", Title: "One"}, {Artist: "B", Title: "Two"}}
+	_, state, err := selector.SelectState(albums, SelectionContext{Queue: albums, Rand: rand.New(rand.NewSource(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Select saves nothing; the bag is saved when the pick is taken
+	bag := state.Lines
+	if len(bag) != 1 {
+		t.Fatalf("Expected one album left in the pass, got %v", bag)
+	}
+	if saved, _ := memoryStorage.Sibling("bag").ReadLines(); len(saved) != 0 {
+		t.Fatalf("Expected SelectState to save nothing, got %v", saved)
+	}
+	memoryStorage.Sibling(state.Sibling).WriteLines(bag)
+
+	// An album added mid-pass is not picked until the pass is over
+	albums = append(albums, Album{Artist: "C", Title: "Three"})
+	choice, err := selector.Select(albums, SelectionContext{Queue: albums, Rand: rand.New(rand.NewSource(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if albums[choice].Key() != bag[0] {
+		t.Errorf("Expected the rest of the pass (%s), got %s", bag[0], albums[choice])
+	}
+}
+
+func TestQueueService_GetNextAlbum_WithSelector(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohead - In Rainbows", "Pink Floyd - The Wall"})
+
+	queue := NewQueue(memoryStorage, WithSelector(LIFOSelector{}))
+
+	album, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if album.String() != "Pink Floyd - The Wall" {
+		t.Errorf("Expected the last album, got %s", album)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Method != ModeLIFO {
+		t.Errorf("Expected the mode recorded as pick method, got %+v", history)
+	}
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+)
+
+// Skip puts the album most recently taken by next back at the end of the
+// queue and records the skip in the history, after which the pick no longer
+// counts as played. The album comes back neither pinned nor snoozed. With
+// demote, the album's selection weight is halved.
+// Skipping again puts back the pick before that one.
+func (qs *QueueService) Skip(demote bool) (Album, error) {
+	var skipped Album
+	err := qs.mutate(OpSkip, func(existingAlbums []Album) (mutation, error) {
+		history, err := qs.History(time.Time{}, time.Time{})
+		if err != nil {
+			return mutation{}, err
+		}
+
+		picks := plays(history)
+		if len(picks) == 0 {
+			return mutation{}, fmt.Errorf("nothing to skip: no album has been picked")
+		}
+
+		// Picks recorded by older versions kept the pin and snooze
+		skipped = picks[len(picks)-1].Album
+		skipped.Pinned, skipped.SnoozedUntil = false, time.Time{}
+		if albumKeys(existingAlbums)[skipped.Key()] {
+			return mutation{}, fmt.Errorf("album '%s' is already back in the queue", skipped)
+		}
+
+		if demote {
+			skipped.Skips++
+		}
+
+		return mutation{
+			albums:      append(existingAlbums, skipped),
+			history:     []HistoryEntry{{Album: skipped, PickedAt: qs.now(), Event: EventSkipped}},
+			description: skipped.String(),
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return skipped, nil
+}
+
+// Snooze hides the album identified by ref (see FindAlbum) from selection
+// until the given time, after which it is eligible again without further
+// action. A zero time wakes the album up immediately.
+func (qs *QueueService) Snooze(ref string, until time.Time) (Album, error) {
+	var updated Album
+	err := qs.mutate(OpSnooze, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+
+		albums := append([]Album(nil), existingAlbums...)
+		albums[index].SnoozedUntil = until
+		updated = albums[index]
+
+		description := fmt.Sprintf("%s (awake)", updated)
+		if !until.IsZero() {
+			description = fmt.Sprintf("%s (until %s)", updated, until.UTC().Format(time.RFC3339))
+		}
+
+		return mutation{
+			albums:      albums,
+			description: description,
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return updated, nil
+}
+
+// firstWake returns the earliest snooze end among albums, or the zero time
+// if none is snoozed
+func firstWake(albums []Album) time.Time {
+	var first time.Time
+	for _, album := range albums {
+		if !album.SnoozedUntil.IsZero() && (first.IsZero() || album.SnoozedUntil.Before(first)) {
+			first = album.SnoozedUntil
+		}
+	}
+	return first
+}
@@ -0,0 +1,205 @@
+package queue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestPlays(t *testing.T) {
+	pick := func(title string) HistoryEntry {
+		return HistoryEntry{Album: Album{Artist: "A", Title: title}}
+	}
+	skip := func(title string) HistoryEntry {
+		return HistoryEntry{Album: Album{Artist: "A", Title: title}, Event: EventSkipped}
+	}
+
+	history := []HistoryEntry{pick("One"), pick("Two"), pick("One"), skip("one"), pick("Three"), skip("Three")}
+
+	var titles []string
+	for _, entry := range plays(history) {
+		titles = append(titles, entry.Album.Title)
+	}
+
+	// Only the latest pick of "One" was taken back
+	if strings.Join(titles, ",") != "One,Two" {
+		t.Errorf("Expected plays One,Two, got %v", titles)
+	}
+}
+
+func TestQueueService_Skip(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohead - In Rainbows\tpriority=4", "Pink Floyd - The Wall"})
+	queue := NewQueue(memoryStorage, WithSelector(FIFOSelector{}))
+
+	picked, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, err := queue.Skip(true)
+	if err != nil {
+		t.Fatalf("Skip returned error: %v", err)
+	}
+	if skipped.Key() != picked.Key() || skipped.Skips != 1 || skipped.Weight() != 2 {
+		t.Errorf("Expected %s back with halved weight, got %+v", picked, skipped)
+	}
+
+	// The album is back at the end of the queue with its metadata
+	albums, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(albums) != 2 || albums[1].Key() != picked.Key() || albums[1].Priority != 4 {
+		t.Errorf("Expected the skipped album at the end of the queue, got %+v", albums)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Event != EventSkipped {
+		t.Errorf("Expected a skip event in history, got %+v", history)
+	}
+	if len(plays(history)) != 0 {
+		t.Errorf("Expected the skipped pick not to count as played")
+	}
+
+	// Nothing left to skip
+	if _, err := queue.Skip(false); err == nil || !strings.Contains(err.Error(), "nothing to skip") {
+		t.Errorf("Expected nothing to skip error, got %v", err)
+	}
+
+	// Skipping is journaled
+	entry, err := queue.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Op != OpSkip {
+		t.Errorf("Expected %s entry, got %s", OpSkip, entry.Op)
+	}
+	if count, _ := queue.CountAlbums(); count != 1 {
+		t.Errorf("Expected undo to take the album out again, got %d albums", count)
+	}
+}
+
+func TestQueueService_Skip_AlreadyInQueue(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Pink Floyd - The Wall"})
+	queue := NewQueue(memoryStorage)
+
+	if _, err := queue.GetNextAlbum(); err != nil {
+		t.Fatal(err)
+	}
+	if err := queue.AddAlbum("Pink Floyd - The Wall"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := queue.Skip(false); err == nil || !strings.Contains(err.Error(), "already back in the queue") {
+		t.Errorf("Expected already in queue error, got %v", err)
+	}
+}
+
+func TestQueueService_Snooze(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Radiohead - In Rainbows", "Pink Floyd - The Wall"})
+	queue := NewQueue(memoryStorage, WithSelector(FIFOSelector{}))
+	queue.now = func() time.Time { return now }
+
+	until := now.Add(14 * 24 * time.Hour)
+	album, err := queue.Snooze("rainbows", until)
+	if err != nil {
+		t.Fatalf("Snooze returned error: %v", err)
+	}
+	if !album.SnoozedUntil.Equal(until) || !album.Snoozed(now) {
+		t.Errorf("Expected album snoozed until %s, got %+v", until, album)
+	}
+
+	// The snoozed album is passed over
+	pick, err := queue.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pick.Album.Title != "The Wall" {
+		t.Errorf("Expected the snoozed album to be passed over, got %s", pick.Album)
+	}
+
+	// With only snoozed albums left, nothing can be picked
+	if _, err := queue.Suggest(pick.Album); err == nil || !strings.Contains(err.Error(), "snoozed") {
+		t.Errorf("Expected every album snoozed error, got %v", err)
+	}
+
+	// Once the snooze expires the album is eligible again
+	now = until
+	pick, err = queue.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pick.Album.Title != "In Rainbows" {
+		t.Errorf("Expected the album to wake up, got %s", pick.Album)
+	}
+
+	// A zero time wakes the album up early
+	now = until.Add(-time.Hour)
+	if _, err := queue.Snooze("1", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	pick, err = queue.Suggest()
+	if err != nil || pick.Album.Title != "In Rainbows" {
+		t.Errorf("Expected the album to be awake, got %v, %v", pick.Album, err)
+	}
+
+	if _, err := queue.Suggest(pick.Album, Album{Artist: "Pink Floyd", Title: "The Wall"}); !errors.Is(err, ErrNoMoreSuggestions) {
+		t.Errorf("Expected ErrNoMoreSuggestions, got %v", err)
+	}
+}
+
+func TestQueueService_Skip_EndsPinAndSnooze(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"A - One", "B - Two", "C - Three\tsnoozed=2099-01-01T00:00:00Z"})
+	queue := NewQueue(memoryStorage, WithSelector(FIFOSelector{}))
+
+	if _, err := queue.Pin("two", true); err != nil {
+		t.Fatal(err)
+	}
+	if picked, err := queue.GetNextAlbum(); err != nil || picked.Title != "Two" {
+		t.Fatalf("Expected the pinned album, got %v, %v", picked, err)
+	}
+
+	// The skipped album is no longer pinned, so the selector picks next
+	skipped, err := queue.Skip(false)
+	if err != nil {
+		t.Fatalf("Skip returned error: %v", err)
+	}
+	if skipped.Pinned {
+		t.Errorf("Expected the skipped album to be unpinned, got %+v", skipped)
+	}
+	if picked, err := queue.GetNextAlbum(); err != nil || picked.Title != "One" {
+		t.Errorf("Expected the selector's pick after the skip, got %v, %v", picked, err)
+	}
+
+	history, err := queue.History(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history[0].Album.Pinned {
+		t.Errorf("Expected the pick to be recorded unpinned, got %+v", history[0])
+	}
+
+	// A snoozed album played by hand comes back awake
+	if _, err := queue.Play("three"); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err = queue.Skip(false)
+	if err != nil {
+		t.Fatalf("Skip returned error: %v", err)
+	}
+	if !skipped.SnoozedUntil.IsZero() {
+		t.Errorf("Expected the skipped album to be awake, got %+v", skipped)
+	}
+}
@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TagCount is a tag with the number of albums in the queue that carry it
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// TagFilter restricts selection to albums carrying every Include tag and
+// none of the Exclude tags. The zero TagFilter allows every album.
+type TagFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Allows reports whether album passes the filter
+func (f TagFilter) Allows(album Album) bool {
+	for _, tag := range f.Include {
+		if !album.HasTag(tag) {
+			return false
+		}
+	}
+	return !slices.ContainsFunc(f.Exclude, album.HasTag)
+}
+
+// String describes the filter, e.g. "jazz, vinyl, not long"
+func (f TagFilter) String() string {
+	parts := slices.Clone(f.Include)
+	for _, tag := range f.Exclude {
+		parts = append(parts, "not "+tag)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Tag adds tags to the album identified by ref (see FindAlbum) and returns
+// the updated album. Tags are normalized by NormalizeTags, and ones the album
+// already carries are ignored.
+func (qs *QueueService) Tag(ref string, tags ...string) (Album, error) {
+	added := NormalizeTags(tags...)
+	if len(added) == 0 {
+		return Album{}, fmt.Errorf("no tag given")
+	}
+
+	return qs.retag(ref, func(current []string) []string {
+		for _, tag := range added {
+			if !slices.Contains(current, tag) {
+				current = append(current, tag)
+			}
+		}
+		return current
+	})
+}
+
+// Untag removes tags from the album identified by ref (see FindAlbum) and
+// returns the updated album. Tags the album does not carry are ignored.
+func (qs *QueueService) Untag(ref string, tags ...string) (Album, error) {
+	removed := NormalizeTags(tags...)
+	if len(removed) == 0 {
+		return Album{}, fmt.Errorf("no tag given")
+	}
+
+	return qs.retag(ref, func(current []string) []string {
+		return slices.DeleteFunc(current, func(tag string) bool { return slices.Contains(removed, tag) })
+	})
+}
+
+// retag replaces the tags of the album identified by ref with the result of
+// change, which receives a copy of the current tags
+func (qs *QueueService) retag(ref string, change func(current []string) []string) (Album, error) {
+	var updated Album
+	err := qs.mutate(OpTag, func(existingAlbums []Album) (mutation, error) {
+		index, err := FindAlbum(existingAlbums, ref)
+		if err != nil {
+			return mutation{}, err
+		}
+
+		albums := append([]Album(nil), existingAlbums...)
+		albums[index].Tags = change(slices.Clone(albums[index].Tags))
+		if len(albums[index].Tags) == 0 {
+			albums[index].Tags = nil
+		}
+		updated = albums[index]
+
+		description := fmt.Sprintf("%s (no tags)", updated)
+		if len(updated.Tags) > 0 {
+			description = fmt.Sprintf("%s (%s)", updated, strings.Join(updated.Tags, ", "))
+		}
+
+		return mutation{
+			albums:      albums,
+			description: description,
+		}, nil
+	})
+	if err != nil {
+		return Album{}, err
+	}
+
+	return updated, nil
+}
+
+// Tags returns every tag used in the queue with the number of albums
+// carrying it, most used first and alphabetically among equal counts
+func (qs *QueueService) Tags() ([]TagCount, error) {
+	albums, err := qs.ListAlbums()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, album := range albums {
+		for _, tag := range album.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(result, func(a, b TagCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+
+	return result, nil
+}
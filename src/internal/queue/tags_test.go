@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"music-queue/src/internal/storage"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags("Jazz", " modal ,vinyl", "JAZZ", "", "late  night")
+
+	expected := []string{"jazz", "modal", "vinyl", "late night"}
+	if !slices.Equal(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+}
+
+func TestQueueService_TagAndUntag(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{"Miles Davis - Kind of Blue", "Nirvana - Nevermind"})
+	queue := NewQueue(memoryStorage)
+
+	album, err := queue.Tag("blue", "Jazz", "1959,vinyl")
+	if err != nil {
+		t.Fatalf("Tag returned error: %v", err)
+	}
+	if !slices.Equal(album.Tags, []string{"jazz", "1959", "vinyl"}) {
+		t.Errorf("Unexpected tags after tagging: %v", album.Tags)
+	}
+
+	album, err = queue.Untag("blue", "1959", "unknown")
+	if err != nil {
+		t.Fatalf("Untag returned error: %v", err)
+	}
+	if !slices.Equal(album.Tags, []string{"jazz", "vinyl"}) {
+		t.Errorf("Unexpected tags after untagging: %v", album.Tags)
+	}
+
+	// Tags are persisted with the album and undone like other changes
+	albums, err := queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(albums[0].Tags, []string{"jazz", "vinyl"}) {
+		t.Errorf("Expected stored tags, got %v", albums[0].Tags)
+	}
+
+	if _, err := queue.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	albums, err = queue.ListAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !albums[0].HasTag("1959") {
+		t.Errorf("Expected undo to restore tag 1959, got %v", albums[0].Tags)
+	}
+
+	if _, err := queue.Tag("blue", " , "); err == nil || !strings.Contains(err.Error(), "no tag given") {
+		t.Errorf("Expected error for empty tag, got %v", err)
+	}
+}
+
+func TestQueueService_Tags(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{
+		"A - One\ttags=jazz,vinyl",
+		"B - Two\ttags=rock",
+		"C - Three\ttags=vinyl,rock",
+		"D - Four",
+	})
+	queue := NewQueue(memoryStorage)
+
+	counts, err := queue.Tags()
+	if err != nil {
+		t.Fatalf("Tags returned error: %v", err)
+	}
+
+	expected := []TagCount{{"rock", 2}, {"vinyl", 2}, {"jazz", 1}}
+	if !slices.Equal(counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, counts)
+	}
+}
+
+func TestQueueService_GetNextAlbum_TagFilter(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{
+		"A - One\ttags=jazz,long",
+		"B - Two\ttags=rock",
+		"C - Three\ttags=jazz",
+		"D - Four",
+	})
+	queue := NewQueue(memoryStorage, WithSelector(FIFOSelector{}), WithTagFilter(TagFilter{Include: []string{"Jazz"}, Exclude: []string{"long"}}))
+
+	album, err := queue.GetNextAlbum()
+	if err != nil {
+		t.Fatalf("GetNextAlbum returned error: %v", err)
+	}
+	if album.Title != "Three" {
+		t.Errorf("Expected the only jazz album that is not long, got %s", album)
+	}
+
+	_, err = queue.GetNextAlbum()
+	if err == nil || !strings.Contains(err.Error(), "no album in the queue matches the tags jazz, not long") {
+		t.Errorf("Expected tag filter error, got %v", err)
+	}
+
+	count, err := queue.CountAlbums()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 albums left in the queue, got %d", count)
+	}
+}
+
+func TestQueueService_Suggest_TagFilterSnoozed(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage("queue")
+	memoryStorage.WriteLines([]string{
+		"A - One\ttags=rock\tsnoozed=2030-01-01T12:00:00Z",
+		"B - Two\ttags=jazz\tsnoozed=2031-06-15T12:00:00Z",
+	})
+	queue := NewQueue(memoryStorage, WithSelector(FIFOSelector{}), WithTagFilter(TagFilter{Include: []string{"jazz"}}))
+	queue.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	// The wake-up time is that of the albums the tags allow
+	_, err := queue.Suggest()
+	if err == nil || !strings.Contains(err.Error(), "the first wakes up 2031-06-15") {
+		t.Errorf("Expected the jazz album's wake-up time, got %v", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // FileStorage handles file-based storage operations
@@ -52,7 +54,10 @@ func (fs *FileStorage) ReadLines() ([]string, error) {
 	return lines, nil
 }
 
-// WriteLines writes a slice of strings to the file, one line per string
+// WriteLines writes a slice of strings to the file, one line per string.
+// The lines are written to a temporary file in the same directory, synced to
+// disk and renamed over the original, so a crash or full disk mid-write leaves
+// the previous contents intact.
 func (fs *FileStorage) WriteLines(lines []string) error {
 	// Ensure the directory exists
 	dir := filepath.Dir(fs.filePath)
@@ -60,21 +65,106 @@ func (fs *FileStorage) WriteLines(lines []string) error {
 		return fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
-	file, err := os.Create(fs.filePath)
+	// Keep the permissions of an existing file
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fs.filePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tempFile, err := os.CreateTemp(dir, "."+filepath.Base(fs.filePath)+".tmp-*")
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", fs.filePath, err)
+		return fmt.Errorf("failed to create temporary file for %s: %w", fs.filePath, err)
 	}
-	defer file.Close()
+	tempPath := tempFile.Name()
 
-	writer := bufio.NewWriter(file)
-	defer writer.Flush()
+	// Remove the temporary file on any failure; after a successful rename it no longer exists
+	committed := false
+	defer func() {
+		if !committed {
+			tempFile.Close()
+			os.Remove(tempPath)
+		}
+	}()
 
+	writer := bufio.NewWriter(tempFile)
 	for _, line := range lines {
 		if _, err := writer.WriteString(line + "\n"); err != nil {
 			return fmt.Errorf("failed to write to file %s: %w", fs.filePath, err)
 		}
 	}
 
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", fs.filePath, err)
+	}
+
+	if err := tempFile.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", fs.filePath, err)
+	}
+
+	if err := tempFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file %s: %w", fs.filePath, err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", fs.filePath, err)
+	}
+
+	if err := os.Rename(tempPath, fs.filePath); err != nil {
+		return fmt.Errorf("failed to replace file %s: %w", fs.filePath, err)
+	}
+	committed = true
+
+	syncDir(dir)
+
+	return nil
+}
+
+// syncDir flushes a directory entry change (such as a rename) to disk.
+// This is best effort: some platforms, notably Windows, cannot sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
+
+// AppendLines appends lines to the end of the file, creating it if necessary
+func (fs *FileStorage) AppendLines(lines ...string) error {
+	// Ensure the directory exists
+	dir := filepath.Dir(fs.filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	file, err := os.OpenFile(fs.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", fs.filePath, err)
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, line := range lines {
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write to file %s: %w", fs.filePath, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write to file %s: %w", fs.filePath, err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync file %s: %w", fs.filePath, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", fs.filePath, err)
+	}
+
 	return nil
 }
 
@@ -82,3 +172,50 @@ func (fs *FileStorage) WriteLines(lines []string) error {
 func (fs *FileStorage) GetFilePath() string {
 	return fs.filePath
 }
+
+// Name returns the file path, which is what identifies a FileStorage
+func (fs *FileStorage) Name() string {
+	return fs.filePath
+}
+
+// Sibling returns a FileStorage for a companion file in the same directory.
+// For the default "queue.txt" the sibling is "<name>.txt"; for custom file
+// names "_<name>" is inserted before the extension (e.g. "jazz_archive.txt").
+func (fs *FileStorage) Sibling(name string) Store {
+	dir := filepath.Dir(fs.filePath)
+	base := filepath.Base(fs.filePath)
+
+	if base == "queue.txt" {
+		return NewFileStorage(filepath.Join(dir, name+".txt"))
+	}
+
+	ext := filepath.Ext(base)
+	nameWithoutExt := strings.TrimSuffix(base, ext)
+	return NewFileStorage(filepath.Join(dir, nameWithoutExt+"_"+name+ext))
+}
+
+// Lock takes an exclusive lock on the queue using a lock file next to it
+// ("queue.txt.lock"). The lock is shared by every process using the same file.
+func (fs *FileStorage) Lock(timeout time.Duration) (func() error, error) {
+	// Ensure the directory exists
+	dir := filepath.Dir(fs.filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	return lockFile(fs.lockPath(), timeout)
+}
+
+// lockPath returns the path of the lock file that guards this file
+func (fs *FileStorage) lockPath() string {
+	return fs.filePath + ".lock"
+}
+
+// parsePID parses the PID recorded in a lock file, returning zero if there is none
+func parsePID(content []byte) int {
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -176,3 +176,133 @@ func TestFileStorage_GetFilePath(t *testing.T) {
 		t.Errorf("Expected file path %s, got %s", anotherPath, retrievedAnotherPath)
 	}
 }
+
+func TestFileStorage_AppendLines(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "nested", "append.txt")
+
+	storage := NewFileStorage(testFile)
+
+	err := storage.AppendLines("Artist 1 - Album 1")
+	if err != nil {
+		t.Errorf("AppendLines returned error: %v", err)
+	}
+
+	err = storage.AppendLines("Artist 2 - Album 2", "Artist 3 - Album 3")
+	if err != nil {
+		t.Errorf("AppendLines returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+
+	expectedContent := "Artist 1 - Album 1\nArtist 2 - Album 2\nArtist 3 - Album 3\n"
+	if string(content) != expectedContent {
+		t.Errorf("Expected content %q, got %q", expectedContent, string(content))
+	}
+}
+
+func TestFileStorage_Sibling(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		sibling  string
+		expected string
+	}{
+		{"default queue file", "/data/queue.txt", "archive", "/data/archive.txt"},
+		{"custom queue file", "/data/jazz.txt", "archive", "/data/jazz_archive.txt"},
+		{"custom queue without extension", "/data/jazz", "archive", "/data/jazz_archive"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sibling := NewFileStorage(tc.path).Sibling(tc.sibling)
+			if sibling.Name() != tc.expected {
+				t.Errorf("Expected sibling %s, got %s", tc.expected, sibling.Name())
+			}
+		})
+	}
+}
+
+func TestFileStorage_WriteLines_ReplacesAtomically(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "queue.txt")
+
+	// Create existing file with restrictive permissions
+	err := os.WriteFile(testFile, []byte("Old Artist - Old Album\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := NewFileStorage(testFile)
+	err = storage.WriteLines([]string{"New Artist - New Album"})
+	if err != nil {
+		t.Fatalf("WriteLines returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "New Artist - New Album\n" {
+		t.Errorf("Expected file to be replaced, got %q", string(content))
+	}
+
+	// Permissions of the original file are kept
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("Expected only the queue file in directory, got %v", names)
+	}
+}
+
+func TestFileStorage_WriteLines_FailureKeepsOriginal(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "queue.txt")
+
+	// A directory in place of the target makes the final rename fail
+	err := os.MkdirAll(filepath.Join(testFile, "child"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := NewFileStorage(testFile)
+	err = storage.WriteLines([]string{"Artist - Album"})
+	if err == nil {
+		t.Fatal("Expected error when the target cannot be replaced")
+	}
+
+	// The original entry is untouched and the temporary file was cleaned up
+	if _, err := os.Stat(filepath.Join(testFile, "child")); err != nil {
+		t.Errorf("Expected original target to be intact, got: %v", err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary file to be removed, found %d entries", len(entries))
+	}
+}
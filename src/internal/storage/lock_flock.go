@@ -0,0 +1,64 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// lockFile takes an exclusive flock on path, creating the file if needed.
+// The lock is released automatically by the kernel if the process dies, so a
+// crashed command can never leave the queue locked.
+func lockFile(path string, timeout time.Duration) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		if time.Now().After(deadline) {
+			pid := readLockPID(file)
+			file.Close()
+			return nil, &LockedError{Name: path, PID: pid, Timeout: timeout}
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+
+	// Record the holder so that waiting processes can report it
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return func() error {
+		file.Truncate(0)
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to unlock %s: %w", path, err)
+		}
+		return file.Close()
+	}, nil
+}
+
+// readLockPID reads the PID written by the current lock holder, or zero
+func readLockPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	return parsePID(buf[:n])
+}
@@ -0,0 +1,42 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// lockFile takes a lock by exclusively creating path and writing the PID into
+// it. Platforms without flock use this fallback; a lock file left behind by a
+// crashed process has to be removed by hand.
+func lockFile(path string, timeout time.Duration) (func() error, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			file.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			file.Close()
+			return func() error {
+				if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("failed to unlock %s: %w", path, err)
+				}
+				return nil
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+
+		if time.Now().After(deadline) {
+			content, _ := os.ReadFile(path)
+			return nil, &LockedError{Name: path, PID: parsePID(content), Timeout: timeout}
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStorage_Lock(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "queue.txt")
+
+	storage := NewFileStorage(queueFile)
+	unlock, err := storage.Lock(time.Second)
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+
+	// Lock file lives next to the queue file
+	if _, err := os.Stat(queueFile + ".lock"); err != nil {
+		t.Errorf("Expected lock file next to queue file: %v", err)
+	}
+
+	// A second instance for the same file cannot take the lock
+	other := NewFileStorage(queueFile)
+	_, err = other.Lock(50 * time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected second Lock to fail while the lock is held")
+	}
+
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected *LockedError, got %T: %v", err, err)
+	}
+
+	if lockedErr.PID != os.Getpid() {
+		t.Errorf("Expected lock holder PID %d, got %d", os.Getpid(), lockedErr.PID)
+	}
+
+	if !strings.Contains(err.Error(), "queue is locked by PID") {
+		t.Errorf("Expected 'queue is locked by PID' message, got: %v", err)
+	}
+
+	// After unlocking the lock can be taken again
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock returned error: %v", err)
+	}
+
+	unlock, err = other.Lock(time.Second)
+	if err != nil {
+		t.Fatalf("Lock after unlock returned error: %v", err)
+	}
+	unlock()
+}
+
+func TestFileStorage_Lock_WaitsForRelease(t *testing.T) {
+	tempDir := t.TempDir()
+	queueFile := filepath.Join(tempDir, "nested", "queue.txt")
+
+	storage := NewFileStorage(queueFile)
+	unlock, err := storage.Lock(time.Second)
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		unlock()
+	}()
+
+	// A waiter with a long enough timeout gets the lock once it is released
+	unlockOther, err := NewFileStorage(queueFile).Lock(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Expected waiting Lock to succeed, got: %v", err)
+	}
+	unlockOther()
+}
+
+func TestMemoryStorage_Lock(t *testing.T) {
+	storage := NewMemoryStorage("queue")
+
+	unlock, err := storage.Lock(time.Second)
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+
+	_, err = storage.Lock(20 * time.Millisecond)
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected *LockedError while lock is held, got: %v", err)
+	}
+	if lockedErr.PID != 0 {
+		t.Errorf("Expected no lock holder PID, got %d", lockedErr.PID)
+	}
+
+	// Unlocking twice is harmless
+	unlock()
+	unlock()
+
+	unlock, err = storage.Lock(time.Second)
+	if err != nil {
+		t.Fatalf("Lock after unlock returned error: %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorage is an in-memory Store, useful for embedding the queue in other
+// tools and for testing business logic without touching the filesystem
+type MemoryStorage struct {
+	name  string
+	group *memoryGroup
+	mu    sync.Mutex
+	lines []string
+	lock  chan struct{}
+}
+
+// memoryGroup holds the siblings created from a single root MemoryStorage so
+// that repeated Sibling calls resolve to the same instance
+type memoryGroup struct {
+	mu     sync.Mutex
+	stores map[string]*MemoryStorage
+}
+
+// NewMemoryStorage creates a new, empty MemoryStorage with the given name
+func NewMemoryStorage(name string) *MemoryStorage {
+	group := &memoryGroup{stores: make(map[string]*MemoryStorage)}
+	ms := &MemoryStorage{name: name, group: group, lock: make(chan struct{}, 1)}
+	group.stores[name] = ms
+	return ms
+}
+
+// ReadLines returns a copy of the stored lines
+func (ms *MemoryStorage) ReadLines() ([]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	lines := make([]string, len(ms.lines))
+	copy(lines, ms.lines)
+	return lines, nil
+}
+
+// WriteLines replaces the stored lines, dropping empty and whitespace-only lines
+// the same way FileStorage does when reading them back
+func (ms *MemoryStorage) WriteLines(lines []string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.lines = cleanLines(lines)
+	return nil
+}
+
+// AppendLines adds lines to the end of the store
+func (ms *MemoryStorage) AppendLines(lines ...string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.lines = append(ms.lines, cleanLines(lines)...)
+	return nil
+}
+
+// Name returns the name given to NewMemoryStorage or Sibling
+func (ms *MemoryStorage) Name() string {
+	return ms.name
+}
+
+// Sibling returns the in-memory companion store with the given name, creating
+// it on first use
+func (ms *MemoryStorage) Sibling(name string) Store {
+	ms.group.mu.Lock()
+	defer ms.group.mu.Unlock()
+
+	if sibling, ok := ms.group.stores[name]; ok {
+		return sibling
+	}
+
+	sibling := &MemoryStorage{name: name, group: ms.group, lock: make(chan struct{}, 1)}
+	ms.group.stores[name] = sibling
+	return sibling
+}
+
+// cleanLines trims each line and drops the empty ones
+func cleanLines(lines []string) []string {
+	cleaned := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			cleaned = append(cleaned, line)
+		}
+	}
+	return cleaned
+}
+
+// Lock takes an exclusive in-process lock on the store
+func (ms *MemoryStorage) Lock(timeout time.Duration) (func() error, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ms.lock <- struct{}{}:
+		var once sync.Once
+		return func() error {
+			once.Do(func() { <-ms.lock })
+			return nil
+		}, nil
+	case <-timer.C:
+		// The holder is in this process, so there is no other PID to report
+		return nil, &LockedError{Name: ms.name, Timeout: timeout}
+	}
+}
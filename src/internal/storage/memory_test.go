@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestNewMemoryStorage(t *testing.T) {
+	storage := NewMemoryStorage("queue")
+
+	if storage == nil {
+		t.Fatal("NewMemoryStorage returned nil")
+	}
+
+	if storage.Name() != "queue" {
+		t.Errorf("Expected name queue, got %s", storage.Name())
+	}
+
+	lines, err := storage.ReadLines()
+	if err != nil {
+		t.Errorf("ReadLines returned error: %v", err)
+	}
+
+	if len(lines) != 0 {
+		t.Errorf("Expected empty store, got %d lines", len(lines))
+	}
+}
+
+func TestMemoryStorage_WriteAndAppendLines(t *testing.T) {
+	storage := NewMemoryStorage("queue")
+
+	err := storage.WriteLines([]string{"Artist 1 - Album 1", "  ", " Artist 2 - Album 2 "})
+	if err != nil {
+		t.Errorf("WriteLines returned error: %v", err)
+	}
+
+	err = storage.AppendLines("Artist 3 - Album 3", "")
+	if err != nil {
+		t.Errorf("AppendLines returned error: %v", err)
+	}
+
+	lines, err := storage.ReadLines()
+	if err != nil {
+		t.Errorf("ReadLines returned error: %v", err)
+	}
+
+	// Should behave like FileStorage: trimmed, without empty lines
+	expected := []string{"Artist 1 - Album 1", "Artist 2 - Album 2", "Artist 3 - Album 3"}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), len(lines))
+	}
+
+	for i, expectedLine := range expected {
+		if lines[i] != expectedLine {
+			t.Errorf("Line %d: expected %q, got %q", i, expectedLine, lines[i])
+		}
+	}
+}
+
+func TestMemoryStorage_ReadLinesReturnsCopy(t *testing.T) {
+	storage := NewMemoryStorage("queue")
+	if err := storage.WriteLines([]string{"Artist 1 - Album 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, _ := storage.ReadLines()
+	lines[0] = "Changed - Album"
+
+	reread, _ := storage.ReadLines()
+	if reread[0] != "Artist 1 - Album 1" {
+		t.Errorf("Modifying returned slice changed the store: got %q", reread[0])
+	}
+}
+
+func TestMemoryStorage_Sibling(t *testing.T) {
+	storage := NewMemoryStorage("queue")
+
+	archive := storage.Sibling("archive")
+	if archive.Name() != "archive" {
+		t.Errorf("Expected sibling name archive, got %s", archive.Name())
+	}
+
+	if err := archive.AppendLines("Artist 1 - Album 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same sibling must be returned on every call, including from siblings
+	again, _ := storage.Sibling("archive").ReadLines()
+	if len(again) != 1 {
+		t.Errorf("Expected sibling to keep its data, got %v", again)
+	}
+
+	fromSibling, _ := archive.Sibling("archive").ReadLines()
+	if len(fromSibling) != 1 {
+		t.Errorf("Expected sibling lookup from a sibling to share data, got %v", fromSibling)
+	}
+
+	// The queue itself is unaffected
+	queueLines, _ := storage.ReadLines()
+	if len(queueLines) != 0 {
+		t.Errorf("Expected queue to stay empty, got %v", queueLines)
+	}
+}
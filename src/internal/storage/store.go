@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Store is the line-oriented persistence interface used by the queue service.
+// FileStorage and MemoryStorage are the two implementations shipped with the
+// application; other backends only need to satisfy this interface.
+type Store interface {
+	// ReadLines returns all non-empty lines, trimmed of surrounding whitespace.
+	// A store that has never been written to returns an empty slice.
+	ReadLines() ([]string, error)
+
+	// WriteLines replaces the entire contents of the store.
+	WriteLines(lines []string) error
+
+	// AppendLines adds lines to the end of the store without rewriting it.
+	AppendLines(lines ...string) error
+
+	// Name identifies the store for messages and logs. It does not have to be
+	// a file path.
+	Name() string
+
+	// Sibling returns a companion store with the given name that lives next
+	// to this one (for example the archive that belongs to a queue). Calling
+	// Sibling twice with the same name returns stores backed by the same data.
+	Sibling(name string) Store
+
+	// Lock takes an exclusive advisory lock on the store, waiting up to timeout
+	// for another holder to release it. The returned function releases the lock.
+	// When the wait times out the error is a *LockedError.
+	Lock(timeout time.Duration) (unlock func() error, err error)
+}
+
+// LockedError is returned by Lock when another process holds the lock
+type LockedError struct {
+	Name    string        // name of the locked store
+	PID     int           // process holding the lock, zero if unknown
+	Timeout time.Duration // how long Lock waited
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("queue is locked by another process (waited %s)", e.Timeout)
+	}
+	return fmt.Sprintf("queue is locked by PID %d (waited %s)", e.PID, e.Timeout)
+}
+
+// lockRetryInterval is how often a blocked Lock call retries
+const lockRetryInterval = 25 * time.Millisecond